@@ -6,6 +6,7 @@ import (
 	serv "banners/internal/service"
 	"banners/internal/storage/postgresql"
 	"banners/internal/storage/redisC"
+	"banners/lib/logger/sl"
 	"context"
 	"log/slog"
 	"net/http"
@@ -35,26 +36,49 @@ func main() {
 
 	repo, err := postgresql.New(cfg.DataSourceName, 5)
 	if err != nil {
-		log.Error("failed to initialize storage", err)
+		log.Error("failed to initialize storage", sl.Err(err))
 		os.Exit(1)
 	}
 
 	c, err := redisC.New(cfg.CacheStorage.Address)
 	if err != nil {
-		log.Error("failed to initialize cache", err)
+		log.Error("failed to initialize cache", sl.Err(err))
 		os.Exit(1)
 	}
 
-	service, err := serv.New(log, repo, repo, c)
+	service, err := serv.New(log, cfg.Auth, cfg.Retention, serv.Storages{
+		Banner:     repo,
+		User:       repo,
+		Token:      repo,
+		Role:       repo,
+		APIKey:     repo,
+		Audit:      repo,
+		Tenant:     repo,
+		Approval:   repo,
+		Schema:     repo,
+		Registry:   repo,
+		Experiment: repo,
+	}, c)
 	if err != nil {
-		log.Error("failed to initialize services", err)
+		log.Error("failed to initialize services", sl.Err(err))
 		os.Exit(1)
 	}
 
-	deleteCtx, _ := context.WithCancel(context.Background())
+	err = service.EnsureAdmin(context.Background(), cfg.BootstrapAdmin.Email, cfg.BootstrapAdmin.Password)
+	if err != nil {
+		log.Error("failed to create bootstrap admin", sl.Err(err))
+		os.Exit(1)
+	}
+
+	retentionCtx, cancelRetention := context.WithCancel(context.Background())
+	defer cancelRetention()
+	go service.RunRetention(retentionCtx)
+
+	deleteCtx, cancelDelete := context.WithCancel(context.Background())
+	defer cancelDelete()
 	handler, err := hand.New(log, service, service, service, deleteCtx)
 	if err != nil {
-		log.Error("failed to initialize handlers", err)
+		log.Error("failed to initialize handlers", sl.Err(err))
 		os.Exit(1)
 	}
 
@@ -87,7 +111,7 @@ func main() {
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Error("failed to stop server", err)
+		log.Error("failed to stop server", sl.Err(err))
 		return
 	}
 
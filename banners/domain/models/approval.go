@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// ApprovalRequest asks a second user to sign off a revision before it goes
+// live on a feature that requires approval.
+type ApprovalRequest struct {
+	ID          int64      `json:"id"`
+	TenantID    int64      `json:"tenant_id"`
+	BannerID    int64      `json:"banner_id"`
+	RevisionID  int64      `json:"revision_id"`
+	Status      string     `json:"status"`
+	Comment     string     `json:"comment,omitempty"`
+	RequestedBy *int64     `json:"requested_by,omitempty"`
+	DecidedBy   *int64     `json:"decided_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	// RevisionAuthor is the user who wrote the revision.
+	RevisionAuthor *int64 `json:"-"`
+}
+
+// FeaturePolicy holds the publishing rules of a feature.
+type FeaturePolicy struct {
+	FeatureID        int64 `json:"feature_id"`
+	RequiresApproval bool  `json:"requires_approval"`
+}
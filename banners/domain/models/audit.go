@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditBannerCreate         = "banner.create"
+	AuditBannerPatch          = "banner.patch"
+	AuditBannerChooseRevision = "banner.choose_revision"
+	AuditBannerPublish        = "banner.publish"
+	AuditBannerRollback       = "banner.rollback"
+	AuditBannerDelete         = "banner.delete"
+	AuditBannerDeleteDeferred = "banner.delete_by_feature_tag"
+	AuditApprovalRequest      = "approval.request"
+	AuditApprovalApprove      = "approval.approve"
+	AuditApprovalReject       = "approval.reject"
+	AuditFeaturePolicySet     = "feature.set_policy"
+	AuditFeatureSchemaCreate  = "feature.create_schema"
+	AuditFeatureDefaultSet    = "feature.set_default"
+	AuditFeatureDefaultDelete = "feature.delete_default"
+	AuditExperimentCreate     = "experiment.create"
+	AuditExperimentStop       = "experiment.stop"
+	AuditRolloutStart         = "rollout.start"
+	AuditRolloutUpdate        = "rollout.update"
+	AuditRolloutFinalize      = "rollout.finalize"
+	AuditRolloutAbort         = "rollout.abort"
+	AuditRegistryCreate       = "registry.create"
+	AuditRegistryUpdate       = "registry.update"
+	AuditRegistryDelete       = "registry.delete"
+	AuditUserCreate           = "user.create"
+	AuditUserUpdate           = "user.update"
+	AuditUserDelete           = "user.delete"
+	AuditRoleSetPermissions   = "role.set_permissions"
+	AuditRoleDelete           = "role.delete"
+	AuditAPIKeyCreate         = "apikey.create"
+	AuditAPIKeyRevoke         = "apikey.revoke"
+	AuditTenantCreate         = "tenant.create"
+)
+
+// AuditEntry is a single record of the audit log. Before and After are
+// snapshots of the target, either of them is empty for creations and
+// deletions.
+type AuditEntry struct {
+	ID           int64           `json:"id"`
+	TenantID     int64           `json:"tenant_id"`
+	ActorID      *int64          `json:"actor_id,omitempty"`
+	ActorEmail   string          `json:"actor_email,omitempty"`
+	APIKeyID     *int64          `json:"api_key_id,omitempty"`
+	Action       string          `json:"action"`
+	BannerID     *int64          `json:"banner_id,omitempty"`
+	RevisionID   *int64          `json:"revision_id,omitempty"`
+	TargetUserID *int64          `json:"target_user_id,omitempty"`
+	Target       string          `json:"target,omitempty"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// AuditFilter narrows down the audit log. Nil fields are not filtered on.
+type AuditFilter struct {
+	TenantID int64
+	ActorID  *int64
+	BannerID *int64
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	Offset   int
+}
+
+// Snapshot encodes v for the Before or After of an entry. It returns nil if
+// v cannot be encoded.
+func Snapshot(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
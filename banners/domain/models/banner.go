@@ -5,13 +5,70 @@ import (
 	"time"
 )
 
+// Revision states. A draft has never been published, a published revision
+// has been chosen at some point, and the live one is chosen now.
+const (
+	RevisionDraft     = "draft"
+	RevisionPublished = "published"
+	RevisionLive      = "live"
+)
+
 type Banner struct {
-	BannerID  int64           `json:"banner_id,omitempty"`
-	TagIDs    []int64         `json:"tag_ids,omitempty"`
-	FeatureID int64           `json:"feature_id,omitempty"`
-	Revision  int64           `json:"revision_id,omitempty"`
-	IsActive  bool            `json:"is_active"`
-	CreatedAt time.Time       `json:"created_at,omitempty"`
-	UpdatedAT time.Time       `json:"updated_at,omitempty"`
-	Content   json.RawMessage `json:"content,omitempty"`
+	BannerID  int64      `json:"banner_id,omitempty"`
+	TagIDs    []int64    `json:"tag_ids,omitempty"`
+	FeatureID int64      `json:"feature_id,omitempty"`
+	Revision  int64      `json:"revision_id,omitempty"`
+	IsActive  bool       `json:"is_active"`
+	CreatedAt time.Time  `json:"created_at,omitempty"`
+	UpdatedAT time.Time  `json:"updated_at,omitempty"`
+	CreatedBy *int64     `json:"created_by,omitempty"`
+	UpdatedBy *int64     `json:"updated_by,omitempty"`
+	StartsAt  *time.Time `json:"starts_at,omitempty"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+	// PublishedAt and State are only filled in for revisions.
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	State       string     `json:"state,omitempty"`
+	// SchemaVersion is the version of the feature schema the content was
+	// checked against, nil if the feature had none.
+	SchemaVersion *int `json:"schema_version,omitempty"`
+	// FeatureName and TagNames are only filled in for listings that ask
+	// for names, with the registered names of the IDs.
+	FeatureName string           `json:"feature_name,omitempty"`
+	TagNames    map[int64]string `json:"tag_names,omitempty"`
+	// MatchedTagID is the tag a user banner was found by, the requested one
+	// or the nearest of its ancestors.
+	MatchedTagID int64 `json:"matched_tag_id,omitempty"`
+	// Fallback is set when the banner is the default of the feature, given
+	// because no banner has the tag.
+	Fallback bool `json:"fallback,omitempty"`
+	// Variant is the key of the experiment variant a user banner comes
+	// from, empty if the user is not in an experiment.
+	Variant string `json:"variant,omitempty"`
+	// Targeting limits the users the revision is served to, nil if it is
+	// served to all users of its tags.
+	Targeting *Targeting `json:"targeting,omitempty"`
+	// Rollout is the rollout of another revision of a user banner, and
+	// Canary is set on the revision it serves to the users it reaches.
+	Rollout *Rollout        `json:"rollout,omitempty"`
+	Canary  bool            `json:"canary,omitempty"`
+	Content json.RawMessage `json:"content,omitempty"`
+}
+
+// LiveAt reports whether t is within the schedule of the banner. A banner
+// without a start or an end is live from or until any time.
+func (b *Banner) LiveAt(t time.Time) bool {
+	if b.StartsAt != nil && t.Before(*b.StartsAt) {
+		return false
+	}
+
+	if b.EndsAt != nil && !t.Before(*b.EndsAt) {
+		return false
+	}
+
+	return true
+}
+
+// ValidSchedule reports whether the banner ends after it starts.
+func (b *Banner) ValidSchedule() bool {
+	return b.StartsAt == nil || b.EndsAt == nil || b.StartsAt.Before(*b.EndsAt)
 }
@@ -0,0 +1,26 @@
+package models
+
+import "banners/lib/jsonpatch"
+
+// RevisionDiff lists what changed between two revisions of a banner.
+// Fields that did not change are left out, Content is an RFC 6902 patch
+// from the content of the first revision to that of the second.
+type RevisionDiff struct {
+	BannerID  int64                 `json:"banner_id"`
+	From      int64                 `json:"from"`
+	To        int64                 `json:"to"`
+	Content   []jsonpatch.Operation `json:"content"`
+	FeatureID *ValueChange          `json:"feature_id,omitempty"`
+	IsActive  *ValueChange          `json:"is_active,omitempty"`
+	TagIDs    *TagChange            `json:"tag_ids,omitempty"`
+}
+
+type ValueChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+type TagChange struct {
+	Added   []int64 `json:"added"`
+	Removed []int64 `json:"removed"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	ExperimentRunning = "running"
+	ExperimentStopped = "stopped"
+)
+
+// Experiment splits the users of a feature and tag between variants of the
+// content, in proportion to the weights of the variants. While it runs,
+// users it can tell apart get their variant instead of the banner.
+type Experiment struct {
+	ID        int64      `json:"id"`
+	FeatureID int64      `json:"feature_id"`
+	TagID     int64      `json:"tag_id"`
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	Variants  []Variant  `json:"variants"`
+	CreatedBy *int64     `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+}
+
+type Variant struct {
+	Key     string          `json:"key"`
+	Weight  int             `json:"weight"`
+	Content json.RawMessage `json:"content,omitempty"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// FeatureDefault is the banner users get for a feature when no banner has
+// their tag.
+type FeatureDefault struct {
+	FeatureID int64 `json:"feature_id"`
+	BannerID  int64 `json:"banner_id"`
+}
@@ -0,0 +1,45 @@
+package models
+
+// Identity is the authenticated caller of a request: either a user with an
+// access token or a machine client with an API key.
+type Identity struct {
+	UserID     int64
+	Email      string
+	Role       string
+	TenantID   int64
+	APIKeyID   int64
+	APIKeyName string
+	Scopes     []string
+	FeatureIDs []int64
+}
+
+func (i *Identity) IsAPIKey() bool {
+	return i.APIKeyID != 0
+}
+
+// AuthorID is the user to record as the author of a change. API keys are
+// not users, so their changes have no author.
+func (i *Identity) AuthorID() *int64 {
+	if i.UserID == 0 {
+		return nil
+	}
+
+	id := i.UserID
+	return &id
+}
+
+// CanAccessFeature reports whether the caller may see banners of the feature.
+// Only API keys can be restricted to a set of features.
+func (i *Identity) CanAccessFeature(featureID int64) bool {
+	if len(i.FeatureIDs) == 0 {
+		return true
+	}
+
+	for _, id := range i.FeatureIDs {
+		if id == featureID {
+			return true
+		}
+	}
+
+	return false
+}
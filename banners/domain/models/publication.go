@@ -0,0 +1,26 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LiveBanner is the revision that was live for a feature and tag at some
+// point in time, together with the publication that made it live. The
+// revision goes with its banner, so for a banner deleted since only the
+// publication is left and IsActive and Content are null.
+type LiveBanner struct {
+	BannerID    int64           `json:"banner_id"`
+	RevisionID  int64           `json:"revision_id"`
+	IsActive    *bool           `json:"is_active"`
+	Content     json.RawMessage `json:"content"`
+	StartsAt    *time.Time      `json:"starts_at,omitempty"`
+	EndsAt      *time.Time      `json:"ends_at,omitempty"`
+	LiveSince   time.Time       `json:"live_since"`
+	PublishedBy *int64          `json:"published_by,omitempty"`
+	// MatchedTagID is the tag the banner was found by, the requested one
+	// or one of its ancestors.
+	MatchedTagID int64 `json:"matched_tag_id,omitempty"`
+	// Fallback is set when the banner was the default of the feature.
+	Fallback bool `json:"fallback,omitempty"`
+}
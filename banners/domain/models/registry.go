@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Registries of the IDs banners refer to.
+const (
+	RegistryFeatures = "features"
+	RegistryTags     = "tags"
+)
+
+// RegistryEntry names a feature or a tag. Archived entries stay for the
+// banners that use them, but new revisions cannot refer to them.
+type RegistryEntry struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	OwnerTeam   string `json:"owner_team"`
+	Archived    bool   `json:"archived"`
+	// ParentID is the tag this tag is nested in. Features have no parents.
+	ParentID  *int64    `json:"parent_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RegistryUpdate holds the fields of an entry to change, nil ones are kept.
+type RegistryUpdate struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	OwnerTeam   *string `json:"owner_team"`
+	Archived    *bool   `json:"archived"`
+	// ParentID moves a tag under another one, 0 makes it a root.
+	ParentID *int64 `json:"parent_id"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// RetentionPolicy decides which banner revisions are kept. See
+// config.Retention for the rules.
+type RetentionPolicy struct {
+	KeepLast int
+	KeepFor  time.Duration
+}
+
+// Enabled reports whether the policy prunes anything at all.
+func (p RetentionPolicy) Enabled() bool {
+	return p.KeepLast > 0 || p.KeepFor > 0
+}
+
+// PrunableRevision is a revision the retention policy would delete.
+type PrunableRevision struct {
+	RevisionID int64     `json:"revision_id"`
+	BannerID   int64     `json:"banner_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PrunePreview reports what the retention policy would delete.
+type PrunePreview struct {
+	KeepLast  int                `json:"keep_last"`
+	KeepFor   string             `json:"keep_for"`
+	Revisions []PrunableRevision `json:"revisions"`
+}
@@ -0,0 +1,24 @@
+package models
+
+const (
+	PermBannerRead         = "banner:read"
+	PermBannerReadInactive = "banner:read_inactive"
+	PermBannerList         = "banner:list"
+	PermBannerEdit         = "banner:edit"
+	PermBannerPublish      = "banner:publish"
+	PermBannerDelete       = "banner:delete"
+	PermBannerApprove      = "banner:approve"
+	PermFeatureManage      = "feature:manage"
+	PermTagManage          = "tag:manage"
+	PermRetentionManage    = "retention:manage"
+	PermUserManage         = "user:manage"
+	PermRoleManage         = "role:manage"
+	PermAPIKeyManage       = "apikey:manage"
+	PermAuditRead          = "audit:read"
+	PermTenantManage       = "tenant:manage"
+)
+
+type Role struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
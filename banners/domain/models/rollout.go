@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Rollout serves a revision of a banner to a percentage of its users before
+// the revision is chosen for all of them.
+type Rollout struct {
+	BannerID   int64     `json:"banner_id"`
+	RevisionID int64     `json:"revision_id"`
+	Percent    int       `json:"percent"`
+	CreatedBy  *int64    `json:"created_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// Candidate is the revision being rolled out. It is only filled in
+	// for user banners.
+	Candidate *Banner `json:"candidate,omitempty"`
+}
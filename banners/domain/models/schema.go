@@ -0,0 +1,17 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FeatureSchema is a version of the JSON Schema that banner content of a
+// feature must match. Versions are never changed, new revisions are checked
+// against the newest one and keep the version they were checked against.
+type FeatureSchema struct {
+	FeatureID int64           `json:"feature_id"`
+	Version   int             `json:"version"`
+	Schema    json.RawMessage `json:"schema"`
+	CreatedBy *int64          `json:"created_by,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
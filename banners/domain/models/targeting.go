@@ -0,0 +1,32 @@
+package models
+
+// Targeting narrows down the users a revision is served to beyond its tags.
+// Users must match every rule that is set, and unset rules match everyone.
+type Targeting struct {
+	// Platforms the user must be on, such as ios or android.
+	Platforms []string `json:"platforms,omitempty"`
+	// MinAppVersion and MaxAppVersion bound the dotted app version of the
+	// user, both inclusive.
+	MinAppVersion string `json:"min_app_version,omitempty"`
+	MaxAppVersion string `json:"max_app_version,omitempty"`
+	// Locales the user must have. A language such as en also matches the
+	// regional locales of it such as en-US.
+	Locales []string `json:"locales,omitempty"`
+	// Attributes maps custom attributes to the values the user may have.
+	Attributes map[string][]string `json:"attributes,omitempty"`
+}
+
+// IsEmpty reports whether the targeting has no rules.
+func (t *Targeting) IsEmpty() bool {
+	return len(t.Platforms) == 0 && t.MinAppVersion == "" && t.MaxAppVersion == "" && len(t.Locales) == 0 && len(t.Attributes) == 0
+}
+
+// Audience describes the user a banner is asked for. UserKey assigns the
+// user to experiments and rollouts, the rest is matched against targeting.
+type Audience struct {
+	UserKey    string
+	Platform   string
+	AppVersion string
+	Locale     string
+	Attributes map[string]string
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// DefaultTenantID is the tenant that owns everything created before
+// tenants were introduced, and the one public signups join.
+const DefaultTenantID = 1
+
+type Tenant struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,10 +1,26 @@
 package models
 
-import jwt "github.com/dgrijalva/jwt-go"
+import (
+	jwt "github.com/dgrijalva/jwt-go"
+	"time"
+)
 
 type Token struct {
-	UserID int64
-	Email  string
-	Role   string
+	UserID   int64
+	Email    string
+	Role     string
+	TenantID int64
 	*jwt.StandardClaims
 }
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
@@ -1,8 +1,38 @@
 package models
 
+import "encoding/json"
+
+const (
+	RoleUser       = "user"
+	RoleAdmin      = "admin"
+	RoleSuperAdmin = "superadmin"
+)
+
 type User struct {
 	ID       int64  `json:"id"`
 	Email    string `json:"email"`
 	Role     string `json:"role"`
 	Password string `json:"password"`
+	Disabled bool   `json:"disabled"`
+	TenantID int64  `json:"tenant_id"`
+}
+
+// Snapshot returns the user as the audit log records it, without the
+// password hash.
+func (u *User) Snapshot() json.RawMessage {
+	if u == nil {
+		return nil
+	}
+
+	return Snapshot(struct {
+		ID       int64  `json:"id"`
+		Email    string `json:"email"`
+		Role     string `json:"role"`
+		Disabled bool   `json:"disabled"`
+	}{
+		ID:       u.ID,
+		Email:    u.Email,
+		Role:     u.Role,
+		Disabled: u.Disabled,
+	})
 }
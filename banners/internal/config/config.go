@@ -13,6 +13,8 @@ type Config struct {
 	DataSourceName string `yaml:"data_source_name" env-default:"postgres://postgres:postgres@db:5432/postgres?sslmode=disable"`
 	HTTPServer     `yaml:"http_server"`
 	CacheStorage   `yaml:"cache_storage"`
+	Auth           `yaml:"auth"`
+	Retention      `yaml:"retention"`
 }
 
 type HTTPServer struct {
@@ -28,6 +30,53 @@ type CacheStorage struct {
 	Retries     int           `yaml:"retries"`
 }
 
+// Auth configures issuing and verification of access tokens.
+// Keys maps a key id (the "kid" token header) to an HMAC secret and
+// PrivateKeys maps a key id to a PEM file with an RSA (RS256) or Ed25519
+// (EdDSA) private key. New tokens are signed with SigningKeyID, while every
+// configured key is accepted on verification, so a key can be rotated by
+// adding a new one, switching SigningKeyID to it and removing the old one
+// once its tokens have expired. In env the keys are passed as
+// AUTH_KEYS="kid1:secret1,kid2:secret2" and AUTH_PRIVATE_KEYS="kid3:/path/key.pem".
+type Auth struct {
+	TokenTTL        time.Duration     `yaml:"token_ttl" env:"AUTH_TOKEN_TTL" env-default:"100m"`
+	RefreshTokenTTL time.Duration     `yaml:"refresh_token_ttl" env:"AUTH_REFRESH_TOKEN_TTL" env-default:"720h"`
+	SigningKeyID    string            `yaml:"signing_key_id" env:"AUTH_SIGNING_KEY_ID"`
+	Keys            map[string]string `yaml:"keys" env:"AUTH_KEYS"`
+	PrivateKeys     map[string]string `yaml:"private_keys" env:"AUTH_PRIVATE_KEYS"`
+	SignupEnabled   bool              `yaml:"signup_enabled" env:"AUTH_SIGNUP_ENABLED" env-default:"true"`
+	BootstrapAdmin  `yaml:"bootstrap_admin"`
+	LoginThrottle   `yaml:"login_throttle"`
+}
+
+// BootstrapAdmin is created on startup if it does not exist. Since public
+// signup can only create plain users, this is how the first admin appears.
+type BootstrapAdmin struct {
+	Email    string `yaml:"email" env:"BOOTSTRAP_ADMIN_EMAIL"`
+	Password string `yaml:"password" env:"BOOTSTRAP_ADMIN_PASSWORD"`
+}
+
+// LoginThrottle locks logins out for Lockout once an email or a client IP
+// has failed MaxAttempts or MaxIPAttempts times within Window. The IP limit
+// is higher, since several users may share an address.
+type LoginThrottle struct {
+	MaxAttempts   int           `yaml:"max_attempts" env:"LOGIN_MAX_ATTEMPTS" env-default:"5"`
+	MaxIPAttempts int           `yaml:"max_ip_attempts" env:"LOGIN_MAX_IP_ATTEMPTS" env-default:"20"`
+	Window        time.Duration `yaml:"window" env:"LOGIN_WINDOW" env-default:"15m"`
+	Lockout       time.Duration `yaml:"lockout" env:"LOGIN_LOCKOUT" env-default:"15m"`
+}
+
+// Retention prunes old banner revisions every Interval. A revision is kept
+// while it is one of the KeepLast newest revisions of its banner or younger
+// than KeepFor. A zero value turns a rule off, and with both rules off
+// nothing is pruned. Revisions that were ever published and revisions
+// waiting for approval are always kept.
+type Retention struct {
+	KeepLast int           `yaml:"keep_last" env:"RETENTION_KEEP_LAST" env-default:"10"`
+	KeepFor  time.Duration `yaml:"keep_for" env:"RETENTION_KEEP_FOR" env-default:"720h"`
+	Interval time.Duration `yaml:"interval" env:"RETENTION_INTERVAL" env-default:"1h"`
+}
+
 func MustLoad() *Config {
 	//env
 	configPath := os.Getenv("CONFIG_PATH")
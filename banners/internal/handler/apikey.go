@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"banners/domain/models"
+	"banners/internal/errorwriter"
+	"banners/internal/service"
+	"banners/internal/storage"
+	"banners/lib/logger/sl"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// CreateAPIKeyResponse carries the secret of a new key. It is the only
+// time the secret is shown.
+type CreateAPIKeyResponse struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+func (h *Handler) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.createAPIKey"
+
+	log := h.log.With(slog.String("op", op))
+
+	type createAPIKey struct {
+		Name       string   `json:"name"`
+		Scopes     []string `json:"scopes"`
+		FeatureIDs []int64  `json:"feature_ids"`
+	}
+
+	var keyReq createAPIKey
+	err := json.NewDecoder(r.Body).Decode(&keyReq)
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+		return
+	}
+
+	key, secret, err := h.userProvider.CreateAPIKey(r.Context(), identityFromContext(r.Context()), keyReq.Name, keyReq.Scopes, keyReq.FeatureIDs)
+	if errors.Is(err, service.ErrAPIKeyNameEmpty) {
+		log.Info("name is empty", sl.Err(err))
+		errorwriter.WriteError(w, "name is empty", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrScopeNotAllowed) || errors.Is(err, storage.ErrPermissionNotFound) {
+		log.Info("invalid scope", sl.Err(err))
+		errorwriter.WriteError(w, "scopes must be existing banner permissions", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrScopeNotGranted) {
+		log.Info("scope not granted", sl.Err(err))
+		errorwriter.WriteError(w, "scopes must be permissions you have", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.Error("failed to create api key", sl.Err(err))
+		errorwriter.WriteError(w, "failed to create api key", http.StatusInternalServerError)
+		return
+	}
+
+	responseJSON, err := json.Marshal(CreateAPIKeyResponse{APIKey: *key, Key: secret})
+	if err != nil {
+		log.Error("failed to marshal response", sl.Err(err))
+		errorwriter.WriteError(w, "failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(responseJSON)
+}
+
+func (h *Handler) listAPIKeys(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.listAPIKeys"
+
+	log := h.log.With(slog.String("op", op))
+
+	keys, err := h.userProvider.ListAPIKeys(r.Context(), identityFromContext(r.Context()).TenantID)
+	if err != nil {
+		log.Error("failed to list api keys", sl.Err(err))
+		errorwriter.WriteError(w, "failed to list api keys", http.StatusInternalServerError)
+		return
+	}
+
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(keys)
+	if err != nil {
+		log.Error("failed to list api keys", sl.Err(err))
+	}
+}
+
+func (h *Handler) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.revokeAPIKey"
+
+	log := h.log.With(slog.String("op", op))
+
+	keyID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Error("keyID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "keyID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	err = h.userProvider.RevokeAPIKey(r.Context(), identityFromContext(r.Context()), keyID)
+	if errors.Is(err, storage.ErrAPIKeyNotFound) {
+		log.Info("api key not found", sl.Err(err))
+		errorwriter.WriteError(w, "api key not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to revoke api key", sl.Err(err))
+		errorwriter.WriteError(w, "failed to revoke api key", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"banners/domain/models"
+	"banners/internal/errorwriter"
+	"banners/internal/service"
+	"banners/internal/storage"
+	"banners/lib/logger/sl"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type approvalComment struct {
+	Comment string `json:"comment"`
+}
+
+func (h *Handler) setFeaturePolicy(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.setFeaturePolicy"
+
+	log := h.log.With(slog.String("op", op))
+
+	featureID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Error("featureID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "featureID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	type setPolicy struct {
+		RequiresApproval *bool `json:"requires_approval"`
+	}
+
+	var policyReq setPolicy
+	err = json.NewDecoder(r.Body).Decode(&policyReq)
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+		return
+	}
+
+	if policyReq.RequiresApproval == nil {
+		log.Error("requires_approval is not provided")
+		errorwriter.WriteError(w, "requires_approval is not provided", http.StatusBadRequest)
+		return
+	}
+
+	policy := &models.FeaturePolicy{
+		FeatureID:        featureID,
+		RequiresApproval: *policyReq.RequiresApproval,
+	}
+
+	err = h.bannerProvider.SetFeaturePolicy(r.Context(), identityFromContext(r.Context()), policy)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrExperimentRunning) {
+		log.Info("experiment running", sl.Err(err))
+		errorwriter.WriteError(w, "an experiment is running for the feature", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("failed to set feature policy", sl.Err(err))
+		errorwriter.WriteError(w, "failed to set feature policy", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(policy)
+	if err != nil {
+		log.Error("failed to set feature policy", sl.Err(err))
+	}
+}
+
+// requestApproval asks for a revision of the banner to be approved. The
+// revision_id query parameter defaults to the newest revision.
+func (h *Handler) requestApproval(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.requestApproval"
+
+	log := h.log.With(slog.String("op", op))
+
+	bannerID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error("bannerID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "bannerID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	var revisionID int
+	if revisionIDStr := r.URL.Query().Get("revision_id"); revisionIDStr != "" {
+		revisionID, err = strconv.Atoi(revisionIDStr)
+		if err != nil {
+			log.Error("revisionID is not a number", sl.Err(err))
+			errorwriter.WriteError(w, "revisionID is not a number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	comment, ok := decodeApprovalComment(w, r, log)
+	if !ok {
+		return
+	}
+
+	request, err := h.bannerProvider.RequestApproval(r.Context(), identityFromContext(r.Context()), bannerID, revisionID, comment)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrBannerNotFound) || errors.Is(err, storage.ErrRevisionDoesNotExist) {
+		log.Info("revision not found", sl.Err(err))
+		errorwriter.WriteError(w, "revision not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrApprovalExists) {
+		log.Info("approval already requested", sl.Err(err))
+		errorwriter.WriteError(w, "revision already has a pending approval request", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("failed to request approval", sl.Err(err))
+		errorwriter.WriteError(w, "failed to request approval", http.StatusInternalServerError)
+		return
+	}
+
+	writeApprovalRequest(w, log, http.StatusCreated, request)
+}
+
+func (h *Handler) listApprovals(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.listApprovals"
+
+	log := h.log.With(slog.String("op", op))
+
+	bannerID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error("bannerID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "bannerID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+
+	if limitStr == "" {
+		limitStr = "20"
+	}
+
+	if offsetStr == "" {
+		offsetStr = "0"
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		log.Error("limit is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "limit is not a number", http.StatusBadRequest)
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		log.Error("offset is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "offset is not a number", http.StatusBadRequest)
+		return
+	}
+
+	if limit < 0 || limit > 100 {
+		log.Error("limit is out of range")
+		errorwriter.WriteError(w, "limit is out of range", http.StatusBadRequest)
+		return
+	}
+
+	if offset < 0 {
+		log.Error("offset is out of range")
+		errorwriter.WriteError(w, "offset is out of range", http.StatusBadRequest)
+		return
+	}
+
+	requests, err := h.bannerProvider.ListApprovalRequests(r.Context(), identityFromContext(r.Context()), bannerID, limit, offset)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.Error("failed to list approval requests", sl.Err(err))
+		errorwriter.WriteError(w, "failed to list approval requests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(requests)
+	if err != nil {
+		log.Error("failed to list approval requests", sl.Err(err))
+	}
+}
+
+func (h *Handler) approveRequest(w http.ResponseWriter, r *http.Request) {
+	h.decideRequest(w, r, "handler.approveRequest", h.bannerProvider.ApproveRequest)
+}
+
+func (h *Handler) rejectRequest(w http.ResponseWriter, r *http.Request) {
+	h.decideRequest(w, r, "handler.rejectRequest", h.bannerProvider.RejectRequest)
+}
+
+func (h *Handler) decideRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	op string,
+	decide func(ctx context.Context, actor *models.Identity, id int64, comment string) (*models.ApprovalRequest, error),
+) {
+	log := h.log.With(slog.String("op", op))
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Error("approvalID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "approvalID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	comment, ok := decodeApprovalComment(w, r, log)
+	if !ok {
+		return
+	}
+
+	request, err := decide(r.Context(), identityFromContext(r.Context()), id, comment)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrApprovalNotFound) {
+		log.Info("approval request not found", sl.Err(err))
+		errorwriter.WriteError(w, "approval request not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrSelfApproval) {
+		log.Info("self approval", sl.Err(err))
+		errorwriter.WriteError(w, "revision cannot be approved by its author or requester", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrApproverRequired) {
+		log.Info("approval by api key", sl.Err(err))
+		errorwriter.WriteError(w, "approvals must be made by a user", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrApprovalNotPending) {
+		log.Info("approval request already decided", sl.Err(err))
+		errorwriter.WriteError(w, "approval request has already been decided", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("failed to decide approval request", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decide approval request", http.StatusInternalServerError)
+		return
+	}
+
+	writeApprovalRequest(w, log, http.StatusOK, request)
+}
+
+// decodeApprovalComment reads the optional comment of an approval request
+// or decision. An empty body has no comment.
+func decodeApprovalComment(w http.ResponseWriter, r *http.Request, log *slog.Logger) (string, bool) {
+	var body approvalComment
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil && !errors.Is(err, io.EOF) {
+		log.Error("failed to decode request body", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+		return "", false
+	}
+
+	return body.Comment, true
+}
+
+func writeApprovalRequest(w http.ResponseWriter, log *slog.Logger, status int, request *models.ApprovalRequest) {
+	responseJSON, err := json.Marshal(request)
+	if err != nil {
+		log.Error("failed to marshal response", sl.Err(err))
+		errorwriter.WriteError(w, "failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(responseJSON)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"banners/domain/models"
+	"banners/internal/errorwriter"
+	"banners/lib/logger/sl"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// listAudit returns the audit log, newest first. It can be filtered by
+// actor_id, banner_id and a from/to time range in RFC 3339.
+func (h *Handler) listAudit(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.listAudit"
+
+	log := h.log.With(slog.String("op", op))
+
+	query := r.URL.Query()
+	limitStr := query.Get("limit")
+	offsetStr := query.Get("offset")
+
+	if limitStr == "" {
+		limitStr = "20"
+	}
+
+	if offsetStr == "" {
+		offsetStr = "0"
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		log.Error("limit is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "limit is not a number", http.StatusBadRequest)
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		log.Error("offset is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "offset is not a number", http.StatusBadRequest)
+		return
+	}
+
+	if limit < 0 || limit > 100 {
+		log.Error("limit is out of range")
+		errorwriter.WriteError(w, "limit is out of range", http.StatusBadRequest)
+		return
+	}
+
+	if offset < 0 {
+		log.Error("offset is out of range")
+		errorwriter.WriteError(w, "offset is out of range", http.StatusBadRequest)
+		return
+	}
+
+	filter := models.AuditFilter{
+		TenantID: identityFromContext(r.Context()).TenantID,
+		Limit:    limit,
+		Offset:   offset,
+	}
+
+	if actorIDStr := query.Get("actor_id"); actorIDStr != "" {
+		actorID, err := strconv.ParseInt(actorIDStr, 10, 64)
+		if err != nil {
+			log.Error("actorID is not a number", sl.Err(err))
+			errorwriter.WriteError(w, "actorID is not a number", http.StatusBadRequest)
+			return
+		}
+		filter.ActorID = &actorID
+	}
+
+	if bannerIDStr := query.Get("banner_id"); bannerIDStr != "" {
+		bannerID, err := strconv.ParseInt(bannerIDStr, 10, 64)
+		if err != nil {
+			log.Error("bannerID is not a number", sl.Err(err))
+			errorwriter.WriteError(w, "bannerID is not a number", http.StatusBadRequest)
+			return
+		}
+		filter.BannerID = &bannerID
+	}
+
+	if fromStr := query.Get("from"); fromStr != "" {
+		from, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			log.Error("from is not a valid time", sl.Err(err))
+			errorwriter.WriteError(w, "from is not a valid RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		from = from.UTC()
+		filter.From = &from
+	}
+
+	if toStr := query.Get("to"); toStr != "" {
+		to, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			log.Error("to is not a valid time", sl.Err(err))
+			errorwriter.WriteError(w, "to is not a valid RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		to = to.UTC()
+		filter.To = &to
+	}
+
+	entries, err := h.userProvider.ListAudit(r.Context(), filter)
+	if err != nil {
+		log.Error("failed to list audit entries", sl.Err(err))
+		errorwriter.WriteError(w, "failed to list audit entries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(entries)
+	if err != nil {
+		log.Error("failed to list audit entries", sl.Err(err))
+	}
+}
@@ -3,21 +3,38 @@ package handler
 import (
 	"banners/domain/models"
 	"banners/internal/errorwriter"
+	"banners/internal/service"
+	"banners/internal/storage"
 	"banners/lib/logger/sl"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 )
 
 type LoginResponse struct {
-	Message string `json:"message"`
-	Token   string `json:"token"`
+	Message      string `json:"message"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 type AuthProvider interface {
-	LoginUser(email, password string) (string, error)
+	LoginUser(ctx context.Context, email, password, ip string) (*models.TokenPair, error)
+	ParseToken(ctx context.Context, tokenString string) (*models.Token, error)
+	JWKS() models.JWKS
+	RefreshTokens(ctx context.Context, refreshToken string) (*models.TokenPair, error)
+	Logout(ctx context.Context, claims *models.Token, refreshToken string) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.Identity, error)
+	HasPermission(ctx context.Context, identity *models.Identity, permission string) (bool, error)
 }
 
 func (h *Handler) loginUser(w http.ResponseWriter, r *http.Request) {
@@ -46,16 +63,131 @@ func (h *Handler) loginUser(w http.ResponseWriter, r *http.Request) {
 
 	log.Info("request body decoded")
 
-	tokenString, err := h.authProvider.LoginUser(user.Email, user.Password)
+	tokens, err := h.authProvider.LoginUser(r.Context(), user.Email, user.Password, clientIP(r))
+	var locked *service.LoginLockedError
+	if errors.As(err, &locked) {
+		log.Warn("login locked out", sl.Err(err))
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		errorwriter.WriteError(w, "too many failed login attempts", http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		log.Info("invalid credentials", sl.Err(err))
+		errorwriter.WriteError(w, "invalid email or password", http.StatusUnauthorized)
+		return
+	}
+	if errors.Is(err, service.ErrUserDisabled) {
+		log.Info("user is disabled", sl.Err(err))
+		errorwriter.WriteError(w, "user is disabled", http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		log.Error("failed to login user", sl.Err(err))
-		errorwriter.WriteError(w, "failed to login user", http.StatusBadRequest)
+		errorwriter.WriteError(w, "failed to login user", http.StatusInternalServerError)
+		return
+	}
+
+	writeTokens(w, "Successfully logged in.", tokens)
+}
+
+func (h *Handler) refreshToken(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.refreshToken"
+
+	log := h.log.With(slog.String("op", op))
+
+	req := &refreshRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
 		return
 	}
 
+	if req.RefreshToken == "" {
+		log.Error("refresh token is empty")
+		errorwriter.WriteError(w, "refresh token is empty", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.authProvider.RefreshTokens(r.Context(), req.RefreshToken)
+	if errors.Is(err, storage.ErrRefreshTokenNotFound) ||
+		errors.Is(err, storage.ErrRefreshTokenRevoked) ||
+		errors.Is(err, storage.ErrRefreshTokenExpired) ||
+		errors.Is(err, storage.ErrUserNotFound) {
+		log.Info("refresh token rejected", sl.Err(err))
+		errorwriter.WriteError(w, "invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Error("failed to refresh token", sl.Err(err))
+		errorwriter.WriteError(w, "failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	writeTokens(w, "Successfully refreshed token.", tokens)
+}
+
+func (h *Handler) logoutUser(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.logoutUser"
+
+	log := h.log.With(slog.String("op", op))
+
+	claims, ok := h.authenticateToken(w, r)
+	if !ok {
+		return
+	}
+
+	// The refresh token is optional, so an empty body is fine.
+	req := &refreshRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		log.Error("failed to decode request body", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+		return
+	}
+
+	err = h.authProvider.Logout(r.Context(), claims, req.RefreshToken)
+	if errors.Is(err, storage.ErrRefreshTokenNotFound) {
+		log.Info("refresh token not found", sl.Err(err))
+		errorwriter.WriteError(w, "invalid refresh token", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Error("failed to logout user", sl.Err(err))
+		errorwriter.WriteError(w, "failed to logout user", http.StatusInternalServerError)
+		return
+	}
+
+	responseJSON, err := json.Marshal(struct {
+		Message string `json:"message"`
+	}{
+		Message: "Successfully logged out.",
+	})
+	if err != nil {
+		errorwriter.WriteError(w, "failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJSON)
+}
+
+// clientIP is the address the request came from, without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func writeTokens(w http.ResponseWriter, message string, tokens *models.TokenPair) {
 	response := LoginResponse{
-		Message: "Successfully logged in.",
-		Token:   tokenString,
+		Message:      message,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}
 
 	responseJSON, err := json.Marshal(response)
@@ -65,7 +197,25 @@ func (h *Handler) loginUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Authorization", "Bearer "+tokenString)
+	w.Header().Set("Authorization", "Bearer "+tokens.AccessToken)
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJSON)
+}
+
+func (h *Handler) getJWKS(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.getJWKS"
+
+	log := h.log.With(slog.String("op", op))
+
+	responseJSON, err := json.Marshal(h.authProvider.JWKS())
+	if err != nil {
+		log.Error("failed to marshal response", sl.Err(err))
+		errorwriter.WriteError(w, "failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
 	w.WriteHeader(http.StatusOK)
 	w.Write(responseJSON)
 }
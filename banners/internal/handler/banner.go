@@ -3,6 +3,7 @@ package handler
 import (
 	"banners/domain/models"
 	"banners/internal/errorwriter"
+	"banners/internal/service"
 	"banners/internal/storage"
 	"banners/lib/logger/sl"
 	"context"
@@ -13,19 +14,49 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type BannerProvider interface {
-	PostBanner(ctx context.Context, banner *models.Banner) (int, error)
-	GetUserBanner(ctx context.Context, tagID int, featureID int) (*models.Banner, error)
-	ChooseRevision(ctx context.Context, bannerID int, revisionID int) error
-	ListRevisions(ctx context.Context, bannerID int, limit int, offset int) (*[]models.Banner, error)
-	ListBanners(ctx context.Context, featureID int, tagID int, limit int, offset int) (*[]models.Banner, error)
-	DeleteBanner(ctx context.Context, bannerID int) error
-	DeleteUserBannerByFeatureTag(ctx context.Context, tagID int, featureID int) error
-	PatchBanner(ctx context.Context, banner *models.Banner) error
-	GetUserBannerCache(ctx context.Context, tagID int, featureID int) (*models.Banner, error)
-	SetUserBannerCache(ctx context.Context, tagID int, featureID int, banner models.Banner) error
+	PostBanner(ctx context.Context, actor *models.Identity, banner *models.Banner) (int, error)
+	GetUserBanner(ctx context.Context, tenantID int64, tagID int, featureID int, audience *models.Audience) (*models.Banner, error)
+	GetUserBannerAsOf(ctx context.Context, tenantID int64, tagID int, featureID int, at time.Time) (*models.LiveBanner, error)
+	ChooseRevision(ctx context.Context, actor *models.Identity, bannerID int, revisionID int) error
+	ListRevisions(ctx context.Context, identity *models.Identity, bannerID int, limit int, offset int) (*[]models.Banner, error)
+	ListBanners(ctx context.Context, tenantID int64, featureID int, tagID int, includeScheduled bool, includeNames bool, limit int, offset int) (*[]models.Banner, error)
+	DeleteBanner(ctx context.Context, actor *models.Identity, bannerID int) error
+	DeleteUserBannerByFeatureTag(ctx context.Context, actor *models.Identity, tagID int, featureID int) error
+	PatchBanner(ctx context.Context, actor *models.Identity, banner *models.Banner, publish bool) (int, error)
+	PublishRevision(ctx context.Context, actor *models.Identity, bannerID int, revisionID int) (int, error)
+	RollbackBanner(ctx context.Context, actor *models.Identity, bannerID int, steps int) (int, error)
+	SetFeaturePolicy(ctx context.Context, actor *models.Identity, policy *models.FeaturePolicy) error
+	CreateFeatureSchema(ctx context.Context, actor *models.Identity, schema *models.FeatureSchema) (*models.FeatureSchema, error)
+	CreateRegistryEntry(ctx context.Context, actor *models.Identity, registry string, entry *models.RegistryEntry) (*models.RegistryEntry, error)
+	GetRegistryEntry(ctx context.Context, tenantID int64, registry string, id int64) (*models.RegistryEntry, error)
+	ListRegistryEntries(ctx context.Context, tenantID int64, registry string, includeArchived bool, limit int, offset int) ([]models.RegistryEntry, error)
+	UpdateRegistryEntry(ctx context.Context, actor *models.Identity, registry string, id int64, update *models.RegistryUpdate) (*models.RegistryEntry, error)
+	DeleteRegistryEntry(ctx context.Context, actor *models.Identity, registry string, id int64) error
+	SetDefaultBanner(ctx context.Context, actor *models.Identity, featureDefault *models.FeatureDefault) error
+	GetDefaultBanner(ctx context.Context, tenantID int64, featureID int64) (*models.FeatureDefault, error)
+	DeleteDefaultBanner(ctx context.Context, actor *models.Identity, featureID int64) error
+	ListFeatureSchemas(ctx context.Context, tenantID int64, featureID int64) ([]models.FeatureSchema, error)
+	CreateExperiment(ctx context.Context, actor *models.Identity, experiment *models.Experiment) (*models.Experiment, error)
+	GetExperiment(ctx context.Context, tenantID int64, id int64) (*models.Experiment, error)
+	ListExperiments(ctx context.Context, tenantID int64, featureID int64, tagID int64, limit int, offset int) ([]models.Experiment, error)
+	StopExperiment(ctx context.Context, actor *models.Identity, id int64) (*models.Experiment, error)
+	StartRollout(ctx context.Context, actor *models.Identity, rollout *models.Rollout) (*models.Rollout, error)
+	GetRollout(ctx context.Context, identity *models.Identity, bannerID int) (*models.Rollout, error)
+	SetRolloutPercent(ctx context.Context, actor *models.Identity, bannerID int, percent int) (*models.Rollout, error)
+	FinalizeRollout(ctx context.Context, actor *models.Identity, bannerID int) (int, error)
+	AbortRollout(ctx context.Context, actor *models.Identity, bannerID int) error
+	RequestApproval(ctx context.Context, actor *models.Identity, bannerID int, revisionID int, comment string) (*models.ApprovalRequest, error)
+	ListApprovalRequests(ctx context.Context, identity *models.Identity, bannerID int, limit int, offset int) ([]models.ApprovalRequest, error)
+	ApproveRequest(ctx context.Context, actor *models.Identity, id int64, comment string) (*models.ApprovalRequest, error)
+	RejectRequest(ctx context.Context, actor *models.Identity, id int64, comment string) (*models.ApprovalRequest, error)
+	DiffRevisions(ctx context.Context, identity *models.Identity, bannerID int, fromID int, toID int) (*models.RevisionDiff, error)
+	PreviewPrune(ctx context.Context, tenantID int64, limit int, offset int) (*models.PrunePreview, error)
+	GetUserBannerCache(ctx context.Context, tenantID int64, tagID int, featureID int, audience *models.Audience) (*models.Banner, error)
+	SetUserBannerCache(ctx context.Context, tenantID int64, tagID int, featureID int, banners []models.Banner) error
 }
 
 func (h *Handler) postBanner(w http.ResponseWriter, r *http.Request) {
@@ -34,10 +65,13 @@ func (h *Handler) postBanner(w http.ResponseWriter, r *http.Request) {
 	log := h.log.With(slog.String("op", op))
 
 	type postBanner struct {
-		Content   json.RawMessage `json:"content"`
-		FeatureID *int64          `json:"feature_id"`
-		TagIDs    []int64         `json:"tag_ids"`
-		IsActive  *bool           `json:"is_active"`
+		Content   json.RawMessage   `json:"content"`
+		FeatureID *int64            `json:"feature_id"`
+		TagIDs    []int64           `json:"tag_ids"`
+		IsActive  *bool             `json:"is_active"`
+		StartsAt  *time.Time        `json:"starts_at"`
+		EndsAt    *time.Time        `json:"ends_at"`
+		Targeting *models.Targeting `json:"targeting"`
 	}
 
 	var bannerReq postBanner
@@ -66,9 +100,42 @@ func (h *Handler) postBanner(w http.ResponseWriter, r *http.Request) {
 		TagIDs:    bannerReq.TagIDs,
 		Content:   bannerReq.Content,
 		IsActive:  *bannerReq.IsActive,
+		StartsAt:  bannerReq.StartsAt,
+		EndsAt:    bannerReq.EndsAt,
+		Targeting: bannerReq.Targeting,
 	}
 
-	bannerID, err := h.bannerProvider.PostBanner(r.Context(), banner)
+	bannerID, err := h.bannerProvider.PostBanner(r.Context(), identityFromContext(r.Context()), banner)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidSchedule) {
+		log.Info("invalid schedule", sl.Err(err))
+		errorwriter.WriteError(w, "ends_at must be after starts_at", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidTargeting) {
+		log.Info("invalid targeting", sl.Err(err))
+		errorwriter.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var unregisteredErr *service.UnregisteredError
+	if errors.As(err, &unregisteredErr) {
+		log.Info("unregistered ids", sl.Err(err))
+		errorwriter.WriteError(w, unregisteredErr.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if writeContentError(w, log, err) {
+		return
+	}
+	var conflictErr *storage.BannerConflictError
+	if errors.As(err, &conflictErr) {
+		log.Info("banner conflict", sl.Err(err))
+		errorwriter.WriteError(w, fmt.Sprintf("banner %d already has feature %d and tag %d", conflictErr.BannerID, conflictErr.FeatureID, conflictErr.TagID), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		log.Error("failed to create banner", sl.Err(err))
 		errorwriter.WriteError(w, "failed to create banner", http.StatusInternalServerError)
@@ -129,6 +196,12 @@ func (h *Handler) getUserBanner(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !identityFromContext(r.Context()).CanAccessFeature(int64(featureID)) {
+		log.Info("feature is not allowed for the api key")
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+
 	var useLastRev bool
 	if useLastRevStr == "" {
 		useLastRev = false
@@ -141,16 +214,35 @@ func (h *Handler) getUserBanner(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Experiments assign variants by user_key, or by the user of the token
+	// for callers that do not give one.
+	userKey := r.URL.Query().Get("user_key")
+	if userKey == "" && identityFromContext(r.Context()).UserID != 0 {
+		userKey = strconv.FormatInt(identityFromContext(r.Context()).UserID, 10)
+	}
+
+	audience := audienceFromRequest(r, userKey)
+
 	var banner *models.Banner
 	if useLastRev == false {
-		banner, err = h.bannerProvider.GetUserBannerCache(r.Context(), tagID, featureID)
+		banner, err = h.bannerProvider.GetUserBannerCache(r.Context(), identityFromContext(r.Context()).TenantID, tagID, featureID, audience)
+		if errors.Is(err, service.ErrInvalidAppVersion) {
+			log.Info("invalid app version", sl.Err(err))
+			errorwriter.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			log.Error("failed to get banner", sl.Err(err))
 			errorwriter.WriteError(w, "failed to get banner", http.StatusNotFound)
 			return
 		}
 	} else {
-		banner, err = h.bannerProvider.GetUserBanner(r.Context(), tagID, featureID)
+		banner, err = h.bannerProvider.GetUserBanner(r.Context(), identityFromContext(r.Context()).TenantID, tagID, featureID, audience)
+		if errors.Is(err, service.ErrInvalidAppVersion) {
+			log.Info("invalid app version", sl.Err(err))
+			errorwriter.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if errors.Is(err, storage.ErrBannerNotFound) {
 			log.Info("banner not found", sl.Err(err))
 			errorwriter.WriteError(w, "banner not found", http.StatusNotFound)
@@ -164,19 +256,34 @@ func (h *Handler) getUserBanner(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if banner.IsActive == false {
-		if r.Context().Value("role") != "admin" {
-			log.Error("you are not admin")
-			errorwriter.WriteError(w, "you are not admin", http.StatusUnauthorized)
+		allowed, err := h.authProvider.HasPermission(r.Context(), identityFromContext(r.Context()), models.PermBannerReadInactive)
+		if err != nil {
+			log.Error("failed to check permission", sl.Err(err))
+			errorwriter.WriteError(w, "failed to get banner", http.StatusInternalServerError)
+			return
+		}
+
+		if !allowed {
+			log.Error("not allowed to view inactive banners")
+			errorwriter.WriteError(w, "not allowed to view inactive banners", http.StatusUnauthorized)
 			return
 		}
 	}
 
 	type createBanner struct {
-		Content json.RawMessage `json:"content"`
+		Content      json.RawMessage `json:"content"`
+		MatchedTagID int64           `json:"matched_tag_id,omitempty"`
+		Fallback     bool            `json:"fallback,omitempty"`
+		Variant      string          `json:"variant,omitempty"`
+		Canary       bool            `json:"canary,omitempty"`
 	}
 
 	response := createBanner{
-		Content: banner.Content,
+		Content:      banner.Content,
+		MatchedTagID: banner.MatchedTagID,
+		Fallback:     banner.Fallback,
+		Variant:      banner.Variant,
+		Canary:       banner.Canary,
 	}
 
 	responseJSON, err := json.Marshal(response)
@@ -195,6 +302,65 @@ func (h *Handler) getUserBanner(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// getUserBannerAsOf tells which banner users of the tag saw for the feature
+// at the time given in RFC 3339.
+func (h *Handler) getUserBannerAsOf(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.getUserBannerAsOf"
+
+	log := h.log.With(slog.String("op", op))
+
+	tagIDStr := r.URL.Query().Get("tag_id")
+	featureIDStr := r.URL.Query().Get("feature_id")
+	atStr := r.URL.Query().Get("at")
+
+	if tagIDStr == "" || featureIDStr == "" || atStr == "" {
+		log.Error("tagID, featureID or at is not provided")
+		errorwriter.WriteError(w, "tagID, featureID or at is not provided", http.StatusBadRequest)
+		return
+	}
+
+	tagID, err := strconv.Atoi(tagIDStr)
+	if err != nil {
+		log.Error("tagID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "tagID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	featureID, err := strconv.Atoi(featureIDStr)
+	if err != nil {
+		log.Error("featureID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "featureID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	at, err := time.Parse(time.RFC3339, atStr)
+	if err != nil {
+		log.Error("at is not a RFC 3339 time", sl.Err(err))
+		errorwriter.WriteError(w, "at is not a RFC 3339 time", http.StatusBadRequest)
+		return
+	}
+
+	banner, err := h.bannerProvider.GetUserBannerAsOf(r.Context(), identityFromContext(r.Context()).TenantID, tagID, featureID, at)
+	if errors.Is(err, storage.ErrBannerNotFound) {
+		log.Info("banner not found", sl.Err(err))
+		errorwriter.WriteError(w, "banner not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to get banner", sl.Err(err))
+		errorwriter.WriteError(w, "failed to get banner", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(banner)
+	if err != nil {
+		log.Error("failed to get banner", sl.Err(err))
+	}
+}
+
 func (h *Handler) chooseBanner(w http.ResponseWriter, r *http.Request) {
 	const op = "handler.chooseBanner"
 
@@ -223,12 +389,33 @@ func (h *Handler) chooseBanner(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.bannerProvider.ChooseRevision(r.Context(), bannerID, revisionID)
+	err = h.bannerProvider.ChooseRevision(r.Context(), identityFromContext(r.Context()), bannerID, revisionID)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrBannerNotFound) || errors.Is(err, storage.ErrRevisionDoesNotExist) {
+		log.Info("revision not found", sl.Err(err))
+		errorwriter.WriteError(w, "revision not found", http.StatusNotFound)
+		return
+	}
 	if errors.Is(err, storage.ErrFailedRevisionChange) {
 		log.Info("failed to choose a revision", sl.Err(err))
 		errorwriter.WriteError(w, "failed to choose a revision", http.StatusBadRequest)
 		return
 	}
+	if errors.Is(err, storage.ErrApprovalRequired) {
+		log.Info("revision is not approved", sl.Err(err))
+		errorwriter.WriteError(w, "revision needs an approval to be published", http.StatusForbidden)
+		return
+	}
+	var conflictErr *storage.BannerConflictError
+	if errors.As(err, &conflictErr) {
+		log.Info("banner conflict", sl.Err(err))
+		errorwriter.WriteError(w, fmt.Sprintf("banner %d already has feature %d and tag %d", conflictErr.BannerID, conflictErr.FeatureID, conflictErr.TagID), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		log.Error("failed to choose a version", sl.Err(err))
 		errorwriter.WriteError(w, "failed to choose a version", http.StatusBadRequest)
@@ -318,7 +505,12 @@ func (h *Handler) listRevisions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	revisions, err := h.bannerProvider.ListRevisions(r.Context(), bannerID, limit, offset)
+	revisions, err := h.bannerProvider.ListRevisions(r.Context(), identityFromContext(r.Context()), bannerID, limit, offset)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		log.Error("failed to list revisions", sl.Err(err))
 		errorwriter.WriteError(w, "failed to list revisions", http.StatusInternalServerError)
@@ -350,6 +542,8 @@ func (h *Handler) listBanners(w http.ResponseWriter, r *http.Request) {
 	featureIDStr := r.URL.Query().Get("feature_id")
 	limitStr := r.URL.Query().Get("limit")
 	offsetStr := r.URL.Query().Get("offset")
+	includeScheduledStr := r.URL.Query().Get("include_scheduled")
+	includeNamesStr := r.URL.Query().Get("include_names")
 
 	if tagIDStr == "" || featureIDStr == "" {
 		log.Error("tagID or featureID is not provided")
@@ -371,6 +565,12 @@ func (h *Handler) listBanners(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !identityFromContext(r.Context()).CanAccessFeature(int64(featureID)) {
+		log.Info("feature is not allowed for the api key")
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+
 	if limitStr == "" {
 		limitStr = "5"
 	}
@@ -405,7 +605,27 @@ func (h *Handler) listBanners(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	banners, err := h.bannerProvider.ListBanners(r.Context(), featureID, tagID, limit, offset)
+	var includeScheduled bool
+	if includeScheduledStr != "" {
+		includeScheduled, err = strconv.ParseBool(includeScheduledStr)
+		if err != nil {
+			log.Error("includeScheduled is not a bool", sl.Err(err))
+			errorwriter.WriteError(w, "include_scheduled is not a bool", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var includeNames bool
+	if includeNamesStr != "" {
+		includeNames, err = strconv.ParseBool(includeNamesStr)
+		if err != nil {
+			log.Error("includeNames is not a bool", sl.Err(err))
+			errorwriter.WriteError(w, "include_names is not a bool", http.StatusBadRequest)
+			return
+		}
+	}
+
+	banners, err := h.bannerProvider.ListBanners(r.Context(), identityFromContext(r.Context()).TenantID, featureID, tagID, includeScheduled, includeNames, limit, offset)
 	if err != nil {
 		log.Error("failed to list banners", sl.Err(err))
 		errorwriter.WriteError(w, "failed to list banners", http.StatusInternalServerError)
@@ -468,7 +688,71 @@ func (h *Handler) patchBanner(w http.ResponseWriter, r *http.Request) {
 
 	log.Info("request body decoded")
 
-	err = h.bannerProvider.PatchBanner(r.Context(), banner)
+	var publish bool
+	if publishStr := r.URL.Query().Get("publish"); publishStr != "" {
+		publish, err = strconv.ParseBool(publishStr)
+		if err != nil {
+			log.Error("publish is not a bool", sl.Err(err))
+			errorwriter.WriteError(w, "publish is not a bool", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Editors may prepare drafts, publishing them takes banner:publish.
+	if publish {
+		allowed, err := h.authProvider.HasPermission(r.Context(), identityFromContext(r.Context()), models.PermBannerPublish)
+		if err != nil {
+			log.Error("failed to check permission", sl.Err(err))
+			errorwriter.WriteError(w, "failed to check permission", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			errorwriter.WriteError(w, "permission denied", http.StatusForbidden)
+			return
+		}
+	}
+
+	revisionID, err := h.bannerProvider.PatchBanner(r.Context(), identityFromContext(r.Context()), banner, publish)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrBannerNotFound) {
+		log.Info("banner not found", sl.Err(err))
+		errorwriter.WriteError(w, "banner not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidSchedule) {
+		log.Info("invalid schedule", sl.Err(err))
+		errorwriter.WriteError(w, "ends_at must be after starts_at", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidTargeting) {
+		log.Info("invalid targeting", sl.Err(err))
+		errorwriter.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var unregisteredErr *service.UnregisteredError
+	if errors.As(err, &unregisteredErr) {
+		log.Info("unregistered ids", sl.Err(err))
+		errorwriter.WriteError(w, unregisteredErr.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if writeContentError(w, log, err) {
+		return
+	}
+	if errors.Is(err, storage.ErrApprovalRequired) {
+		log.Info("revision is not approved", sl.Err(err))
+		errorwriter.WriteError(w, "revision needs an approval to be published", http.StatusForbidden)
+		return
+	}
+	var conflictErr *storage.BannerConflictError
+	if errors.As(err, &conflictErr) {
+		log.Info("banner conflict", sl.Err(err))
+		errorwriter.WriteError(w, fmt.Sprintf("banner %d already has feature %d and tag %d", conflictErr.BannerID, conflictErr.FeatureID, conflictErr.TagID), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		log.Error("failed to patch banner", sl.Err(err))
 		errorwriter.WriteError(w, "failed to patch banner", http.StatusBadRequest)
@@ -477,13 +761,218 @@ func (h *Handler) patchBanner(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_, err = w.Write([]byte(fmt.Sprintf("patched banner: %v", bannerID)))
+	_, err = w.Write([]byte(fmt.Sprintf("patched banner: %v, revision: %v", bannerID, revisionID)))
 	if err != nil {
 		log.Error("failed to patch a banner", sl.Err(err))
 		errorwriter.WriteError(w, "failed to patch a banner", http.StatusInternalServerError)
 	}
 }
 
+func (h *Handler) publishBanner(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.publishBanner"
+
+	log := h.log.With(slog.String("op", op))
+
+	bannerID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error("bannerID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "bannerID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	var revisionID int
+	if revisionIDStr := r.URL.Query().Get("revision_id"); revisionIDStr != "" {
+		revisionID, err = strconv.Atoi(revisionIDStr)
+		if err != nil {
+			log.Error("revisionID is not a number", sl.Err(err))
+			errorwriter.WriteError(w, "revisionID is not a number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	revisionID, err = h.bannerProvider.PublishRevision(r.Context(), identityFromContext(r.Context()), bannerID, revisionID)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrBannerNotFound) || errors.Is(err, storage.ErrRevisionDoesNotExist) {
+		log.Info("revision not found", sl.Err(err))
+		errorwriter.WriteError(w, "revision not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrApprovalRequired) {
+		log.Info("revision is not approved", sl.Err(err))
+		errorwriter.WriteError(w, "revision needs an approval to be published", http.StatusForbidden)
+		return
+	}
+	var conflictErr *storage.BannerConflictError
+	if errors.As(err, &conflictErr) {
+		log.Info("banner conflict", sl.Err(err))
+		errorwriter.WriteError(w, fmt.Sprintf("banner %d already has feature %d and tag %d", conflictErr.BannerID, conflictErr.FeatureID, conflictErr.TagID), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("failed to publish revision", sl.Err(err))
+		errorwriter.WriteError(w, "failed to publish revision", http.StatusInternalServerError)
+		return
+	}
+
+	type publishResponse struct {
+		Message    string `json:"message"`
+		BannerID   int    `json:"banner_id"`
+		RevisionID int    `json:"revision_id"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(publishResponse{
+		Message:    "Successfully published a revision",
+		BannerID:   bannerID,
+		RevisionID: revisionID,
+	})
+	if err != nil {
+		log.Error("failed to publish revision", sl.Err(err))
+	}
+}
+
+// rollbackBanner goes back the given number of steps, one by default,
+// through the publication history of the banner.
+func (h *Handler) rollbackBanner(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.rollbackBanner"
+
+	log := h.log.With(slog.String("op", op))
+
+	bannerID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error("bannerID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "bannerID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	steps := 1
+	if stepsStr := r.URL.Query().Get("steps"); stepsStr != "" {
+		steps, err = strconv.Atoi(stepsStr)
+		if err != nil {
+			log.Error("steps is not a number", sl.Err(err))
+			errorwriter.WriteError(w, "steps is not a number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	revisionID, err := h.bannerProvider.RollbackBanner(r.Context(), identityFromContext(r.Context()), bannerID, steps)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidSteps) {
+		log.Info("invalid steps", sl.Err(err))
+		errorwriter.WriteError(w, "steps must be positive", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, storage.ErrBannerNotFound) {
+		log.Info("banner not found", sl.Err(err))
+		errorwriter.WriteError(w, "banner not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrNothingToRollBack) {
+		log.Info("nothing to roll back", sl.Err(err))
+		errorwriter.WriteError(w, "banner has no earlier publication to roll back to", http.StatusConflict)
+		return
+	}
+	var conflictErr *storage.BannerConflictError
+	if errors.As(err, &conflictErr) {
+		log.Info("banner conflict", sl.Err(err))
+		errorwriter.WriteError(w, fmt.Sprintf("banner %d already has feature %d and tag %d", conflictErr.BannerID, conflictErr.FeatureID, conflictErr.TagID), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("failed to roll back banner", sl.Err(err))
+		errorwriter.WriteError(w, "failed to roll back banner", http.StatusInternalServerError)
+		return
+	}
+
+	type rollbackResponse struct {
+		Message    string `json:"message"`
+		BannerID   int    `json:"banner_id"`
+		RevisionID int    `json:"revision_id"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(rollbackResponse{
+		Message:    "Successfully rolled back banner",
+		BannerID:   bannerID,
+		RevisionID: revisionID,
+	})
+	if err != nil {
+		log.Error("failed to roll back banner", sl.Err(err))
+	}
+}
+
+// diffRevisions compares the revisions from and to of the banner.
+func (h *Handler) diffRevisions(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.diffRevisions"
+
+	log := h.log.With(slog.String("op", op))
+
+	bannerID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error("bannerID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "bannerID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	fromStr := r.URL.Query().Get("from")
+	toStr := r.URL.Query().Get("to")
+
+	if fromStr == "" || toStr == "" {
+		log.Error("from or to is not provided")
+		errorwriter.WriteError(w, "from or to is not provided", http.StatusBadRequest)
+		return
+	}
+
+	fromID, err := strconv.Atoi(fromStr)
+	if err != nil {
+		log.Error("from is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "from is not a number", http.StatusBadRequest)
+		return
+	}
+
+	toID, err := strconv.Atoi(toStr)
+	if err != nil {
+		log.Error("to is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "to is not a number", http.StatusBadRequest)
+		return
+	}
+
+	diff, err := h.bannerProvider.DiffRevisions(r.Context(), identityFromContext(r.Context()), bannerID, fromID, toID)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrRevisionDoesNotExist) {
+		log.Info("revision not found", sl.Err(err))
+		errorwriter.WriteError(w, "revision not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to diff revisions", sl.Err(err))
+		errorwriter.WriteError(w, "failed to diff revisions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(diff)
+	if err != nil {
+		log.Error("failed to diff revisions", sl.Err(err))
+	}
+}
+
 func (h *Handler) deleteBanner(w http.ResponseWriter, r *http.Request) {
 	const op = "storage.postgresql.deleteBanner"
 
@@ -504,7 +993,17 @@ func (h *Handler) deleteBanner(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.bannerProvider.DeleteBanner(r.Context(), bannerID)
+	err = h.bannerProvider.DeleteBanner(r.Context(), identityFromContext(r.Context()), bannerID)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrBannerNotFound) {
+		log.Info("banner not found", sl.Err(err))
+		errorwriter.WriteError(w, "banner not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		log.Error("failed to delete banner", sl.Err(err))
 		errorwriter.WriteError(w, "failed to delete banner", http.StatusBadRequest)
@@ -549,7 +1048,13 @@ func (h *Handler) deleteBannerFeatureTag(deleteCtx context.Context) http.Handler
 			return
 		}
 
-		err = h.bannerProvider.DeleteUserBannerByFeatureTag(deleteCtx, tagID, featureID)
+		if !identityFromContext(r.Context()).CanAccessFeature(int64(featureID)) {
+			log.Info("feature is not allowed for the api key")
+			errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+			return
+		}
+
+		err = h.bannerProvider.DeleteUserBannerByFeatureTag(deleteCtx, identityFromContext(r.Context()), tagID, featureID)
 		if err != nil {
 			log.Error("failed to delete banner", sl.Err(err))
 			errorwriter.WriteError(w, "failed to delete banner", http.StatusBadRequest)
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"banners/domain/models"
+	"banners/internal/errorwriter"
+	"banners/internal/service"
+	"banners/internal/storage"
+	"banners/lib/logger/sl"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+func (h *Handler) createExperiment(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.createExperiment"
+
+	log := h.log.With(slog.String("op", op))
+
+	type createExperiment struct {
+		FeatureID int64            `json:"feature_id"`
+		TagID     int64            `json:"tag_id"`
+		Name      string           `json:"name"`
+		Variants  []models.Variant `json:"variants"`
+	}
+
+	var experimentReq createExperiment
+	err := json.NewDecoder(r.Body).Decode(&experimentReq)
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+		return
+	}
+
+	experiment := &models.Experiment{
+		FeatureID: experimentReq.FeatureID,
+		TagID:     experimentReq.TagID,
+		Name:      experimentReq.Name,
+		Variants:  experimentReq.Variants,
+	}
+
+	if !identityFromContext(r.Context()).CanAccessFeature(experiment.FeatureID) {
+		log.Info("feature is not allowed for the api key")
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+
+	created, err := h.bannerProvider.CreateExperiment(r.Context(), identityFromContext(r.Context()), experiment)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrExperimentTarget) || errors.Is(err, service.ErrExperimentName) || errors.Is(err, service.ErrExperimentVariants) {
+		log.Info("invalid experiment", sl.Err(err))
+		errorwriter.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var unregisteredErr *service.UnregisteredError
+	if errors.As(err, &unregisteredErr) {
+		log.Info("unregistered ids", sl.Err(err))
+		errorwriter.WriteError(w, unregisteredErr.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if writeContentError(w, log, err) {
+		return
+	}
+	if errors.Is(err, storage.ErrApprovalRequired) {
+		log.Info("feature requires approval", sl.Err(err))
+		errorwriter.WriteError(w, "feature requires approval, experiments are not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrExperimentRunning) {
+		log.Info("experiment already running", sl.Err(err))
+		errorwriter.WriteError(w, "another experiment is running for the feature and tag", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("failed to create experiment", sl.Err(err))
+		errorwriter.WriteError(w, "failed to create experiment", http.StatusInternalServerError)
+		return
+	}
+
+	writeExperiment(w, log, http.StatusCreated, created)
+}
+
+func (h *Handler) getExperiment(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.getExperiment"
+
+	log := h.log.With(slog.String("op", op))
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Error("id is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "id is not a number", http.StatusBadRequest)
+		return
+	}
+
+	experiment, err := h.bannerProvider.GetExperiment(r.Context(), identityFromContext(r.Context()).TenantID, id)
+	if errors.Is(err, storage.ErrExperimentNotFound) {
+		log.Info("experiment not found", sl.Err(err))
+		errorwriter.WriteError(w, "experiment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to get experiment", sl.Err(err))
+		errorwriter.WriteError(w, "failed to get experiment", http.StatusInternalServerError)
+		return
+	}
+
+	if !identityFromContext(r.Context()).CanAccessFeature(experiment.FeatureID) {
+		log.Info("feature is not allowed for the api key")
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+
+	writeExperiment(w, log, http.StatusOK, experiment)
+}
+
+// listExperiments lists the experiments of the tenant, of the feature and
+// the tag only if feature_id or tag_id are given.
+func (h *Handler) listExperiments(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.listExperiments"
+
+	log := h.log.With(slog.String("op", op))
+
+	featureIDStr := r.URL.Query().Get("feature_id")
+	tagIDStr := r.URL.Query().Get("tag_id")
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+
+	if limitStr == "" {
+		limitStr = "20"
+	}
+
+	if offsetStr == "" {
+		offsetStr = "0"
+	}
+
+	var featureID, tagID int64
+	var err error
+	if featureIDStr != "" {
+		featureID, err = strconv.ParseInt(featureIDStr, 10, 64)
+		if err != nil {
+			log.Error("featureID is not a number", sl.Err(err))
+			errorwriter.WriteError(w, "featureID is not a number", http.StatusBadRequest)
+			return
+		}
+
+		if !identityFromContext(r.Context()).CanAccessFeature(featureID) {
+			log.Info("feature is not allowed for the api key")
+			errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+			return
+		}
+	}
+
+	if tagIDStr != "" {
+		tagID, err = strconv.ParseInt(tagIDStr, 10, 64)
+		if err != nil {
+			log.Error("tagID is not a number", sl.Err(err))
+			errorwriter.WriteError(w, "tagID is not a number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		log.Error("limit is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "limit is not a number", http.StatusBadRequest)
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		log.Error("offset is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "offset is not a number", http.StatusBadRequest)
+		return
+	}
+
+	if limit < 0 || limit > 100 {
+		log.Error("limit is out of range")
+		errorwriter.WriteError(w, "limit is out of range", http.StatusBadRequest)
+		return
+	}
+
+	if offset < 0 {
+		log.Error("offset is out of range")
+		errorwriter.WriteError(w, "offset is out of range", http.StatusBadRequest)
+		return
+	}
+
+	experiments, err := h.bannerProvider.ListExperiments(r.Context(), identityFromContext(r.Context()).TenantID, featureID, tagID, limit, offset)
+	if err != nil {
+		log.Error("failed to list experiments", sl.Err(err))
+		errorwriter.WriteError(w, "failed to list experiments", http.StatusInternalServerError)
+		return
+	}
+
+	// API keys restricted to some features only see their experiments.
+	experiments = slices.DeleteFunc(experiments, func(experiment models.Experiment) bool {
+		return !identityFromContext(r.Context()).CanAccessFeature(experiment.FeatureID)
+	})
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(experiments)
+	if err != nil {
+		log.Error("failed to write experiments", sl.Err(err))
+	}
+}
+
+func (h *Handler) stopExperiment(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.stopExperiment"
+
+	log := h.log.With(slog.String("op", op))
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Error("id is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "id is not a number", http.StatusBadRequest)
+		return
+	}
+
+	experiment, err := h.bannerProvider.StopExperiment(r.Context(), identityFromContext(r.Context()), id)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrExperimentNotFound) {
+		log.Info("experiment not found", sl.Err(err))
+		errorwriter.WriteError(w, "experiment not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrExperimentStopped) {
+		log.Info("experiment already stopped", sl.Err(err))
+		errorwriter.WriteError(w, "experiment has already been stopped", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("failed to stop experiment", sl.Err(err))
+		errorwriter.WriteError(w, "failed to stop experiment", http.StatusInternalServerError)
+		return
+	}
+
+	writeExperiment(w, log, http.StatusOK, experiment)
+}
+
+func writeExperiment(w http.ResponseWriter, log *slog.Logger, status int, experiment *models.Experiment) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(experiment)
+	if err != nil {
+		log.Error("failed to write experiment", sl.Err(err))
+	}
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"banners/domain/models"
+	"banners/internal/errorwriter"
+	"banners/internal/service"
+	"banners/internal/storage"
+	"banners/lib/logger/sl"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) setDefaultBanner(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.setDefaultBanner"
+
+	log := h.log.With(slog.String("op", op))
+
+	featureID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Error("featureID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "featureID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	type setDefault struct {
+		BannerID *int64 `json:"banner_id"`
+	}
+
+	var defaultReq setDefault
+	err = json.NewDecoder(r.Body).Decode(&defaultReq)
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+		return
+	}
+
+	if defaultReq.BannerID == nil {
+		log.Error("banner_id is not provided")
+		errorwriter.WriteError(w, "banner_id is not provided", http.StatusBadRequest)
+		return
+	}
+
+	featureDefault := &models.FeatureDefault{
+		FeatureID: featureID,
+		BannerID:  *defaultReq.BannerID,
+	}
+
+	err = h.bannerProvider.SetDefaultBanner(r.Context(), identityFromContext(r.Context()), featureDefault)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrBannerNotFound) {
+		log.Info("banner not found", sl.Err(err))
+		errorwriter.WriteError(w, "banner not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrNotOnFeature) {
+		log.Info("banner is not on the feature", sl.Err(err))
+		errorwriter.WriteError(w, "chosen revision of the banner is not on the feature", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("failed to set default banner", sl.Err(err))
+		errorwriter.WriteError(w, "failed to set default banner", http.StatusInternalServerError)
+		return
+	}
+
+	writeFeatureDefault(w, log, featureDefault)
+}
+
+func (h *Handler) getDefaultBanner(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.getDefaultBanner"
+
+	log := h.log.With(slog.String("op", op))
+
+	featureID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Error("featureID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "featureID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	if !identityFromContext(r.Context()).CanAccessFeature(featureID) {
+		log.Info("feature is not allowed for the api key")
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+
+	featureDefault, err := h.bannerProvider.GetDefaultBanner(r.Context(), identityFromContext(r.Context()).TenantID, featureID)
+	if errors.Is(err, storage.ErrDefaultNotFound) {
+		log.Info("no default banner", sl.Err(err))
+		errorwriter.WriteError(w, "feature has no default banner", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to get default banner", sl.Err(err))
+		errorwriter.WriteError(w, "failed to get default banner", http.StatusInternalServerError)
+		return
+	}
+
+	writeFeatureDefault(w, log, featureDefault)
+}
+
+func (h *Handler) deleteDefaultBanner(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.deleteDefaultBanner"
+
+	log := h.log.With(slog.String("op", op))
+
+	featureID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Error("featureID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "featureID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	err = h.bannerProvider.DeleteDefaultBanner(r.Context(), identityFromContext(r.Context()), featureID)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrDefaultNotFound) {
+		log.Info("no default banner", sl.Err(err))
+		errorwriter.WriteError(w, "feature has no default banner", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to delete default banner", sl.Err(err))
+		errorwriter.WriteError(w, "failed to delete default banner", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeFeatureDefault(w http.ResponseWriter, log *slog.Logger, featureDefault *models.FeatureDefault) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err := json.NewEncoder(w).Encode(featureDefault)
+	if err != nil {
+		log.Error("failed to write default banner", sl.Err(err))
+	}
+}
@@ -1,11 +1,12 @@
 package handler
 
 import (
+	"banners/domain/models"
 	"banners/internal/errorwriter"
+	"banners/lib/logger/sl"
 	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/dgrijalva/jwt-go"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -39,101 +40,182 @@ func (h *Handler) InitRoutes() *http.ServeMux {
 
 	mux.HandleFunc("POST /login", h.loginUser)
 	mux.HandleFunc("POST /create/user", h.createUser)
-
-	mux.HandleFunc("POST /banner", adminMiddleware(http.HandlerFunc(h.postBanner)))
-	mux.HandleFunc("GET /banner", authMiddleware(http.HandlerFunc(h.listBanners)))
-
-	mux.HandleFunc("GET /user_banner", authMiddleware(http.HandlerFunc(h.getUserBanner)))
-
-	mux.HandleFunc("POST /choose_revision", adminMiddleware(http.HandlerFunc(h.chooseBanner)))
-
-	mux.HandleFunc("GET /banner_revisions/{banner_id}", adminMiddleware(http.HandlerFunc(h.listRevisions)))
-
-	mux.HandleFunc("DELETE /banner/{id}", adminMiddleware(http.HandlerFunc(h.deleteBanner)))
-	mux.HandleFunc("PATCH /banner/{id}", adminMiddleware(http.HandlerFunc(h.patchBanner)))
-
-	mux.HandleFunc("DELETE /banner_deferred", adminMiddleware(h.deleteBannerFeatureTag(h.context)))
+	mux.HandleFunc("GET /.well-known/jwks.json", h.getJWKS)
+	mux.HandleFunc("POST /token/refresh", h.refreshToken)
+	mux.HandleFunc("POST /logout", h.logoutUser)
+
+	mux.HandleFunc("POST /users", h.requirePermission(models.PermUserManage, http.HandlerFunc(h.adminCreateUser)))
+	mux.HandleFunc("GET /users", h.requirePermission(models.PermUserManage, http.HandlerFunc(h.listUsers)))
+	mux.HandleFunc("GET /users/{id}", h.requirePermission(models.PermUserManage, http.HandlerFunc(h.getUser)))
+	mux.HandleFunc("PATCH /users/{id}", h.requirePermission(models.PermUserManage, http.HandlerFunc(h.patchUser)))
+	mux.HandleFunc("DELETE /users/{id}", h.requirePermission(models.PermUserManage, http.HandlerFunc(h.deleteUser)))
+
+	mux.HandleFunc("GET /roles", h.requirePermission(models.PermRoleManage, http.HandlerFunc(h.listRoles)))
+	mux.HandleFunc("PUT /roles/{name}", h.requirePermission(models.PermRoleManage, http.HandlerFunc(h.putRole)))
+	mux.HandleFunc("DELETE /roles/{name}", h.requirePermission(models.PermRoleManage, http.HandlerFunc(h.deleteRole)))
+
+	mux.HandleFunc("POST /api_keys", h.requirePermission(models.PermAPIKeyManage, http.HandlerFunc(h.createAPIKey)))
+	mux.HandleFunc("GET /api_keys", h.requirePermission(models.PermAPIKeyManage, http.HandlerFunc(h.listAPIKeys)))
+	mux.HandleFunc("DELETE /api_keys/{id}", h.requirePermission(models.PermAPIKeyManage, http.HandlerFunc(h.revokeAPIKey)))
+
+	mux.HandleFunc("POST /tenants", h.requirePermission(models.PermTenantManage, http.HandlerFunc(h.createTenant)))
+	mux.HandleFunc("GET /tenants", h.requirePermission(models.PermTenantManage, http.HandlerFunc(h.listTenants)))
+
+	mux.HandleFunc("GET /retention/dry_run", h.requirePermission(models.PermRetentionManage, http.HandlerFunc(h.previewPrune)))
+
+	mux.HandleFunc("GET /audit", h.requirePermission(models.PermAuditRead, http.HandlerFunc(h.listAudit)))
+
+	mux.HandleFunc("POST /banner", h.requirePermission(models.PermBannerEdit, http.HandlerFunc(h.postBanner)))
+	mux.HandleFunc("GET /banner", h.requirePermission(models.PermBannerList, http.HandlerFunc(h.listBanners)))
+
+	mux.HandleFunc("GET /user_banner", h.requirePermission(models.PermBannerRead, http.HandlerFunc(h.getUserBanner)))
+	mux.HandleFunc("GET /user_banner/as_of", h.requirePermission(models.PermAuditRead, http.HandlerFunc(h.getUserBannerAsOf)))
+
+	mux.HandleFunc("POST /choose_revision", h.requirePermission(models.PermBannerPublish, http.HandlerFunc(h.chooseBanner)))
+
+	mux.HandleFunc("GET /banner_revisions/{banner_id}", h.requirePermission(models.PermBannerList, http.HandlerFunc(h.listRevisions)))
+	mux.HandleFunc("GET /banner/{id}/diff", h.requirePermission(models.PermBannerList, http.HandlerFunc(h.diffRevisions)))
+
+	mux.HandleFunc("DELETE /banner/{id}", h.requirePermission(models.PermBannerDelete, http.HandlerFunc(h.deleteBanner)))
+	mux.HandleFunc("PATCH /banner/{id}", h.requirePermission(models.PermBannerEdit, http.HandlerFunc(h.patchBanner)))
+	mux.HandleFunc("POST /banner/{id}/publish", h.requirePermission(models.PermBannerPublish, http.HandlerFunc(h.publishBanner)))
+	mux.HandleFunc("POST /banner/{id}/rollback", h.requirePermission(models.PermBannerPublish, http.HandlerFunc(h.rollbackBanner)))
+	mux.HandleFunc("POST /banner/{id}/rollout", h.requirePermission(models.PermBannerPublish, http.HandlerFunc(h.startRollout)))
+	mux.HandleFunc("GET /banner/{id}/rollout", h.requirePermission(models.PermBannerList, http.HandlerFunc(h.getRollout)))
+	mux.HandleFunc("PATCH /banner/{id}/rollout", h.requirePermission(models.PermBannerPublish, http.HandlerFunc(h.patchRollout)))
+	mux.HandleFunc("DELETE /banner/{id}/rollout", h.requirePermission(models.PermBannerPublish, http.HandlerFunc(h.abortRollout)))
+	mux.HandleFunc("POST /banner/{id}/rollout/finalize", h.requirePermission(models.PermBannerPublish, http.HandlerFunc(h.finalizeRollout)))
+	mux.HandleFunc("POST /banner/{id}/approvals", h.requirePermission(models.PermBannerEdit, http.HandlerFunc(h.requestApproval)))
+	mux.HandleFunc("GET /banner/{id}/approvals", h.requirePermission(models.PermBannerList, http.HandlerFunc(h.listApprovals)))
+	mux.HandleFunc("POST /approvals/{id}/approve", h.requirePermission(models.PermBannerApprove, http.HandlerFunc(h.approveRequest)))
+	mux.HandleFunc("POST /approvals/{id}/reject", h.requirePermission(models.PermBannerApprove, http.HandlerFunc(h.rejectRequest)))
+
+	mux.HandleFunc("POST /features", h.requirePermission(models.PermFeatureManage, h.createRegistryEntry(models.RegistryFeatures)))
+	mux.HandleFunc("GET /features", h.requirePermission(models.PermBannerList, h.listRegistryEntries(models.RegistryFeatures)))
+	mux.HandleFunc("GET /features/{id}", h.requirePermission(models.PermBannerList, h.getRegistryEntry(models.RegistryFeatures)))
+	mux.HandleFunc("PATCH /features/{id}", h.requirePermission(models.PermFeatureManage, h.patchRegistryEntry(models.RegistryFeatures)))
+	mux.HandleFunc("DELETE /features/{id}", h.requirePermission(models.PermFeatureManage, h.deleteRegistryEntry(models.RegistryFeatures)))
+	mux.HandleFunc("PUT /features/{id}/policy", h.requirePermission(models.PermFeatureManage, http.HandlerFunc(h.setFeaturePolicy)))
+	mux.HandleFunc("PUT /features/{id}/default", h.requirePermission(models.PermBannerPublish, http.HandlerFunc(h.setDefaultBanner)))
+	mux.HandleFunc("GET /features/{id}/default", h.requirePermission(models.PermBannerList, http.HandlerFunc(h.getDefaultBanner)))
+	mux.HandleFunc("DELETE /features/{id}/default", h.requirePermission(models.PermBannerPublish, http.HandlerFunc(h.deleteDefaultBanner)))
+	mux.HandleFunc("POST /features/{id}/schemas", h.requirePermission(models.PermFeatureManage, http.HandlerFunc(h.createFeatureSchema)))
+	mux.HandleFunc("GET /features/{id}/schemas", h.requirePermission(models.PermBannerList, http.HandlerFunc(h.listFeatureSchemas)))
+
+	mux.HandleFunc("POST /experiments", h.requirePermission(models.PermBannerPublish, http.HandlerFunc(h.createExperiment)))
+	mux.HandleFunc("GET /experiments", h.requirePermission(models.PermBannerList, http.HandlerFunc(h.listExperiments)))
+	mux.HandleFunc("GET /experiments/{id}", h.requirePermission(models.PermBannerList, http.HandlerFunc(h.getExperiment)))
+	mux.HandleFunc("POST /experiments/{id}/stop", h.requirePermission(models.PermBannerPublish, http.HandlerFunc(h.stopExperiment)))
+
+	mux.HandleFunc("POST /tags", h.requirePermission(models.PermTagManage, h.createRegistryEntry(models.RegistryTags)))
+	mux.HandleFunc("GET /tags", h.requirePermission(models.PermBannerList, h.listRegistryEntries(models.RegistryTags)))
+	mux.HandleFunc("GET /tags/{id}", h.requirePermission(models.PermBannerList, h.getRegistryEntry(models.RegistryTags)))
+	mux.HandleFunc("PATCH /tags/{id}", h.requirePermission(models.PermTagManage, h.patchRegistryEntry(models.RegistryTags)))
+	mux.HandleFunc("DELETE /tags/{id}", h.requirePermission(models.PermTagManage, h.deleteRegistryEntry(models.RegistryTags)))
+
+	mux.HandleFunc("DELETE /banner_deferred", h.requirePermission(models.PermBannerDelete, h.deleteBannerFeatureTag(h.context)))
 
 	return mux
 }
 
-func authMiddleware(next http.Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			handleUnauthorized(w, "Authorization header missing")
-			return
-		}
+type ctxKey int
 
-		tokenString := extractTokenString(authHeader)
-		token, err := parseToken(tokenString)
-		if err != nil {
-			handleUnauthorized(w, "Error parsing token: "+err.Error())
-			return
-		}
+const identityKey ctxKey = iota
 
-		if !token.Valid {
-			handleUnauthorized(w, "Invalid token")
+// requirePermission lets the request through only if the caller is granted
+// the permission, by the role of the user or by the scopes of the API key.
+func (h *Handler) requirePermission(permission string, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := h.authenticate(w, r)
+		if !ok {
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			handleUnauthorized(w, "Token claims are not in the expected format")
+		allowed, err := h.authProvider.HasPermission(r.Context(), identity, permission)
+		if err != nil {
+			h.log.Error("failed to check permission", sl.Err(err))
+			errorwriter.WriteError(w, "failed to check permission", http.StatusInternalServerError)
 			return
 		}
 
-		role, ok := claims["Role"].(string)
-		if !ok {
-			handleUnauthorized(w, "Role claim not found or not a string")
+		if !allowed {
+			errorwriter.WriteError(w, "permission denied", http.StatusForbidden)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), "role", role)
+		ctx := context.WithValue(r.Context(), identityKey, identity)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
 
-func adminMiddleware(next http.Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			handleUnauthorized(w, "Authorization header missing")
-			return
-		}
+// identityFromContext returns the caller stored by requirePermission.
+func identityFromContext(ctx context.Context) *models.Identity {
+	identity, ok := ctx.Value(identityKey).(*models.Identity)
+	if !ok {
+		return &models.Identity{}
+	}
+
+	return identity
+}
+
+// authenticate accepts an API key, given as "Authorization: ApiKey <key>"
+// or in the X-Api-Key header, or a bearer access token. If the credentials
+// are missing or invalid, the response is already written and false is
+// returned.
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (*models.Identity, bool) {
+	apiKey := r.Header.Get("X-Api-Key")
+	if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "ApiKey "); ok {
+		apiKey = key
+	}
 
-		tokenString := extractTokenString(authHeader)
-		token, err := parseToken(tokenString)
+	if apiKey != "" {
+		identity, err := h.authProvider.AuthenticateAPIKey(r.Context(), apiKey)
 		if err != nil {
-			handleUnauthorized(w, "Error parsing token: "+err.Error())
-			return
+			h.log.Info("failed to authenticate api key", sl.Err(err))
+			handleUnauthorized(w, "Invalid api key")
+			return nil, false
 		}
 
-		if !token.Valid {
-			handleUnauthorized(w, "Invalid token")
-			return
-		}
+		return identity, true
+	}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			handleUnauthorized(w, "Token claims are not in the expected format")
-			return
-		}
+	claims, ok := h.authenticateToken(w, r)
+	if !ok {
+		return nil, false
+	}
 
-		role, ok := claims["Role"].(string)
-		if !ok {
-			handleUnauthorized(w, "Role claim not found or not a string")
-			return
-		}
+	return &models.Identity{
+		UserID:   claims.UserID,
+		Email:    claims.Email,
+		Role:     claims.Role,
+		TenantID: claims.TenantID,
+	}, true
+}
 
-		if role != "admin" {
-			handleUnauthorized(w, "Wrong role")
-			return
-		}
+// authenticateToken verifies the bearer token of the request and returns
+// its claims. If the token is missing or invalid, the response is already
+// written and false is returned.
+func (h *Handler) authenticateToken(w http.ResponseWriter, r *http.Request) (*models.Token, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		handleUnauthorized(w, "Authorization header missing")
+		return nil, false
+	}
 
-		ctx := context.WithValue(r.Context(), "role", role)
-		next.ServeHTTP(w, r.WithContext(ctx))
+	tokenString := extractTokenString(authHeader)
+	claims, err := h.authProvider.ParseToken(r.Context(), tokenString)
+	if err != nil {
+		h.log.Info("failed to parse token", sl.Err(err))
+		handleUnauthorized(w, "Invalid token")
+		return nil, false
 	}
+
+	if claims.Role == "" {
+		handleUnauthorized(w, "Role claim not found")
+		return nil, false
+	}
+
+	return claims, true
 }
 
 func handleUnauthorized(w http.ResponseWriter, message string) {
@@ -148,12 +230,3 @@ func handleUnauthorized(w http.ResponseWriter, message string) {
 func extractTokenString(authHeader string) string {
 	return strings.Replace(authHeader, "Bearer ", "", 1)
 }
-
-func parseToken(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte("secret"), nil
-	})
-}
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"banners/domain/models"
+	"banners/internal/errorwriter"
+	"banners/internal/service"
+	"banners/internal/storage"
+	"banners/lib/logger/sl"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// registryNoun names an entry of a registry in responses.
+var registryNoun = map[string]string{
+	models.RegistryFeatures: "feature",
+	models.RegistryTags:     "tag",
+}
+
+func (h *Handler) createRegistryEntry(registry string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.createRegistryEntry"
+
+		log := h.log.With(slog.String("op", op), slog.String("registry", registry))
+
+		entry := &models.RegistryEntry{}
+		err := json.NewDecoder(r.Body).Decode(entry)
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+			return
+		}
+
+		created, err := h.bannerProvider.CreateRegistryEntry(r.Context(), identityFromContext(r.Context()), registry, entry)
+		if errors.Is(err, service.ErrInvalidRegistryID) || errors.Is(err, service.ErrRegistryName) {
+			log.Info("invalid registry entry", sl.Err(err))
+			errorwriter.WriteError(w, "id must be positive and name must not be empty", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, service.ErrRegistryParent) || errors.Is(err, service.ErrTagCycle) {
+			log.Info("invalid parent", sl.Err(err))
+			errorwriter.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, storage.ErrParentNotFound) {
+			log.Info("parent not found", sl.Err(err))
+			errorwriter.WriteError(w, "parent tag not found", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, storage.ErrRegistryExists) {
+			log.Info("registry entry exists", sl.Err(err))
+			errorwriter.WriteError(w, registryNoun[registry]+" with this id or name already exists", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Error("failed to create registry entry", sl.Err(err))
+			errorwriter.WriteError(w, "failed to create "+registryNoun[registry], http.StatusInternalServerError)
+			return
+		}
+
+		writeRegistryEntry(w, log, http.StatusCreated, created)
+	}
+}
+
+func (h *Handler) getRegistryEntry(registry string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.getRegistryEntry"
+
+		log := h.log.With(slog.String("op", op), slog.String("registry", registry))
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Error("id is not a number", sl.Err(err))
+			errorwriter.WriteError(w, "id is not a number", http.StatusBadRequest)
+			return
+		}
+
+		entry, err := h.bannerProvider.GetRegistryEntry(r.Context(), identityFromContext(r.Context()).TenantID, registry, id)
+		if errors.Is(err, storage.ErrRegistryNotFound) {
+			log.Info("registry entry not found", sl.Err(err))
+			errorwriter.WriteError(w, registryNoun[registry]+" not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Error("failed to get registry entry", sl.Err(err))
+			errorwriter.WriteError(w, "failed to get "+registryNoun[registry], http.StatusInternalServerError)
+			return
+		}
+
+		writeRegistryEntry(w, log, http.StatusOK, entry)
+	}
+}
+
+// listRegistryEntries lists the entries of the registry, archived ones only
+// if include_archived is set.
+func (h *Handler) listRegistryEntries(registry string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.listRegistryEntries"
+
+		log := h.log.With(slog.String("op", op), slog.String("registry", registry))
+
+		limitStr := r.URL.Query().Get("limit")
+		offsetStr := r.URL.Query().Get("offset")
+
+		if limitStr == "" {
+			limitStr = "20"
+		}
+
+		if offsetStr == "" {
+			offsetStr = "0"
+		}
+
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			log.Error("limit is not a number", sl.Err(err))
+			errorwriter.WriteError(w, "limit is not a number", http.StatusBadRequest)
+			return
+		}
+
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			log.Error("offset is not a number", sl.Err(err))
+			errorwriter.WriteError(w, "offset is not a number", http.StatusBadRequest)
+			return
+		}
+
+		if limit < 0 || limit > 100 {
+			log.Error("limit is out of range")
+			errorwriter.WriteError(w, "limit is out of range", http.StatusBadRequest)
+			return
+		}
+
+		if offset < 0 {
+			log.Error("offset is out of range")
+			errorwriter.WriteError(w, "offset is out of range", http.StatusBadRequest)
+			return
+		}
+
+		var includeArchived bool
+		if includeArchivedStr := r.URL.Query().Get("include_archived"); includeArchivedStr != "" {
+			includeArchived, err = strconv.ParseBool(includeArchivedStr)
+			if err != nil {
+				log.Error("includeArchived is not a bool", sl.Err(err))
+				errorwriter.WriteError(w, "include_archived is not a bool", http.StatusBadRequest)
+				return
+			}
+		}
+
+		entries, err := h.bannerProvider.ListRegistryEntries(r.Context(), identityFromContext(r.Context()).TenantID, registry, includeArchived, limit, offset)
+		if err != nil {
+			log.Error("failed to list registry entries", sl.Err(err))
+			errorwriter.WriteError(w, "failed to list "+registry, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(entries)
+		if err != nil {
+			log.Error("failed to list registry entries", sl.Err(err))
+		}
+	}
+}
+
+func (h *Handler) patchRegistryEntry(registry string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.patchRegistryEntry"
+
+		log := h.log.With(slog.String("op", op), slog.String("registry", registry))
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Error("id is not a number", sl.Err(err))
+			errorwriter.WriteError(w, "id is not a number", http.StatusBadRequest)
+			return
+		}
+
+		update := &models.RegistryUpdate{}
+		err = json.NewDecoder(r.Body).Decode(update)
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+			return
+		}
+
+		entry, err := h.bannerProvider.UpdateRegistryEntry(r.Context(), identityFromContext(r.Context()), registry, id, update)
+		if errors.Is(err, service.ErrNothingToUpdate) {
+			log.Info("nothing to update", sl.Err(err))
+			errorwriter.WriteError(w, "name, description, owner_team, archived or parent_id must be provided", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, service.ErrRegistryName) {
+			log.Info("empty name", sl.Err(err))
+			errorwriter.WriteError(w, "name must not be empty", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, service.ErrRegistryParent) || errors.Is(err, service.ErrTagCycle) {
+			log.Info("invalid parent", sl.Err(err))
+			errorwriter.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, storage.ErrParentNotFound) {
+			log.Info("parent not found", sl.Err(err))
+			errorwriter.WriteError(w, "parent tag not found", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, storage.ErrRegistryNotFound) {
+			log.Info("registry entry not found", sl.Err(err))
+			errorwriter.WriteError(w, registryNoun[registry]+" not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, storage.ErrRegistryExists) {
+			log.Info("registry name taken", sl.Err(err))
+			errorwriter.WriteError(w, registryNoun[registry]+" with this name already exists", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Error("failed to update registry entry", sl.Err(err))
+			errorwriter.WriteError(w, "failed to update "+registryNoun[registry], http.StatusInternalServerError)
+			return
+		}
+
+		writeRegistryEntry(w, log, http.StatusOK, entry)
+	}
+}
+
+func (h *Handler) deleteRegistryEntry(registry string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.deleteRegistryEntry"
+
+		log := h.log.With(slog.String("op", op), slog.String("registry", registry))
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Error("id is not a number", sl.Err(err))
+			errorwriter.WriteError(w, "id is not a number", http.StatusBadRequest)
+			return
+		}
+
+		err = h.bannerProvider.DeleteRegistryEntry(r.Context(), identityFromContext(r.Context()), registry, id)
+		if errors.Is(err, storage.ErrRegistryNotFound) {
+			log.Info("registry entry not found", sl.Err(err))
+			errorwriter.WriteError(w, registryNoun[registry]+" not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, storage.ErrRegistryInUse) {
+			log.Info("registry entry in use", sl.Err(err))
+			errorwriter.WriteError(w, registryNoun[registry]+" is used by banners, archive it instead", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete registry entry", sl.Err(err))
+			errorwriter.WriteError(w, "failed to delete "+registryNoun[registry], http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeRegistryEntry(w http.ResponseWriter, log *slog.Logger, status int, entry *models.RegistryEntry) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(entry)
+	if err != nil {
+		log.Error("failed to write registry entry", sl.Err(err))
+	}
+}
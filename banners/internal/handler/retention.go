@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"banners/internal/errorwriter"
+	"banners/lib/logger/sl"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// previewPrune reports the revisions of the caller's tenant that the
+// retention policy would delete, without deleting them.
+func (h *Handler) previewPrune(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.previewPrune"
+
+	log := h.log.With(slog.String("op", op))
+
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+
+	if limitStr == "" {
+		limitStr = "100"
+	}
+
+	if offsetStr == "" {
+		offsetStr = "0"
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		log.Error("limit is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "limit is not a number", http.StatusBadRequest)
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		log.Error("offset is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "offset is not a number", http.StatusBadRequest)
+		return
+	}
+
+	if limit < 0 || limit > 100 {
+		log.Error("limit is out of range")
+		errorwriter.WriteError(w, "limit is out of range", http.StatusBadRequest)
+		return
+	}
+
+	if offset < 0 {
+		log.Error("offset is out of range")
+		errorwriter.WriteError(w, "offset is out of range", http.StatusBadRequest)
+		return
+	}
+
+	preview, err := h.bannerProvider.PreviewPrune(r.Context(), identityFromContext(r.Context()).TenantID, limit, offset)
+	if err != nil {
+		log.Error("failed to preview pruning", sl.Err(err))
+		errorwriter.WriteError(w, "failed to preview pruning", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(preview)
+	if err != nil {
+		log.Error("failed to preview pruning", sl.Err(err))
+	}
+}
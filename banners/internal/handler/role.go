@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"banners/internal/errorwriter"
+	"banners/internal/storage"
+	"banners/lib/logger/sl"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+func (h *Handler) listRoles(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.listRoles"
+
+	log := h.log.With(slog.String("op", op))
+
+	roles, err := h.userProvider.ListRoles(r.Context())
+	if err != nil {
+		log.Error("failed to list roles", sl.Err(err))
+		errorwriter.WriteError(w, "failed to list roles", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(roles)
+	if err != nil {
+		log.Error("failed to list roles", sl.Err(err))
+	}
+}
+
+func (h *Handler) putRole(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.putRole"
+
+	log := h.log.With(slog.String("op", op))
+
+	role := r.PathValue("name")
+	if role == "" {
+		log.Error("role is not provided")
+		errorwriter.WriteError(w, "role is not provided", http.StatusBadRequest)
+		return
+	}
+
+	type putRole struct {
+		Permissions []string `json:"permissions"`
+	}
+
+	var roleReq putRole
+	err := json.NewDecoder(r.Body).Decode(&roleReq)
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+		return
+	}
+
+	if roleReq.Permissions == nil {
+		log.Error("permissions are not provided")
+		errorwriter.WriteError(w, "permissions are not provided", http.StatusBadRequest)
+		return
+	}
+
+	err = h.userProvider.SetRolePermissions(r.Context(), identityFromContext(r.Context()), role, roleReq.Permissions)
+	if errors.Is(err, storage.ErrPermissionNotFound) {
+		log.Info("unknown permission", sl.Err(err))
+		errorwriter.WriteError(w, "unknown permission", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Error("failed to set role permissions", sl.Err(err))
+		errorwriter.WriteError(w, "failed to set role permissions", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) deleteRole(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.deleteRole"
+
+	log := h.log.With(slog.String("op", op))
+
+	err := h.userProvider.DeleteRole(r.Context(), identityFromContext(r.Context()), r.PathValue("name"))
+	if errors.Is(err, storage.ErrRoleNotFound) {
+		log.Info("role not found", sl.Err(err))
+		errorwriter.WriteError(w, "role not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrRoleInUse) {
+		log.Info("role is in use", sl.Err(err))
+		errorwriter.WriteError(w, "role is assigned to users", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("failed to delete role", sl.Err(err))
+		errorwriter.WriteError(w, "failed to delete role", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
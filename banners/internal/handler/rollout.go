@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"banners/domain/models"
+	"banners/internal/errorwriter"
+	"banners/internal/service"
+	"banners/internal/storage"
+	"banners/lib/logger/sl"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) startRollout(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.startRollout"
+
+	log := h.log.With(slog.String("op", op))
+
+	bannerID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error("bannerID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "bannerID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	type startRollout struct {
+		RevisionID *int64 `json:"revision_id"`
+		Percent    *int   `json:"percent"`
+	}
+
+	var rolloutReq startRollout
+	err = json.NewDecoder(r.Body).Decode(&rolloutReq)
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+		return
+	}
+
+	if rolloutReq.RevisionID == nil || rolloutReq.Percent == nil {
+		log.Error("revision_id or percent is not provided")
+		errorwriter.WriteError(w, "revision_id or percent is not provided", http.StatusBadRequest)
+		return
+	}
+
+	rollout, err := h.bannerProvider.StartRollout(r.Context(), identityFromContext(r.Context()), &models.Rollout{
+		BannerID:   int64(bannerID),
+		RevisionID: *rolloutReq.RevisionID,
+		Percent:    *rolloutReq.Percent,
+	})
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidPercent) {
+		log.Info("invalid percent", sl.Err(err))
+		errorwriter.WriteError(w, "percent must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, storage.ErrBannerNotFound) || errors.Is(err, storage.ErrRevisionDoesNotExist) {
+		log.Info("revision not found", sl.Err(err))
+		errorwriter.WriteError(w, "revision not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrApprovalRequired) {
+		log.Info("revision is not approved", sl.Err(err))
+		errorwriter.WriteError(w, "revision needs an approval to be rolled out", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrRolloutOfLive) {
+		log.Info("revision is live", sl.Err(err))
+		errorwriter.WriteError(w, "revision is already live", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, storage.ErrRolloutTargets) {
+		log.Info("revision has other feature or tags", sl.Err(err))
+		errorwriter.WriteError(w, "revision must have the feature and tags of the live revision", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, storage.ErrRolloutExists) {
+		log.Info("rollout exists", sl.Err(err))
+		errorwriter.WriteError(w, "banner already has a rollout", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("failed to start rollout", sl.Err(err))
+		errorwriter.WriteError(w, "failed to start rollout", http.StatusInternalServerError)
+		return
+	}
+
+	writeRollout(w, log, http.StatusCreated, rollout)
+}
+
+func (h *Handler) getRollout(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.getRollout"
+
+	log := h.log.With(slog.String("op", op))
+
+	bannerID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error("bannerID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "bannerID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	rollout, err := h.bannerProvider.GetRollout(r.Context(), identityFromContext(r.Context()), bannerID)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrRolloutNotFound) {
+		log.Info("rollout not found", sl.Err(err))
+		errorwriter.WriteError(w, "banner has no rollout", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to get rollout", sl.Err(err))
+		errorwriter.WriteError(w, "failed to get rollout", http.StatusInternalServerError)
+		return
+	}
+
+	writeRollout(w, log, http.StatusOK, rollout)
+}
+
+func (h *Handler) patchRollout(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.patchRollout"
+
+	log := h.log.With(slog.String("op", op))
+
+	bannerID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error("bannerID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "bannerID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	type patchRollout struct {
+		Percent *int `json:"percent"`
+	}
+
+	var rolloutReq patchRollout
+	err = json.NewDecoder(r.Body).Decode(&rolloutReq)
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+		return
+	}
+
+	if rolloutReq.Percent == nil {
+		log.Error("percent is not provided")
+		errorwriter.WriteError(w, "percent is not provided", http.StatusBadRequest)
+		return
+	}
+
+	rollout, err := h.bannerProvider.SetRolloutPercent(r.Context(), identityFromContext(r.Context()), bannerID, *rolloutReq.Percent)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidPercent) {
+		log.Info("invalid percent", sl.Err(err))
+		errorwriter.WriteError(w, "percent must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, storage.ErrRolloutNotFound) {
+		log.Info("rollout not found", sl.Err(err))
+		errorwriter.WriteError(w, "banner has no rollout", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to update rollout", sl.Err(err))
+		errorwriter.WriteError(w, "failed to update rollout", http.StatusInternalServerError)
+		return
+	}
+
+	writeRollout(w, log, http.StatusOK, rollout)
+}
+
+// finalizeRollout publishes the revision being rolled out to all users.
+func (h *Handler) finalizeRollout(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.finalizeRollout"
+
+	log := h.log.With(slog.String("op", op))
+
+	bannerID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error("bannerID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "bannerID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	revisionID, err := h.bannerProvider.FinalizeRollout(r.Context(), identityFromContext(r.Context()), bannerID)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrRolloutNotFound) {
+		log.Info("rollout not found", sl.Err(err))
+		errorwriter.WriteError(w, "banner has no rollout", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrApprovalRequired) {
+		log.Info("revision is not approved", sl.Err(err))
+		errorwriter.WriteError(w, "revision needs an approval to be published", http.StatusForbidden)
+		return
+	}
+	var conflictErr *storage.BannerConflictError
+	if errors.As(err, &conflictErr) {
+		log.Info("banner conflict", sl.Err(err))
+		errorwriter.WriteError(w, fmt.Sprintf("banner %d already has feature %d and tag %d", conflictErr.BannerID, conflictErr.FeatureID, conflictErr.TagID), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("failed to finalize rollout", sl.Err(err))
+		errorwriter.WriteError(w, "failed to finalize rollout", http.StatusInternalServerError)
+		return
+	}
+
+	type finalizeResponse struct {
+		Message    string `json:"message"`
+		BannerID   int    `json:"banner_id"`
+		RevisionID int    `json:"revision_id"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(finalizeResponse{
+		Message:    "Successfully finalized a rollout",
+		BannerID:   bannerID,
+		RevisionID: revisionID,
+	})
+	if err != nil {
+		log.Error("failed to finalize rollout", sl.Err(err))
+	}
+}
+
+func (h *Handler) abortRollout(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.abortRollout"
+
+	log := h.log.With(slog.String("op", op))
+
+	bannerID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error("bannerID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "bannerID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	err = h.bannerProvider.AbortRollout(r.Context(), identityFromContext(r.Context()), bannerID)
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrRolloutNotFound) {
+		log.Info("rollout not found", sl.Err(err))
+		errorwriter.WriteError(w, "banner has no rollout", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to abort rollout", sl.Err(err))
+		errorwriter.WriteError(w, "failed to abort rollout", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeRollout(w http.ResponseWriter, log *slog.Logger, status int, rollout *models.Rollout) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(rollout)
+	if err != nil {
+		log.Error("failed to write rollout", sl.Err(err))
+	}
+}
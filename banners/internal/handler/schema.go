@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"banners/domain/models"
+	"banners/internal/errorwriter"
+	"banners/internal/service"
+	"banners/internal/storage"
+	"banners/lib/jsonschema"
+	"banners/lib/logger/sl"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// contentError is the 422 response to content that does not match the
+// feature schema.
+type contentError struct {
+	Error         string                 `json:"error"`
+	SchemaVersion int                    `json:"schema_version"`
+	Violations    []jsonschema.Violation `json:"violations"`
+}
+
+// createFeatureSchema registers the request body as the next version of the
+// JSON Schema of the feature.
+func (h *Handler) createFeatureSchema(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.createFeatureSchema"
+
+	log := h.log.With(slog.String("op", op))
+
+	featureID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Error("featureID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "featureID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	var schema json.RawMessage
+	err = json.NewDecoder(r.Body).Decode(&schema)
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.bannerProvider.CreateFeatureSchema(r.Context(), identityFromContext(r.Context()), &models.FeatureSchema{
+		FeatureID: featureID,
+		Schema:    schema,
+	})
+	if errors.Is(err, service.ErrFeatureForbidden) {
+		log.Info("feature is not allowed for the api key", sl.Err(err))
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidSchema) {
+		log.Info("invalid schema", sl.Err(err))
+		errorwriter.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, storage.ErrSchemaVersionExists) {
+		log.Info("schema registered concurrently", sl.Err(err))
+		errorwriter.WriteError(w, "another schema version was registered at the same time", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("failed to create feature schema", sl.Err(err))
+		errorwriter.WriteError(w, "failed to create feature schema", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(created)
+	if err != nil {
+		log.Error("failed to create feature schema", sl.Err(err))
+	}
+}
+
+func (h *Handler) listFeatureSchemas(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.listFeatureSchemas"
+
+	log := h.log.With(slog.String("op", op))
+
+	featureID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Error("featureID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "featureID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	if !identityFromContext(r.Context()).CanAccessFeature(featureID) {
+		log.Info("feature is not allowed for the api key")
+		errorwriter.WriteError(w, "feature is not allowed", http.StatusForbidden)
+		return
+	}
+
+	schemas, err := h.bannerProvider.ListFeatureSchemas(r.Context(), identityFromContext(r.Context()).TenantID, featureID)
+	if err != nil {
+		log.Error("failed to list feature schemas", sl.Err(err))
+		errorwriter.WriteError(w, "failed to list feature schemas", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(schemas)
+	if err != nil {
+		log.Error("failed to list feature schemas", sl.Err(err))
+	}
+}
+
+// writeContentError answers with the pointers of the content that failed
+// validation if err is a service.ContentError, and reports whether it was.
+func writeContentError(w http.ResponseWriter, log *slog.Logger, err error) bool {
+	var contentErr *service.ContentError
+	if !errors.As(err, &contentErr) {
+		return false
+	}
+
+	log.Info("invalid content", sl.Err(err))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	err = json.NewEncoder(w).Encode(contentError{
+		Error:         contentErr.Error(),
+		SchemaVersion: contentErr.SchemaVersion,
+		Violations:    contentErr.Violations,
+	})
+	if err != nil {
+		log.Error("failed to write content error", sl.Err(err))
+	}
+
+	return true
+}
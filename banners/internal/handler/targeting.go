@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"banners/domain/models"
+	"net/http"
+	"strings"
+)
+
+// audienceFromRequest reads what banners may target from the query of a
+// user banner request, falling back to the headers apps send anyway.
+// Custom attributes are given as attr.<name>=<value>.
+func audienceFromRequest(r *http.Request, userKey string) *models.Audience {
+	query := r.URL.Query()
+
+	audience := &models.Audience{
+		UserKey:    userKey,
+		Platform:   query.Get("platform"),
+		AppVersion: query.Get("app_version"),
+		Locale:     query.Get("locale"),
+	}
+
+	if audience.Platform == "" {
+		audience.Platform = r.Header.Get("X-Platform")
+	}
+
+	if audience.AppVersion == "" {
+		audience.AppVersion = r.Header.Get("X-App-Version")
+	}
+
+	if audience.Locale == "" {
+		audience.Locale = preferredLocale(r.Header.Get("Accept-Language"))
+	}
+
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, "attr.")
+		if !ok || name == "" || len(values) == 0 {
+			continue
+		}
+
+		if audience.Attributes == nil {
+			audience.Attributes = make(map[string]string)
+		}
+		audience.Attributes[name] = values[0]
+	}
+
+	return audience
+}
+
+// preferredLocale returns the first language of an Accept-Language header.
+// Clients list their preferred language first.
+func preferredLocale(acceptLanguage string) string {
+	first, _, _ := strings.Cut(acceptLanguage, ",")
+	locale, _, _ := strings.Cut(first, ";")
+
+	locale = strings.TrimSpace(locale)
+	if locale == "*" {
+		return ""
+	}
+
+	return locale
+}
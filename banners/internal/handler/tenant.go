@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"banners/internal/errorwriter"
+	"banners/internal/service"
+	"banners/internal/storage"
+	"banners/lib/logger/sl"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+func (h *Handler) createTenant(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.createTenant"
+
+	log := h.log.With(slog.String("op", op))
+
+	type createTenant struct {
+		Name string `json:"name"`
+	}
+
+	var tenantReq createTenant
+	err := json.NewDecoder(r.Body).Decode(&tenantReq)
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+		return
+	}
+
+	tenant, err := h.userProvider.CreateTenant(r.Context(), identityFromContext(r.Context()), tenantReq.Name)
+	if errors.Is(err, service.ErrTenantNameEmpty) {
+		log.Info("name is empty", sl.Err(err))
+		errorwriter.WriteError(w, "name is empty", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, storage.ErrTenantExists) {
+		log.Info("tenant already exists", sl.Err(err))
+		errorwriter.WriteError(w, "tenant already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("failed to create tenant", sl.Err(err))
+		errorwriter.WriteError(w, "failed to create tenant", http.StatusInternalServerError)
+		return
+	}
+
+	responseJSON, err := json.Marshal(tenant)
+	if err != nil {
+		log.Error("failed to marshal response", sl.Err(err))
+		errorwriter.WriteError(w, "failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(responseJSON)
+}
+
+func (h *Handler) listTenants(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.listTenants"
+
+	log := h.log.With(slog.String("op", op))
+
+	tenants, err := h.userProvider.ListTenants(r.Context())
+	if err != nil {
+		log.Error("failed to list tenants", sl.Err(err))
+		errorwriter.WriteError(w, "failed to list tenants", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(tenants)
+	if err != nil {
+		log.Error("failed to list tenants", sl.Err(err))
+	}
+}
@@ -3,12 +3,16 @@ package handler
 import (
 	"banners/domain/models"
 	"banners/internal/errorwriter"
+	"banners/internal/service"
+	"banners/internal/storage"
 	"banners/lib/logger/sl"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 )
 
 type CreateUserResponse struct {
@@ -16,8 +20,31 @@ type CreateUserResponse struct {
 	Email   string `json:"email"`
 }
 
+// UserResponse is a user as shown to admins, without the password hash.
+type UserResponse struct {
+	ID       int64  `json:"id"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	Disabled bool   `json:"disabled"`
+	TenantID int64  `json:"tenant_id"`
+}
+
 type UserProvider interface {
-	CreateUser(email, role, password string) error
+	SignUpUser(ctx context.Context, email, role, password string) error
+	CreateUser(ctx context.Context, actor *models.Identity, email, role, password string, tenantID int64) error
+	ListUsers(ctx context.Context, tenantID int64, limit int, offset int) ([]models.User, error)
+	GetUser(ctx context.Context, tenantID int64, id int64) (*models.User, error)
+	UpdateUser(ctx context.Context, actor *models.Identity, id int64, role *string, disabled *bool) (*models.User, error)
+	DeleteUser(ctx context.Context, actor *models.Identity, id int64) error
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	SetRolePermissions(ctx context.Context, actor *models.Identity, role string, permissions []string) error
+	DeleteRole(ctx context.Context, actor *models.Identity, role string) error
+	CreateAPIKey(ctx context.Context, actor *models.Identity, name string, scopes []string, featureIDs []int64) (*models.APIKey, string, error)
+	ListAPIKeys(ctx context.Context, tenantID int64) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, actor *models.Identity, id int64) error
+	ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+	CreateTenant(ctx context.Context, actor *models.Identity, name string) (*models.Tenant, error)
+	ListTenants(ctx context.Context) ([]models.Tenant, error)
 }
 
 func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
@@ -25,17 +52,262 @@ func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
 
 	log := h.log.With(slog.String("op", op))
 
+	user, ok := h.decodeNewUser(w, r, log)
+	if !ok {
+		return
+	}
+
+	err := h.userProvider.SignUpUser(r.Context(), user.Email, user.Role, user.Password)
+	if errors.Is(err, service.ErrSignupDisabled) {
+		log.Info("signup rejected", sl.Err(err))
+		errorwriter.WriteError(w, "signup is disabled", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrRoleNotAllowed) {
+		log.Info("signup rejected", sl.Err(err))
+		errorwriter.WriteError(w, "signup is limited to the user role", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.Error("failed to create user", sl.Err(err))
+		errorwriter.WriteError(w, "failed to create user", http.StatusConflict)
+		return
+	}
+
+	writeUserCreated(w, log, user.Email)
+}
+
+func (h *Handler) adminCreateUser(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.adminCreateUser"
+
+	log := h.log.With(slog.String("op", op))
+
+	user, ok := h.decodeNewUser(w, r, log)
+	if !ok {
+		return
+	}
+
+	err := h.userProvider.CreateUser(r.Context(), identityFromContext(r.Context()), user.Email, user.Role, user.Password, user.TenantID)
+	if errors.Is(err, service.ErrForeignTenant) || errors.Is(err, service.ErrRoleNotGrantable) {
+		log.Info("user creation rejected", sl.Err(err))
+		errorwriter.WriteError(w, "not allowed to create this user", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrTenantNotFound) {
+		log.Info("invalid tenant", sl.Err(err))
+		errorwriter.WriteError(w, "unknown tenant", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, storage.ErrRoleNotFound) {
+		log.Info("invalid role", sl.Err(err))
+		errorwriter.WriteError(w, "unknown role", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Error("failed to create user", sl.Err(err))
+		errorwriter.WriteError(w, "failed to create user", http.StatusConflict)
+		return
+	}
+
+	writeUserCreated(w, log, user.Email)
+}
+
+func (h *Handler) listUsers(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.listUsers"
+
+	log := h.log.With(slog.String("op", op))
+
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+
+	if limitStr == "" {
+		limitStr = "20"
+	}
+
+	if offsetStr == "" {
+		offsetStr = "0"
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		log.Error("limit is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "limit is not a number", http.StatusBadRequest)
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		log.Error("offset is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "offset is not a number", http.StatusBadRequest)
+		return
+	}
+
+	if limit < 0 || limit > 100 {
+		log.Error("limit is out of range")
+		errorwriter.WriteError(w, "limit is out of range", http.StatusBadRequest)
+		return
+	}
+
+	if offset < 0 {
+		log.Error("offset is out of range")
+		errorwriter.WriteError(w, "offset is out of range", http.StatusBadRequest)
+		return
+	}
+
+	users, err := h.userProvider.ListUsers(r.Context(), identityFromContext(r.Context()).TenantID, limit, offset)
+	if err != nil {
+		log.Error("failed to list users", sl.Err(err))
+		errorwriter.WriteError(w, "failed to list users", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]UserResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, toUserResponse(&user))
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		log.Error("failed to list users", sl.Err(err))
+	}
+}
+
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.getUser"
+
+	log := h.log.With(slog.String("op", op))
+
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Error("userID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "userID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userProvider.GetUser(r.Context(), identityFromContext(r.Context()).TenantID, userID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		log.Info("user not found", sl.Err(err))
+		errorwriter.WriteError(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to get user", sl.Err(err))
+		errorwriter.WriteError(w, "failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	writeUser(w, log, user)
+}
+
+func (h *Handler) patchUser(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.patchUser"
+
+	log := h.log.With(slog.String("op", op))
+
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Error("userID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "userID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	type patchUser struct {
+		Role     *string `json:"role"`
+		Disabled *bool   `json:"disabled"`
+	}
+
+	var userReq patchUser
+	err = json.NewDecoder(r.Body).Decode(&userReq)
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userProvider.UpdateUser(r.Context(), identityFromContext(r.Context()), userID, userReq.Role, userReq.Disabled)
+	if errors.Is(err, service.ErrNothingToUpdate) {
+		log.Info("nothing to update", sl.Err(err))
+		errorwriter.WriteError(w, "role or disabled must be provided", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, storage.ErrRoleNotFound) {
+		log.Info("invalid role", sl.Err(err))
+		errorwriter.WriteError(w, "unknown role", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrRoleNotGrantable) {
+		log.Info("role not grantable", sl.Err(err))
+		errorwriter.WriteError(w, "not allowed to grant this role", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, service.ErrCannotModifySelf) {
+		log.Info("admin tried to modify themselves", sl.Err(err))
+		errorwriter.WriteError(w, "admins cannot disable or re-role themselves", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrUserNotFound) {
+		log.Info("user not found", sl.Err(err))
+		errorwriter.WriteError(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to update user", sl.Err(err))
+		errorwriter.WriteError(w, "failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	writeUser(w, log, user)
+}
+
+func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.deleteUser"
+
+	log := h.log.With(slog.String("op", op))
+
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Error("userID is not a number", sl.Err(err))
+		errorwriter.WriteError(w, "userID is not a number", http.StatusBadRequest)
+		return
+	}
+
+	err = h.userProvider.DeleteUser(r.Context(), identityFromContext(r.Context()), userID)
+	if errors.Is(err, service.ErrCannotModifySelf) {
+		log.Info("admin tried to delete themselves", sl.Err(err))
+		errorwriter.WriteError(w, "admins cannot delete themselves", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrUserNotFound) {
+		log.Info("user not found", sl.Err(err))
+		errorwriter.WriteError(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to delete user", sl.Err(err))
+		errorwriter.WriteError(w, "failed to delete user", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeNewUser reads and validates the body of a user creation request.
+// On failure the response is already written and false is returned.
+func (h *Handler) decodeNewUser(w http.ResponseWriter, r *http.Request, log *slog.Logger) (*models.User, bool) {
 	user := &models.User{}
 	err := json.NewDecoder(r.Body).Decode(user)
 	if err != nil {
 		log.Error("failed to decode request body", sl.Err(err))
 		errorwriter.WriteError(w, "failed to decode request", http.StatusBadRequest)
-		return
+		return nil, false
 	}
 	if errors.Is(err, io.EOF) {
 		log.Error("request body is empty", sl.Err(err))
 		errorwriter.WriteError(w, "empty request", http.StatusBadRequest)
-		return
+		return nil, false
 	}
 
 	log.Info("request body decoded")
@@ -43,30 +315,27 @@ func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
 	if user.Email == "" {
 		h.log.Error("email is empty")
 		errorwriter.WriteError(w, "email is empty", http.StatusBadRequest)
-		return
+		return nil, false
 	}
 	if user.Role == "" {
 		h.log.Error("role is empty")
 		errorwriter.WriteError(w, "role is empty", http.StatusBadRequest)
 
-		return
+		return nil, false
 	}
 	if user.Password == "" {
 		h.log.Error("password is empty")
 		errorwriter.WriteError(w, "password is empty", http.StatusBadRequest)
-		return
+		return nil, false
 	}
 
-	err = h.userProvider.CreateUser(user.Email, user.Role, user.Password)
-	if err != nil {
-		log.Error("failed to create user", sl.Err(err))
-		errorwriter.WriteError(w, "failed to create user", http.StatusConflict)
-		return
-	}
+	return user, true
+}
 
+func writeUserCreated(w http.ResponseWriter, log *slog.Logger, email string) {
 	response := CreateUserResponse{
 		Message: "Successfully created user.",
-		Email:   user.Email,
+		Email:   email,
 	}
 
 	responseJSON, err := json.Marshal(response)
@@ -80,3 +349,26 @@ func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 	w.Write(responseJSON)
 }
+
+func writeUser(w http.ResponseWriter, log *slog.Logger, user *models.User) {
+	responseJSON, err := json.Marshal(toUserResponse(user))
+	if err != nil {
+		log.Error("failed to marshal response", sl.Err(err))
+		errorwriter.WriteError(w, "failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJSON)
+}
+
+func toUserResponse(user *models.User) UserResponse {
+	return UserResponse{
+		ID:       user.ID,
+		Email:    user.Email,
+		Role:     user.Role,
+		Disabled: user.Disabled,
+		TenantID: user.TenantID,
+	}
+}
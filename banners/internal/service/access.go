@@ -0,0 +1,45 @@
+package service
+
+import (
+	"banners/domain/models"
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrFeatureForbidden = errors.New("feature is not allowed")
+
+// checkFeatures fails with ErrFeatureForbidden unless the identity may access
+// every one of the features.
+func checkFeatures(identity *models.Identity, featureIDs ...int64) error {
+	for _, featureID := range featureIDs {
+		if !identity.CanAccessFeature(featureID) {
+			return fmt.Errorf("feature %d: %w", featureID, ErrFeatureForbidden)
+		}
+	}
+
+	return nil
+}
+
+// checkBannerAccess fails with ErrFeatureForbidden unless the identity may
+// access every feature the revisions of the banner have had, so that a
+// restricted API key cannot reach another feature through an old revision.
+func (s *Service) checkBannerAccess(ctx context.Context, identity *models.Identity, bannerID int) error {
+	const op = "service.checkBannerAccess"
+
+	if len(identity.FeatureIDs) == 0 {
+		return nil
+	}
+
+	featureIDs, err := s.bannerStorage.BannerFeaturesStorage(ctx, identity.TenantID, bannerID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = checkFeatures(identity, featureIDs...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"banners/lib/logger/sl"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// apiKeyPrefix marks our keys, so they are easy to spot in configs and
+// secret scanners.
+const apiKeyPrefix = "bnr_"
+
+var (
+	ErrAPIKeyNameEmpty = errors.New("api key name is empty")
+	ErrScopeNotAllowed = errors.New("api keys may only carry banner scopes")
+	ErrScopeNotGranted = errors.New("api keys may only carry scopes the actor has")
+	ErrMalformedAPIKey = errors.New("malformed api key")
+)
+
+type APIKeyStorage interface {
+	CreateAPIKeyStorage(ctx context.Context, key *models.APIKey, keyHash string, entry *models.AuditEntry) (*models.APIKey, error)
+	ListAPIKeysStorage(ctx context.Context, tenantID int64) ([]models.APIKey, error)
+	GetActiveAPIKeyStorage(ctx context.Context, keyHash string) (*models.APIKey, error)
+	RevokeAPIKeyStorage(ctx context.Context, tenantID int64, id int64, entry *models.AuditEntry) error
+}
+
+// CreateAPIKey creates a key for a machine client and returns it together
+// with the secret. Only the hash of the secret is stored, so it cannot be
+// shown again. Without scopes the key may only read banners. The actor must
+// have every scope of the key, so nobody can hand out more than they have.
+func (s *Service) CreateAPIKey(ctx context.Context, actor *models.Identity, name string, scopes []string, featureIDs []int64) (*models.APIKey, string, error) {
+	const op = "service.CreateAPIKey"
+
+	if name == "" {
+		return nil, "", fmt.Errorf("%s: %w", op, ErrAPIKeyNameEmpty)
+	}
+
+	if len(scopes) == 0 {
+		scopes = []string{models.PermBannerRead}
+	}
+
+	for _, scope := range scopes {
+		if !strings.HasPrefix(scope, "banner:") {
+			return nil, "", fmt.Errorf("%s: %w: %s", op, ErrScopeNotAllowed, scope)
+		}
+	}
+
+	unknown, err := s.roleStorage.UnknownPermissionsStorage(ctx, scopes)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	if len(unknown) != 0 {
+		return nil, "", fmt.Errorf("%s: %w: %s", op, storage.ErrPermissionNotFound, strings.Join(unknown, ", "))
+	}
+
+	for _, scope := range scopes {
+		allowed, err := s.HasPermission(ctx, actor, scope)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", op, err)
+		}
+		if !allowed {
+			return nil, "", fmt.Errorf("%s: %w: %s", op, ErrScopeNotGranted, scope)
+		}
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	secret = apiKeyPrefix + secret
+
+	key := &models.APIKey{
+		Name:       name,
+		Prefix:     secret[:len(apiKeyPrefix)+6],
+		TenantID:   actor.TenantID,
+		Scopes:     scopes,
+		FeatureIDs: featureIDs,
+		CreatedBy:  &actor.UserID,
+	}
+
+	created, err := s.apiKeyStorage.CreateAPIKeyStorage(ctx, key, hashToken(secret), newAuditEntry(actor, models.AuditAPIKeyCreate))
+	if err != nil {
+		s.log.Error("failed to create api key", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, secret, nil
+}
+
+func (s *Service) ListAPIKeys(ctx context.Context, tenantID int64) ([]models.APIKey, error) {
+	const op = "service.ListAPIKeys"
+
+	keys, err := s.apiKeyStorage.ListAPIKeysStorage(ctx, tenantID)
+	if err != nil {
+		s.log.Error("failed to list api keys", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+func (s *Service) RevokeAPIKey(ctx context.Context, actor *models.Identity, id int64) error {
+	const op = "service.RevokeAPIKey"
+
+	entry := newAuditEntry(actor, models.AuditAPIKeyRevoke)
+	entry.Target = strconv.FormatInt(id, 10)
+
+	err := s.apiKeyStorage.RevokeAPIKeyStorage(ctx, actor.TenantID, id, entry)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey resolves a key presented by a machine client. The key
+// is looked up on every request, so a revocation applies at once.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, secret string) (*models.Identity, error) {
+	const op = "service.AuthenticateAPIKey"
+
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, fmt.Errorf("%s: %w", op, ErrMalformedAPIKey)
+	}
+
+	key, err := s.apiKeyStorage.GetActiveAPIKeyStorage(ctx, hashToken(secret))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &models.Identity{
+		APIKeyID:   key.ID,
+		APIKeyName: key.Name,
+		TenantID:   key.TenantID,
+		Scopes:     key.Scopes,
+		FeatureIDs: key.FeatureIDs,
+	}, nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/lib/logger/sl"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var (
+	ErrSelfApproval     = errors.New("revision cannot be approved by its author or requester")
+	ErrApproverRequired = errors.New("approvals must be made by a user")
+)
+
+type ApprovalStorage interface {
+	SetFeaturePolicyStorage(ctx context.Context, tenantID int64, policy *models.FeaturePolicy, entry *models.AuditEntry) error
+	CreateApprovalRequestStorage(ctx context.Context, tenantID int64, request *models.ApprovalRequest, entry *models.AuditEntry) (*models.ApprovalRequest, error)
+	GetApprovalRequestStorage(ctx context.Context, tenantID int64, id int64) (*models.ApprovalRequest, error)
+	ListApprovalRequestsStorage(ctx context.Context, tenantID int64, bannerID int, limit int, offset int) ([]models.ApprovalRequest, error)
+	DecideApprovalRequestStorage(ctx context.Context, tenantID int64, id int64, status string, decidedBy *int64, comment string, entry *models.AuditEntry) (*models.ApprovalRequest, error)
+}
+
+func (s *Service) SetFeaturePolicy(ctx context.Context, actor *models.Identity, policy *models.FeaturePolicy) error {
+	const op = "service.SetFeaturePolicy"
+
+	err := checkFeatures(actor, policy.FeatureID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	entry := newAuditEntry(actor, models.AuditFeaturePolicySet)
+	entry.Target = strconv.FormatInt(policy.FeatureID, 10)
+	entry.After = models.Snapshot(policy)
+
+	err = s.approvalStorage.SetFeaturePolicyStorage(ctx, actor.TenantID, policy, entry)
+	if err != nil {
+		s.log.Error("failed to set feature policy", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RequestApproval asks for the revision to be approved. A zero revisionID
+// asks for the newest revision of the banner.
+func (s *Service) RequestApproval(ctx context.Context, actor *models.Identity, bannerID int, revisionID int, comment string) (*models.ApprovalRequest, error) {
+	const op = "service.RequestApproval"
+
+	err := s.checkBannerAccess(ctx, actor, bannerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if revisionID == 0 {
+		revisionID, err = s.bannerStorage.LatestRevisionStorage(ctx, actor.TenantID, bannerID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	request, err := s.approvalStorage.CreateApprovalRequestStorage(ctx, actor.TenantID, &models.ApprovalRequest{
+		BannerID:    int64(bannerID),
+		RevisionID:  int64(revisionID),
+		Comment:     comment,
+		RequestedBy: actor.AuthorID(),
+	}, newAuditEntry(actor, models.AuditApprovalRequest))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return request, nil
+}
+
+func (s *Service) ListApprovalRequests(ctx context.Context, identity *models.Identity, bannerID int, limit int, offset int) ([]models.ApprovalRequest, error) {
+	const op = "service.ListApprovalRequests"
+
+	err := s.checkBannerAccess(ctx, identity, bannerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	requests, err := s.approvalStorage.ListApprovalRequestsStorage(ctx, identity.TenantID, bannerID, limit, offset)
+	if err != nil {
+		s.log.Error("failed to list approval requests", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return requests, nil
+}
+
+// ApproveRequest approves a pending request. The approver has to be a user
+// other than the one who requested the approval or wrote the revision.
+func (s *Service) ApproveRequest(ctx context.Context, actor *models.Identity, id int64, comment string) (*models.ApprovalRequest, error) {
+	const op = "service.ApproveRequest"
+
+	approver := actor.AuthorID()
+	if approver == nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrApproverRequired)
+	}
+
+	request, err := s.approvalStorage.GetApprovalRequestStorage(ctx, actor.TenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if sameUser(request.RequestedBy, approver) || sameUser(request.RevisionAuthor, approver) {
+		return nil, fmt.Errorf("%s: %w", op, ErrSelfApproval)
+	}
+
+	decided, err := s.decideRequest(ctx, actor, request, models.ApprovalApproved, models.AuditApprovalApprove, comment)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return decided, nil
+}
+
+func (s *Service) RejectRequest(ctx context.Context, actor *models.Identity, id int64, comment string) (*models.ApprovalRequest, error) {
+	const op = "service.RejectRequest"
+
+	request, err := s.approvalStorage.GetApprovalRequestStorage(ctx, actor.TenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	decided, err := s.decideRequest(ctx, actor, request, models.ApprovalRejected, models.AuditApprovalReject, comment)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return decided, nil
+}
+
+func (s *Service) decideRequest(ctx context.Context, actor *models.Identity, request *models.ApprovalRequest, status string, action string, comment string) (*models.ApprovalRequest, error) {
+	const op = "service.decideRequest"
+
+	err := s.checkBannerAccess(ctx, actor, int(request.BannerID))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	decided, err := s.approvalStorage.DecideApprovalRequestStorage(ctx, actor.TenantID, request.ID, status, actor.AuthorID(), comment, newAuditEntry(actor, action))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return decided, nil
+}
+
+func sameUser(a, b *int64) bool {
+	return a != nil && b != nil && *a == *b
+}
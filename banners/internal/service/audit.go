@@ -0,0 +1,64 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"banners/lib/logger/sl"
+	"context"
+	"errors"
+	"fmt"
+)
+
+type AuditStorage interface {
+	ListAuditEntriesStorage(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+func (s *Service) ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	const op = "service.ListAudit"
+
+	entries, err := s.auditStorage.ListAuditEntriesStorage(ctx, filter)
+	if err != nil {
+		s.log.Error("failed to list audit entries", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+// newAuditEntry starts the record of an action of the actor. The storage
+// writes it in the transaction of the action, so that an action cannot be
+// left unaccounted for and a record cannot outlive an action that failed.
+func newAuditEntry(actor *models.Identity, action string) *models.AuditEntry {
+	entry := &models.AuditEntry{Action: action}
+
+	if actor != nil {
+		entry.TenantID = actor.TenantID
+		if actor.UserID != 0 {
+			entry.ActorID = &actor.UserID
+			entry.ActorEmail = actor.Email
+		}
+		if actor.IsAPIKey() {
+			entry.APIKeyID = &actor.APIKeyID
+		}
+	}
+
+	return entry
+}
+
+// bannerSnapshot returns the banner as it is now, or nil if it does not
+// exist or cannot be read. It tells which cached user banners a change
+// affects, the audit log reads its snapshots in the transaction of the
+// change instead.
+func (s *Service) bannerSnapshot(ctx context.Context, tenantID int64, bannerID int) *models.Banner {
+	banner, err := s.bannerStorage.GetBannerStorage(ctx, tenantID, bannerID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrBannerNotFound) {
+			s.log.Error("failed to snapshot banner", sl.Err(err))
+		}
+
+		return nil
+	}
+
+	return banner
+}
@@ -7,23 +7,75 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 )
 
+var (
+	ErrInvalidSchedule = errors.New("banner must end after it starts")
+	ErrInvalidSteps    = errors.New("steps must be positive")
+)
+
+// auditSnapshotLimit bounds how many banners a deferred deletion records.
+const auditSnapshotLimit = 100
+
 type BannerStorage interface {
-	PostBannerStorage(ctx context.Context, banner *models.Banner) (int, error)
-	GetUsersBannerStorage(ctx context.Context, tagID int, featureID int) (*models.Banner, error)
-	ChooseRevisionStorage(ctx context.Context, bannerID int, revisionID int) error
-	ListRevisionsStorage(ctx context.Context, bannerID int, limit int, offset int) (*[]models.Banner, error)
-	ListBannersStorage(ctx context.Context, featureID int, tagID int, limit int, offset int) (*[]models.Banner, error)
-	DeleteBannerStorage(ctx context.Context, bannerID int) error
-	DeleteUserBannerByFeatureTagStorage(ctx context.Context, tagID int, featureID int) error
-	PatchBannerStorage(ctx context.Context, banner *models.Banner) error
+	PostBannerStorage(ctx context.Context, tenantID int64, banner *models.Banner, entry *models.AuditEntry) (int, error)
+	GetBannerStorage(ctx context.Context, tenantID int64, bannerID int) (*models.Banner, error)
+	UserBannerCandidatesStorage(ctx context.Context, tenantID int64, tagID int, featureID int) ([]models.Banner, error)
+	ChooseRevisionStorage(ctx context.Context, tenantID int64, bannerID int, revisionID int, publishedBy *int64, entry *models.AuditEntry) error
+	RollbackBannerStorage(ctx context.Context, tenantID int64, bannerID int, steps int, entry *models.AuditEntry) (int, error)
+	GetUsersBannerAsOfStorage(ctx context.Context, tenantID int64, tagID int, featureID int, at time.Time) (*models.LiveBanner, error)
+	ListRevisionsStorage(ctx context.Context, tenantID int64, bannerID int, limit int, offset int) (*[]models.Banner, error)
+	ListBannersStorage(ctx context.Context, tenantID int64, featureID int, tagID int, includeScheduled bool, limit int, offset int) (*[]models.Banner, error)
+	DeleteBannerStorage(ctx context.Context, tenantID int64, bannerID int, entry *models.AuditEntry) error
+	DeleteUserBannerByFeatureTagStorage(ctx context.Context, tenantID int64, tagID int, featureID int, entry *models.AuditEntry) error
+	PatchBannerStorage(ctx context.Context, tenantID int64, banner *models.Banner, publish bool, entry *models.AuditEntry) (int, error)
+	LatestRevisionStorage(ctx context.Context, tenantID int64, bannerID int) (int, error)
+	BannerFeaturesStorage(ctx context.Context, tenantID int64, bannerID int) ([]int64, error)
+	GetRevisionStorage(ctx context.Context, tenantID int64, bannerID int, revisionID int) (*models.Banner, error)
+	ListPrunableRevisionsStorage(ctx context.Context, tenantID int64, policy models.RetentionPolicy, limit int, offset int) ([]models.PrunableRevision, error)
+	PruneRevisionsStorage(ctx context.Context, policy models.RetentionPolicy) (int64, error)
+	SetDefaultBannerStorage(ctx context.Context, tenantID int64, featureDefault *models.FeatureDefault, entry *models.AuditEntry) error
+	GetDefaultBannerIDStorage(ctx context.Context, tenantID int64, featureID int64) (int64, error)
+	DeleteDefaultBannerStorage(ctx context.Context, tenantID int64, featureID int64, entry *models.AuditEntry) error
+	GetDefaultBannerStorage(ctx context.Context, tenantID int64, featureID int) (*models.Banner, error)
+	GetDefaultBannerAsOfStorage(ctx context.Context, tenantID int64, featureID int, at time.Time) (*models.LiveBanner, error)
+	StartRolloutStorage(ctx context.Context, tenantID int64, rollout *models.Rollout, entry *models.AuditEntry) (*models.Rollout, error)
+	GetRolloutStorage(ctx context.Context, tenantID int64, bannerID int) (*models.Rollout, error)
+	SetRolloutPercentStorage(ctx context.Context, tenantID int64, bannerID int, percent int, entry *models.AuditEntry) (*models.Rollout, error)
+	DeleteRolloutStorage(ctx context.Context, tenantID int64, bannerID int, entry *models.AuditEntry) error
 }
 
-func (s *Service) PostBanner(ctx context.Context, banner *models.Banner) (int, error) {
+func (s *Service) PostBanner(ctx context.Context, actor *models.Identity, banner *models.Banner) (int, error) {
 	const op = "service.PostBanner"
 
-	bannerID, err := s.bannerStorage.PostBannerStorage(ctx, banner)
+	if !banner.ValidSchedule() {
+		return -1, fmt.Errorf("%s: %w", op, ErrInvalidSchedule)
+	}
+
+	err := checkFeatures(actor, banner.FeatureID)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.checkRegistries(ctx, actor.TenantID, banner)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	banner.Targeting, err = checkTargeting(banner.Targeting)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.checkContent(ctx, actor.TenantID, banner)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	banner.UpdatedBy = actor.AuthorID()
+
+	bannerID, err := s.bannerStorage.PostBannerStorage(ctx, actor.TenantID, banner, newAuditEntry(actor, models.AuditBannerCreate))
 	if err != nil {
 		s.log.Error("failed to post banner", sl.Err(err))
 
@@ -33,65 +85,140 @@ func (s *Service) PostBanner(ctx context.Context, banner *models.Banner) (int, e
 	return bannerID, nil
 }
 
-func (s *Service) GetUserBanner(ctx context.Context, tagID int, featureID int) (*models.Banner, error) {
+// GetUserBanner returns the banner of the feature for the tag that targets
+// the audience, or the default banner of the feature if none does. Users
+// with a key get their variant instead while an experiment runs for the
+// feature and the tag, and the revision being rolled out if the rollout
+// reaches them.
+func (s *Service) GetUserBanner(ctx context.Context, tenantID int64, tagID int, featureID int, audience *models.Audience) (*models.Banner, error) {
 	const op = "service.GetUserBanner"
 
-	banner, err := s.bannerStorage.GetUsersBannerStorage(ctx, tagID, featureID)
+	err := checkAudience(audience)
 	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if audience.UserKey != "" {
+		banner, err := s.experimentBanner(ctx, tenantID, tagID, featureID, audience.UserKey)
+		if err == nil {
+			return banner, nil
+		}
+		if !errors.Is(err, storage.ErrExperimentNotFound) {
+			s.log.Error("failed to get experiment banner", sl.Err(err))
+
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	now := time.Now()
+
+	candidates, err := s.bannerStorage.UserBannerCandidatesStorage(ctx, tenantID, tagID, featureID)
+	if err != nil && !errors.Is(err, storage.ErrBannerNotFound) {
 		s.log.Error("failed to get user banner", sl.Err(err))
 
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	banner := pickBanner(candidates, audience, now)
+	if banner == nil {
+		banner, err = s.bannerStorage.GetDefaultBannerStorage(ctx, tenantID, featureID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if !matchTargeting(banner.Targeting, audience) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+		}
+	}
+
+	return pickRevision(banner, audience, now), nil
+}
+
+// GetUserBannerAsOf returns the banner that users of the tag saw for the
+// feature at the given time, the default of the feature if no banner had
+// the tag or one of its ancestors then.
+func (s *Service) GetUserBannerAsOf(ctx context.Context, tenantID int64, tagID int, featureID int, at time.Time) (*models.LiveBanner, error) {
+	const op = "service.GetUserBannerAsOf"
+
+	banner, err := s.bannerStorage.GetUsersBannerAsOfStorage(ctx, tenantID, tagID, featureID, at)
+	if errors.Is(err, storage.ErrBannerNotFound) {
+		banner, err = s.bannerStorage.GetDefaultBannerAsOfStorage(ctx, tenantID, featureID, at)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	return banner, nil
 }
 
-func (s *Service) GetUserBannerCache(ctx context.Context, tagID int, featureID int) (*models.Banner, error) {
+// GetUserBannerCache is GetUserBanner going through the cache. All the
+// candidate banners of the tag are cached and the targeting is matched
+// after, so that a single entry serves every platform, app version, locale
+// and attribute.
+func (s *Service) GetUserBannerCache(ctx context.Context, tenantID int64, tagID int, featureID int, audience *models.Audience) (*models.Banner, error) {
 	const op = "service.GetUserBannerCache"
 
-	key := fmt.Sprintf("banner:%d:%d", tagID, featureID)
-	banner, err := s.c.Get(ctx, key)
-	if errors.Is(err, storage.ErrNotFoundInCache) {
-		banner, err := s.bannerStorage.GetUsersBannerStorage(ctx, tagID, featureID)
-		if err != nil {
-			s.log.Error("failed to get user banner", sl.Err(err))
+	err := checkAudience(audience)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if audience.UserKey != "" {
+		banner, err := s.experimentBannerCache(ctx, tenantID, tagID, featureID, audience.UserKey)
+		if err == nil {
+			return banner, nil
+		}
+		if !errors.Is(err, storage.ErrExperimentNotFound) {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
+	}
 
-		err = s.SetUserBannerCache(ctx, tagID, featureID, *banner)
-		if err != nil {
-			s.log.Error("failed to set user banner in cache", sl.Err(err))
+	var candidates []models.Banner
+	err = s.c.GetValue(ctx, userBannerKey(tenantID, int64(tagID), int64(featureID)), &candidates)
+	if errors.Is(err, storage.ErrNotFoundInCache) {
+		candidates, err = s.bannerStorage.UserBannerCandidatesStorage(ctx, tenantID, tagID, featureID)
+		if err != nil && !errors.Is(err, storage.ErrBannerNotFound) {
+			s.log.Error("failed to get user banner", sl.Err(err))
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 
-		return banner, nil
-	}
-	if err != nil {
+		if len(candidates) > 0 {
+			err = s.SetUserBannerCache(ctx, tenantID, tagID, featureID, candidates)
+			if err != nil {
+				s.log.Error("failed to set user banner in cache", sl.Err(err))
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	} else if err != nil {
 		s.log.Error("failed to get user banner from cache", sl.Err(err))
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	//var content json.RawMessage
-	//err = content.UnmarshalJSON([]byte(bannerContentStr))
-	//if err != nil {
-	//	s.log.Error("failed to unmarshal content string to json", sl.Err(err))
-	//	return nil, fmt.Errorf("%s: %w", op, err)
-	//}
+	now := time.Now()
 
-	return banner, nil
+	// The banners may have been cached before their schedule ended, which
+	// pickBanner checks again.
+	banner := pickBanner(candidates, audience, now)
+	if banner == nil {
+		banner, err = s.defaultBannerCache(ctx, tenantID, featureID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if !matchTargeting(banner.Targeting, audience) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+		}
+	}
+
+	return pickRevision(banner, audience, now), nil
 }
 
-func (s *Service) SetUserBannerCache(ctx context.Context, tagID int, featureID int, banner models.Banner) error {
+// SetUserBannerCache caches the candidate banners of the feature for the
+// tag, in order of preference.
+func (s *Service) SetUserBannerCache(ctx context.Context, tenantID int64, tagID int, featureID int, banners []models.Banner) error {
 	const op = "service.SetUserBannerCache"
 
-	//bytes, err := content.MarshalJSON()
-	//if err != nil {
-	//	s.log.Error("failed to marshal content to string", sl.Err(err))
-	//	return fmt.Errorf("%s: %w", op, err)
-	//}
-
-	key := fmt.Sprintf("banner:%d:%d", tagID, featureID)
-	err := s.c.Set(ctx, key, banner)
+	err := s.c.SetValue(ctx, userBannerKey(tenantID, int64(tagID), int64(featureID)), banners)
 	if err != nil {
 		s.log.Error("failed to set user banner in cache", sl.Err(err))
 		return fmt.Errorf("%s: %w", op, err)
@@ -100,23 +227,37 @@ func (s *Service) SetUserBannerCache(ctx context.Context, tagID int, featureID i
 	return nil
 }
 
-func (s *Service) ChooseRevision(ctx context.Context, bannerID int, revisionID int) error {
+func (s *Service) ChooseRevision(ctx context.Context, actor *models.Identity, bannerID int, revisionID int) error {
 	const op = "service.ChooseRevision"
 
-	err := s.bannerStorage.ChooseRevisionStorage(ctx, bannerID, revisionID)
+	err := s.checkBannerAccess(ctx, actor, bannerID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	before := s.bannerSnapshot(ctx, actor.TenantID, bannerID)
+
+	err = s.bannerStorage.ChooseRevisionStorage(ctx, actor.TenantID, bannerID, revisionID, actor.AuthorID(), newAuditEntry(actor, models.AuditBannerChooseRevision))
 	if err != nil {
 		s.log.Error("failed to choose revision", sl.Err(err))
 
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	s.invalidateUserBanners(ctx, actor.TenantID, before, s.bannerSnapshot(ctx, actor.TenantID, bannerID))
+
 	return nil
 }
 
-func (s *Service) ListRevisions(ctx context.Context, bannerID int, limit int, offset int) (*[]models.Banner, error) {
+func (s *Service) ListRevisions(ctx context.Context, identity *models.Identity, bannerID int, limit int, offset int) (*[]models.Banner, error) {
 	const op = "service.ListRevisions"
 
-	revisions, err := s.bannerStorage.ListRevisionsStorage(ctx, bannerID, limit, offset)
+	err := s.checkBannerAccess(ctx, identity, bannerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	revisions, err := s.bannerStorage.ListRevisionsStorage(ctx, identity.TenantID, bannerID, limit, offset)
 	if err != nil {
 		s.log.Error("failed to list revisions", sl.Err(err))
 
@@ -126,36 +267,70 @@ func (s *Service) ListRevisions(ctx context.Context, bannerID int, limit int, of
 	return revisions, nil
 }
 
-func (s *Service) ListBanners(ctx context.Context, featureID int, tagID int, limit int, offset int) (*[]models.Banner, error) {
+// ListBanners lists the chosen revisions of the banners. With includeNames
+// the registered names of their features and tags are filled in.
+func (s *Service) ListBanners(ctx context.Context, tenantID int64, featureID int, tagID int, includeScheduled bool, includeNames bool, limit int, offset int) (*[]models.Banner, error) {
 	const op = "service.ListBanners"
 
-	banners, err := s.bannerStorage.ListBannersStorage(ctx, featureID, tagID, limit, offset)
+	banners, err := s.bannerStorage.ListBannersStorage(ctx, tenantID, featureID, tagID, includeScheduled, limit, offset)
 	if err != nil {
 		s.log.Error("failed to list banners", sl.Err(err))
 
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if includeNames {
+		err = s.nameBanners(ctx, tenantID, *banners)
+		if err != nil {
+			s.log.Error("failed to name banners", sl.Err(err))
+
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	return banners, nil
 }
 
-func (s *Service) DeleteBanner(ctx context.Context, bannerID int) error {
+func (s *Service) DeleteBanner(ctx context.Context, actor *models.Identity, bannerID int) error {
 	const op = "service.DeleteBanner"
 
-	err := s.bannerStorage.DeleteBannerStorage(ctx, bannerID)
+	err := s.checkBannerAccess(ctx, actor, bannerID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	before := s.bannerSnapshot(ctx, actor.TenantID, bannerID)
+
+	err = s.bannerStorage.DeleteBannerStorage(ctx, actor.TenantID, bannerID, newAuditEntry(actor, models.AuditBannerDelete))
 	if err != nil {
 		s.log.Error("failed to list revisions", sl.Err(err))
 
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	s.invalidateUserBanners(ctx, actor.TenantID, before)
+
 	return nil
 }
 
-func (s *Service) DeleteUserBannerByFeatureTag(ctx context.Context, tagID int, featureID int) error {
+// DeleteUserBannerByFeatureTag schedules the deletion of the banners of the
+// feature and tag. The audit entry is written along with the deletion and
+// holds the banners as they were when the deletion was scheduled.
+func (s *Service) DeleteUserBannerByFeatureTag(ctx context.Context, actor *models.Identity, tagID int, featureID int) error {
 	const op = "service.DeleteUserBannerByFeatureTag"
 
-	err := s.bannerStorage.DeleteUserBannerByFeatureTagStorage(ctx, tagID, featureID)
+	before, err := s.bannerStorage.ListBannersStorage(ctx, actor.TenantID, featureID, tagID, true, auditSnapshotLimit, 0)
+	if err != nil {
+		s.log.Error("failed to snapshot banners", sl.Err(err))
+	}
+
+	entry := newAuditEntry(actor, models.AuditBannerDeleteDeferred)
+	entry.Target = fmt.Sprintf("feature:%d tag:%d", featureID, tagID)
+	if before != nil {
+		entry.Before = models.Snapshot(before)
+	}
+
+	err = s.bannerStorage.DeleteUserBannerByFeatureTagStorage(ctx, actor.TenantID, tagID, featureID, entry)
 	if err != nil {
 		s.log.Error("failed to list revisions", sl.Err(err))
 
@@ -165,15 +340,150 @@ func (s *Service) DeleteUserBannerByFeatureTag(ctx context.Context, tagID int, f
 	return nil
 }
 
-func (s *Service) PatchBanner(ctx context.Context, banner *models.Banner) error {
+// PatchBanner adds a draft revision to the banner, or a live one if publish
+// is set, and returns the ID of the revision.
+func (s *Service) PatchBanner(ctx context.Context, actor *models.Identity, banner *models.Banner, publish bool) (int, error) {
 	const op = "service.PatchBanner"
 
-	err := s.bannerStorage.PatchBannerStorage(ctx, banner)
+	if !banner.ValidSchedule() {
+		return -1, fmt.Errorf("%s: %w", op, ErrInvalidSchedule)
+	}
+
+	err := checkFeatures(actor, banner.FeatureID)
 	if err != nil {
-		s.log.Error("failed to list revisions", sl.Err(err))
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
 
-		return fmt.Errorf("%s: %w", op, err)
+	err = s.checkBannerAccess(ctx, actor, int(banner.BannerID))
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	err = s.checkRegistries(ctx, actor.TenantID, banner)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	banner.Targeting, err = checkTargeting(banner.Targeting)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.checkContent(ctx, actor.TenantID, banner)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	banner.UpdatedBy = actor.AuthorID()
+
+	before := s.bannerSnapshot(ctx, actor.TenantID, int(banner.BannerID))
+
+	revisionID, err := s.bannerStorage.PatchBannerStorage(ctx, actor.TenantID, banner, publish, newAuditEntry(actor, models.AuditBannerPatch))
+	if err != nil {
+		s.log.Error("failed to patch banner", sl.Err(err))
+
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if publish {
+		s.invalidateUserBanners(ctx, actor.TenantID, before, s.bannerSnapshot(ctx, actor.TenantID, int(banner.BannerID)))
+	}
+
+	return revisionID, nil
+}
+
+// PublishRevision makes the revision the live one of the banner. A zero
+// revisionID publishes the newest revision. It returns the ID of the
+// published revision.
+func (s *Service) PublishRevision(ctx context.Context, actor *models.Identity, bannerID int, revisionID int) (int, error) {
+	const op = "service.PublishRevision"
+
+	err := s.checkBannerAccess(ctx, actor, bannerID)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if revisionID == 0 {
+		revisionID, err = s.bannerStorage.LatestRevisionStorage(ctx, actor.TenantID, bannerID)
+		if err != nil {
+			return -1, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	before := s.bannerSnapshot(ctx, actor.TenantID, bannerID)
+
+	err = s.bannerStorage.ChooseRevisionStorage(ctx, actor.TenantID, bannerID, revisionID, actor.AuthorID(), newAuditEntry(actor, models.AuditBannerPublish))
+	if err != nil {
+		s.log.Error("failed to publish revision", sl.Err(err))
+
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.invalidateUserBanners(ctx, actor.TenantID, before, s.bannerSnapshot(ctx, actor.TenantID, bannerID))
+
+	return revisionID, nil
+}
+
+// RollbackBanner makes the revision that was live steps publications ago
+// live again and returns its ID.
+func (s *Service) RollbackBanner(ctx context.Context, actor *models.Identity, bannerID int, steps int) (int, error) {
+	const op = "service.RollbackBanner"
+
+	if steps < 1 {
+		return -1, fmt.Errorf("%s: %w", op, ErrInvalidSteps)
+	}
+
+	err := s.checkBannerAccess(ctx, actor, bannerID)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	before := s.bannerSnapshot(ctx, actor.TenantID, bannerID)
+
+	revisionID, err := s.bannerStorage.RollbackBannerStorage(ctx, actor.TenantID, bannerID, steps, newAuditEntry(actor, models.AuditBannerRollback))
+	if err != nil {
+		s.log.Error("failed to roll back banner", sl.Err(err))
+
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.invalidateUserBanners(ctx, actor.TenantID, before, s.bannerSnapshot(ctx, actor.TenantID, bannerID))
+
+	return revisionID, nil
+}
+
+// invalidateUserBanners drops the cached user banners of every feature and
+// tag pair of the given banners. Failures are only logged, the cache entries
+// expire on their own.
+func (s *Service) invalidateUserBanners(ctx context.Context, tenantID int64, banners ...*models.Banner) {
+	var keys []string
+	for _, banner := range banners {
+		if banner == nil {
+			continue
+		}
+
+		// Users of nested tags may have been given the banner too.
+		tagIDs, err := s.registryStorage.TagDescendantsStorage(ctx, tenantID, banner.TagIDs)
+		if err != nil {
+			s.log.Error("failed to find nested tags", sl.Err(err))
+
+			tagIDs = banner.TagIDs
+		}
+
+		for _, tagID := range tagIDs {
+			keys = append(keys, userBannerKey(tenantID, tagID, banner.FeatureID))
+		}
+
+		// The banner may be the default of its feature.
+		keys = append(keys, defaultBannerKey(tenantID, banner.FeatureID))
+	}
+
+	err := s.c.Del(ctx, keys...)
+	if err != nil {
+		s.log.Error("failed to invalidate user banners", sl.Err(err))
+	}
+}
+
+func userBannerKey(tenantID int64, tagID int64, featureID int64) string {
+	return fmt.Sprintf("user_banners:%d:%d:%d", tenantID, tagID, featureID)
 }
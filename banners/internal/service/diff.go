@@ -0,0 +1,85 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/lib/jsonpatch"
+	"context"
+	"fmt"
+	"sort"
+)
+
+// DiffRevisions compares two revisions of the banner.
+func (s *Service) DiffRevisions(ctx context.Context, identity *models.Identity, bannerID int, fromID int, toID int) (*models.RevisionDiff, error) {
+	const op = "service.DiffRevisions"
+
+	err := s.checkBannerAccess(ctx, identity, bannerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	from, err := s.bannerStorage.GetRevisionStorage(ctx, identity.TenantID, bannerID, fromID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	to, err := s.bannerStorage.GetRevisionStorage(ctx, identity.TenantID, bannerID, toID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	content, err := jsonpatch.Diff(from.Content, to.Content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	diff := &models.RevisionDiff{
+		BannerID: int64(bannerID),
+		From:     from.Revision,
+		To:       to.Revision,
+		Content:  content,
+	}
+
+	if from.FeatureID != to.FeatureID {
+		diff.FeatureID = &models.ValueChange{From: from.FeatureID, To: to.FeatureID}
+	}
+
+	if from.IsActive != to.IsActive {
+		diff.IsActive = &models.ValueChange{From: from.IsActive, To: to.IsActive}
+	}
+
+	added, removed := diffTags(from.TagIDs, to.TagIDs)
+	if len(added) != 0 || len(removed) != 0 {
+		diff.TagIDs = &models.TagChange{Added: added, Removed: removed}
+	}
+
+	return diff, nil
+}
+
+// diffTags returns the sorted tags that are only in to and only in from.
+func diffTags(from, to []int64) (added, removed []int64) {
+	added, removed = []int64{}, []int64{}
+
+	inFrom := make(map[int64]bool, len(from))
+	for _, tagID := range from {
+		inFrom[tagID] = true
+	}
+
+	inTo := make(map[int64]bool, len(to))
+	for _, tagID := range to {
+		inTo[tagID] = true
+		if !inFrom[tagID] {
+			added = append(added, tagID)
+		}
+	}
+
+	for _, tagID := range from {
+		if !inTo[tagID] {
+			removed = append(removed, tagID)
+		}
+	}
+
+	sort.Slice(added, func(i, j int) bool { return added[i] < added[j] })
+	sort.Slice(removed, func(i, j int) bool { return removed[i] < removed[j] })
+
+	return added, removed
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"banners/lib/bucket"
+	"banners/lib/logger/sl"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var (
+	ErrExperimentTarget   = errors.New("feature_id and tag_id must be positive")
+	ErrExperimentName     = errors.New("name must not be empty")
+	ErrExperimentVariants = errors.New("experiment needs at least two variants with distinct non-empty keys, positive weights and content")
+)
+
+type ExperimentStorage interface {
+	CreateExperimentStorage(ctx context.Context, tenantID int64, experiment *models.Experiment, entry *models.AuditEntry) (*models.Experiment, error)
+	GetExperimentStorage(ctx context.Context, tenantID int64, id int64) (*models.Experiment, error)
+	ListExperimentsStorage(ctx context.Context, tenantID int64, featureID int64, tagID int64, limit int, offset int) ([]models.Experiment, error)
+	RunningExperimentStorage(ctx context.Context, tenantID int64, featureID int64, tagID int64) (*models.Experiment, error)
+	VariantContentStorage(ctx context.Context, tenantID int64, experimentID int64, key string) (json.RawMessage, error)
+	StopExperimentStorage(ctx context.Context, tenantID int64, id int64, entry *models.AuditEntry) (*models.Experiment, error)
+}
+
+// CreateExperiment starts an experiment on the feature and tag. The content
+// of every variant must pass the schema of the feature, like banners do.
+// Variants are never approved, so features that require approval have no
+// experiments.
+func (s *Service) CreateExperiment(ctx context.Context, actor *models.Identity, experiment *models.Experiment) (*models.Experiment, error) {
+	const op = "service.CreateExperiment"
+
+	err := checkFeatures(actor, experiment.FeatureID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if experiment.FeatureID <= 0 || experiment.TagID <= 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrExperimentTarget)
+	}
+
+	if experiment.Name == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrExperimentName)
+	}
+
+	if !validVariants(experiment.Variants) {
+		return nil, fmt.Errorf("%s: %w", op, ErrExperimentVariants)
+	}
+
+	err = s.checkRegistries(ctx, actor.TenantID, &models.Banner{FeatureID: experiment.FeatureID, TagIDs: []int64{experiment.TagID}})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, variant := range experiment.Variants {
+		err = s.checkContent(ctx, actor.TenantID, &models.Banner{FeatureID: experiment.FeatureID, Content: variant.Content})
+		if err != nil {
+			return nil, fmt.Errorf("%s: variant %q: %w", op, variant.Key, err)
+		}
+	}
+
+	experiment.CreatedBy = actor.AuthorID()
+
+	created, err := s.experimentStorage.CreateExperimentStorage(ctx, actor.TenantID, experiment, newAuditEntry(actor, models.AuditExperimentCreate))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.invalidateExperiment(ctx, actor.TenantID, created.FeatureID, created.TagID)
+
+	return created, nil
+}
+
+func (s *Service) GetExperiment(ctx context.Context, tenantID int64, id int64) (*models.Experiment, error) {
+	const op = "service.GetExperiment"
+
+	experiment, err := s.experimentStorage.GetExperimentStorage(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return experiment, nil
+}
+
+func (s *Service) ListExperiments(ctx context.Context, tenantID int64, featureID int64, tagID int64, limit int, offset int) ([]models.Experiment, error) {
+	const op = "service.ListExperiments"
+
+	experiments, err := s.experimentStorage.ListExperimentsStorage(ctx, tenantID, featureID, tagID, limit, offset)
+	if err != nil {
+		s.log.Error("failed to list experiments", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return experiments, nil
+}
+
+// StopExperiment stops the experiment, so that its users get the banner of
+// the feature and tag again.
+func (s *Service) StopExperiment(ctx context.Context, actor *models.Identity, id int64) (*models.Experiment, error) {
+	const op = "service.StopExperiment"
+
+	before, err := s.experimentStorage.GetExperimentStorage(ctx, actor.TenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = checkFeatures(actor, before.FeatureID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	after, err := s.experimentStorage.StopExperimentStorage(ctx, actor.TenantID, id, newAuditEntry(actor, models.AuditExperimentStop))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.invalidateExperiment(ctx, actor.TenantID, after.FeatureID, after.TagID)
+
+	return after, nil
+}
+
+// experimentBanner returns the variant of the experiment running for the
+// feature and the requested tag that the user key is assigned to. It
+// returns storage.ErrExperimentNotFound if no experiment runs there.
+func (s *Service) experimentBanner(ctx context.Context, tenantID int64, tagID int, featureID int, userKey string) (*models.Banner, error) {
+	const op = "service.experimentBanner"
+
+	experiment, err := s.experimentStorage.RunningExperimentStorage(ctx, tenantID, int64(featureID), int64(tagID))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	variant := assignVariant(experiment, userKey)
+
+	content, err := s.experimentStorage.VariantContentStorage(ctx, tenantID, experiment.ID, variant.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return variantBanner(experiment, variant.Key, content), nil
+}
+
+// experimentBannerCache is experimentBanner going through the cache. The
+// experiment is cached per feature and tag, also when there is none, and
+// the content of each of its variants under a key of its own.
+func (s *Service) experimentBannerCache(ctx context.Context, tenantID int64, tagID int, featureID int, userKey string) (*models.Banner, error) {
+	const op = "service.experimentBannerCache"
+
+	experiment, err := s.runningExperimentCache(ctx, tenantID, int64(featureID), int64(tagID))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	variant := assignVariant(experiment, userKey)
+	key := variantBannerKey(tenantID, experiment.ID, variant.Key)
+
+	banner, err := s.c.Get(ctx, key)
+	if err == nil {
+		return banner, nil
+	}
+	if !errors.Is(err, storage.ErrNotFoundInCache) {
+		s.log.Error("failed to get variant banner from cache", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	content, err := s.experimentStorage.VariantContentStorage(ctx, tenantID, experiment.ID, variant.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	banner = variantBanner(experiment, variant.Key, content)
+
+	// Variants never change, so their entries only expire.
+	err = s.c.Set(ctx, key, *banner)
+	if err != nil {
+		s.log.Error("failed to set variant banner in cache", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return banner, nil
+}
+
+// runningExperimentCache returns the experiment running for the feature and
+// tag without the content of its variants. An experiment with a zero ID is
+// cached when none runs, so that the banners of feature and tag pairs
+// without experiments are not slowed down.
+func (s *Service) runningExperimentCache(ctx context.Context, tenantID int64, featureID int64, tagID int64) (*models.Experiment, error) {
+	const op = "service.runningExperimentCache"
+
+	key := experimentKey(tenantID, tagID, featureID)
+
+	var experiment models.Experiment
+	err := s.c.GetValue(ctx, key, &experiment)
+	if err == nil {
+		if experiment.ID == 0 {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrExperimentNotFound)
+		}
+
+		return &experiment, nil
+	}
+	if !errors.Is(err, storage.ErrNotFoundInCache) {
+		s.log.Error("failed to get experiment from cache", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	running, err := s.experimentStorage.RunningExperimentStorage(ctx, tenantID, featureID, tagID)
+	if err != nil && !errors.Is(err, storage.ErrExperimentNotFound) {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if running == nil {
+		running = &models.Experiment{}
+	}
+
+	err = s.c.SetValue(ctx, key, running)
+	if err != nil {
+		s.log.Error("failed to set experiment in cache", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if running.ID == 0 {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrExperimentNotFound)
+	}
+
+	return running, nil
+}
+
+func (s *Service) invalidateExperiment(ctx context.Context, tenantID int64, featureID int64, tagID int64) {
+	err := s.c.Del(ctx, experimentKey(tenantID, tagID, featureID))
+	if err != nil {
+		s.log.Error("failed to invalidate experiment", sl.Err(err))
+	}
+}
+
+// assignVariant picks the variant of the user key. Each variant gets a
+// share of the keys in proportion to its weight, and as the experiment ID
+// seeds the hash, users are shuffled anew for every experiment.
+func assignVariant(experiment *models.Experiment, userKey string) models.Variant {
+	var total uint64
+	for _, variant := range experiment.Variants {
+		total += uint64(variant.Weight)
+	}
+
+	point := bucket.Of(strconv.FormatInt(experiment.ID, 10), userKey, total)
+
+	for _, variant := range experiment.Variants {
+		if point < uint64(variant.Weight) {
+			return variant
+		}
+		point -= uint64(variant.Weight)
+	}
+
+	return experiment.Variants[len(experiment.Variants)-1]
+}
+
+func validVariants(variants []models.Variant) bool {
+	if len(variants) < 2 {
+		return false
+	}
+
+	keys := make(map[string]bool, len(variants))
+	for _, variant := range variants {
+		if variant.Key == "" || keys[variant.Key] || variant.Weight <= 0 || len(variant.Content) == 0 {
+			return false
+		}
+		keys[variant.Key] = true
+	}
+
+	return true
+}
+
+func variantBanner(experiment *models.Experiment, key string, content json.RawMessage) *models.Banner {
+	return &models.Banner{
+		FeatureID:    experiment.FeatureID,
+		TagIDs:       []int64{experiment.TagID},
+		IsActive:     true,
+		MatchedTagID: experiment.TagID,
+		Variant:      key,
+		Content:      content,
+	}
+}
+
+func experimentKey(tenantID int64, tagID int64, featureID int64) string {
+	return fmt.Sprintf("experiment:%d:%d:%d", tenantID, tagID, featureID)
+}
+
+func variantBannerKey(tenantID int64, experimentID int64, key string) string {
+	return fmt.Sprintf("banner_variant:%d:%d:%s", tenantID, experimentID, key)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"banners/domain/models"
+	"strconv"
+	"testing"
+)
+
+func TestAssignVariantFollowsWeights(t *testing.T) {
+	experiment := &models.Experiment{
+		ID: 1,
+		Variants: []models.Variant{
+			{Key: "a", Weight: 1},
+			{Key: "b", Weight: 3},
+			{Key: "c", Weight: 6},
+		},
+	}
+
+	const keys = 100000
+
+	counts := map[string]int{}
+	for i := 0; i < keys; i++ {
+		counts[assignVariant(experiment, "user-"+strconv.Itoa(i)).Key]++
+	}
+
+	for _, variant := range experiment.Variants {
+		want := keys * variant.Weight / 10
+		if got := counts[variant.Key]; got < want*9/10 || got > want*11/10 {
+			t.Errorf("variant %q got %d keys, want about %d", variant.Key, got, want)
+		}
+	}
+}
+
+func TestAssignVariantIsSticky(t *testing.T) {
+	experiment := &models.Experiment{
+		ID:       1,
+		Variants: []models.Variant{{Key: "a", Weight: 50}, {Key: "b", Weight: 50}},
+	}
+
+	for i := 0; i < 100; i++ {
+		key := "user-" + strconv.Itoa(i)
+		if assignVariant(experiment, key).Key != assignVariant(experiment, key).Key {
+			t.Fatalf("user %q got different variants", key)
+		}
+	}
+}
+
+func TestAssignVariantShufflesPerExperiment(t *testing.T) {
+	variants := []models.Variant{{Key: "a", Weight: 50}, {Key: "b", Weight: 50}}
+	first := &models.Experiment{ID: 1, Variants: variants}
+	second := &models.Experiment{ID: 2, Variants: variants}
+
+	const keys = 10000
+
+	same := 0
+	for i := 0; i < keys; i++ {
+		key := "user-" + strconv.Itoa(i)
+		if assignVariant(first, key).Key == assignVariant(second, key).Key {
+			same++
+		}
+	}
+
+	if same < keys*45/100 || same > keys*55/100 {
+		t.Errorf("%d of %d users got the same variant in both experiments, want about half", same, keys)
+	}
+}
+
+func TestValidVariants(t *testing.T) {
+	content := []byte(`{"title": "a"}`)
+
+	tests := []struct {
+		name     string
+		variants []models.Variant
+		want     bool
+	}{
+		{
+			name:     "valid",
+			variants: []models.Variant{{Key: "a", Weight: 1, Content: content}, {Key: "b", Weight: 2, Content: content}},
+			want:     true,
+		},
+		{
+			name:     "single variant",
+			variants: []models.Variant{{Key: "a", Weight: 1, Content: content}},
+		},
+		{
+			name:     "duplicate key",
+			variants: []models.Variant{{Key: "a", Weight: 1, Content: content}, {Key: "a", Weight: 1, Content: content}},
+		},
+		{
+			name:     "zero weight",
+			variants: []models.Variant{{Key: "a", Weight: 1, Content: content}, {Key: "b", Content: content}},
+		},
+		{
+			name:     "no content",
+			variants: []models.Variant{{Key: "a", Weight: 1, Content: content}, {Key: "b", Weight: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validVariants(tt.variants); got != tt.want {
+				t.Errorf("validVariants() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
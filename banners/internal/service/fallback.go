@@ -0,0 +1,113 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"banners/lib/logger/sl"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SetDefaultBanner makes the banner the one users get for the feature when
+// no banner has their tag.
+func (s *Service) SetDefaultBanner(ctx context.Context, actor *models.Identity, featureDefault *models.FeatureDefault) error {
+	const op = "service.SetDefaultBanner"
+
+	err := checkFeatures(actor, featureDefault.FeatureID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.checkBannerAccess(ctx, actor, int(featureDefault.BannerID))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.bannerStorage.SetDefaultBannerStorage(ctx, actor.TenantID, featureDefault, newAuditEntry(actor, models.AuditFeatureDefaultSet))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.invalidateDefaultBanner(ctx, actor.TenantID, featureDefault.FeatureID)
+
+	return nil
+}
+
+func (s *Service) GetDefaultBanner(ctx context.Context, tenantID int64, featureID int64) (*models.FeatureDefault, error) {
+	const op = "service.GetDefaultBanner"
+
+	bannerID, err := s.bannerStorage.GetDefaultBannerIDStorage(ctx, tenantID, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &models.FeatureDefault{FeatureID: featureID, BannerID: bannerID}, nil
+}
+
+func (s *Service) DeleteDefaultBanner(ctx context.Context, actor *models.Identity, featureID int64) error {
+	const op = "service.DeleteDefaultBanner"
+
+	err := checkFeatures(actor, featureID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.bannerStorage.DeleteDefaultBannerStorage(ctx, actor.TenantID, featureID, newAuditEntry(actor, models.AuditFeatureDefaultDelete))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.invalidateDefaultBanner(ctx, actor.TenantID, featureID)
+
+	return nil
+}
+
+// defaultBannerCache returns the default banner of the feature, from the
+// cache if it is there. Defaults are cached once per feature rather than
+// under every tag they are served for, so that a change of the default
+// only has one key to drop.
+func (s *Service) defaultBannerCache(ctx context.Context, tenantID int64, featureID int) (*models.Banner, error) {
+	const op = "service.defaultBannerCache"
+
+	key := defaultBannerKey(tenantID, int64(featureID))
+
+	banner, err := s.c.Get(ctx, key)
+	if err == nil {
+		// The banner may have been cached before its schedule ended.
+		if !banner.LiveAt(time.Now()) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+		}
+
+		return banner, nil
+	}
+	if !errors.Is(err, storage.ErrNotFoundInCache) {
+		s.log.Error("failed to get default banner from cache", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	banner, err = s.bannerStorage.GetDefaultBannerStorage(ctx, tenantID, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.c.Set(ctx, key, *banner)
+	if err != nil {
+		s.log.Error("failed to set default banner in cache", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return banner, nil
+}
+
+func (s *Service) invalidateDefaultBanner(ctx context.Context, tenantID int64, featureID int64) {
+	err := s.c.Del(ctx, defaultBannerKey(tenantID, featureID))
+	if err != nil {
+		s.log.Error("failed to invalidate default banner", sl.Err(err))
+	}
+}
+
+func defaultBannerKey(tenantID int64, featureID int64) string {
+	return fmt.Sprintf("banner_default:%d:%d", tenantID, featureID)
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"banners/lib/logger/sl"
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrLoginLocked        = errors.New("too many failed login attempts")
+)
+
+// LoginLockedError is returned while an email or an IP is locked out.
+// It matches ErrLoginLocked.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrLoginLocked, e.RetryAfter)
+}
+
+func (e *LoginLockedError) Is(target error) bool {
+	return target == ErrLoginLocked
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// compareDummyHash spends as much time as checking a real password, so a
+// login with an unknown email cannot be told apart by its response time.
+func compareDummyHash(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	})
+
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+func loginEmailKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+func loginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLoginLock returns a LoginLockedError if the email or the IP is
+// locked out.
+func (s *Service) checkLoginLock(ctx context.Context, email, ip string) error {
+	var retryAfter time.Duration
+	for _, key := range []string{loginEmailKey(email), loginIPKey(ip)} {
+		ttl, err := s.c.LockedFor(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		retryAfter = max(retryAfter, ttl)
+	}
+
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// registerLoginFailure counts a failed login for the email and the IP and
+// locks out the ones that have reached their limit.
+func (s *Service) registerLoginFailure(ctx context.Context, email, ip string) {
+	limits := map[string]int{
+		loginEmailKey(email): s.throttle.MaxAttempts,
+		loginIPKey(ip):       s.throttle.MaxIPAttempts,
+	}
+
+	for key, limit := range limits {
+		failures, err := s.c.RegisterFailure(ctx, key, s.throttle.Window)
+		if err != nil {
+			s.log.Error("failed to register login failure", sl.Err(err))
+			continue
+		}
+
+		if failures < int64(limit) {
+			continue
+		}
+
+		s.log.Warn("login locked out", slog.String("key", key), slog.Int64("failures", failures))
+
+		if err := s.c.Lock(ctx, key, s.throttle.Lockout); err != nil {
+			s.log.Error("failed to lock login out", sl.Err(err))
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/lib/logger/sl"
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidRegistryID = errors.New("id must be positive")
+	ErrRegistryName      = errors.New("name must not be empty")
+	ErrUnregistered      = errors.New("banner refers to unregistered or archived ids")
+	ErrRegistryParent    = errors.New("only tags have parents")
+	ErrTagCycle          = errors.New("tag cannot be nested in itself or its descendants")
+)
+
+// UnregisteredError lists the IDs of a registry a banner refers to that are
+// not registered or are archived. It matches ErrUnregistered.
+type UnregisteredError struct {
+	Registry string
+	IDs      []int64
+}
+
+func (e *UnregisteredError) Error() string {
+	return fmt.Sprintf("unregistered or archived %s: %v", e.Registry, e.IDs)
+}
+
+func (e *UnregisteredError) Is(target error) bool {
+	return target == ErrUnregistered
+}
+
+type RegistryStorage interface {
+	CreateRegistryEntryStorage(ctx context.Context, tenantID int64, registry string, entry *models.RegistryEntry, audit *models.AuditEntry) (*models.RegistryEntry, error)
+	GetRegistryEntryStorage(ctx context.Context, tenantID int64, registry string, id int64) (*models.RegistryEntry, error)
+	ListRegistryEntriesStorage(ctx context.Context, tenantID int64, registry string, includeArchived bool, limit int, offset int) ([]models.RegistryEntry, error)
+	UpdateRegistryEntryStorage(ctx context.Context, tenantID int64, registry string, id int64, update *models.RegistryUpdate, audit *models.AuditEntry) (*models.RegistryEntry, error)
+	DeleteRegistryEntryStorage(ctx context.Context, tenantID int64, registry string, id int64, audit *models.AuditEntry) error
+	UnregisteredIDsStorage(ctx context.Context, tenantID int64, registry string, ids []int64) ([]int64, error)
+	RegistryNamesStorage(ctx context.Context, tenantID int64, registry string, ids []int64) (map[int64]string, error)
+	TagAncestorsStorage(ctx context.Context, tenantID int64, tagID int64) ([]int64, error)
+	TagDescendantsStorage(ctx context.Context, tenantID int64, tagIDs []int64) ([]int64, error)
+}
+
+func (s *Service) CreateRegistryEntry(ctx context.Context, actor *models.Identity, registry string, entry *models.RegistryEntry) (*models.RegistryEntry, error) {
+	const op = "service.CreateRegistryEntry"
+
+	if entry.ID <= 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidRegistryID)
+	}
+
+	if entry.Name == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrRegistryName)
+	}
+
+	if entry.ParentID != nil && *entry.ParentID == 0 {
+		entry.ParentID = nil
+	}
+
+	err := s.checkParent(ctx, actor.TenantID, registry, entry.ID, entry.ParentID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	created, err := s.registryStorage.CreateRegistryEntryStorage(ctx, actor.TenantID, registry, entry, newAuditEntry(actor, models.AuditRegistryCreate))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+func (s *Service) GetRegistryEntry(ctx context.Context, tenantID int64, registry string, id int64) (*models.RegistryEntry, error) {
+	const op = "service.GetRegistryEntry"
+
+	entry, err := s.registryStorage.GetRegistryEntryStorage(ctx, tenantID, registry, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entry, nil
+}
+
+func (s *Service) ListRegistryEntries(ctx context.Context, tenantID int64, registry string, includeArchived bool, limit int, offset int) ([]models.RegistryEntry, error) {
+	const op = "service.ListRegistryEntries"
+
+	entries, err := s.registryStorage.ListRegistryEntriesStorage(ctx, tenantID, registry, includeArchived, limit, offset)
+	if err != nil {
+		s.log.Error("failed to list registry entries", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+func (s *Service) UpdateRegistryEntry(ctx context.Context, actor *models.Identity, registry string, id int64, update *models.RegistryUpdate) (*models.RegistryEntry, error) {
+	const op = "service.UpdateRegistryEntry"
+
+	if update.Name == nil && update.Description == nil && update.OwnerTeam == nil && update.Archived == nil && update.ParentID == nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrNothingToUpdate)
+	}
+
+	if update.Name != nil && *update.Name == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrRegistryName)
+	}
+
+	if update.ParentID != nil && registry != models.RegistryTags {
+		return nil, fmt.Errorf("%s: %w", op, ErrRegistryParent)
+	}
+
+	if update.ParentID != nil && *update.ParentID != 0 {
+		err := s.checkParent(ctx, actor.TenantID, registry, id, update.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// Cached user banners of the tags below a moved tag are not invalidated
+	// and expire on their own.
+	entry, err := s.registryStorage.UpdateRegistryEntryStorage(ctx, actor.TenantID, registry, id, update, newAuditEntry(actor, models.AuditRegistryUpdate))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entry, nil
+}
+
+func (s *Service) DeleteRegistryEntry(ctx context.Context, actor *models.Identity, registry string, id int64) error {
+	const op = "service.DeleteRegistryEntry"
+
+	err := s.registryStorage.DeleteRegistryEntryStorage(ctx, actor.TenantID, registry, id, newAuditEntry(actor, models.AuditRegistryDelete))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// checkParent makes sure an entry can be nested in parentID: only tags have
+// parents, and a tag cannot end up among its own ancestors.
+func (s *Service) checkParent(ctx context.Context, tenantID int64, registry string, id int64, parentID *int64) error {
+	const op = "service.checkParent"
+
+	if parentID == nil {
+		return nil
+	}
+
+	if registry != models.RegistryTags {
+		return fmt.Errorf("%s: %w", op, ErrRegistryParent)
+	}
+
+	ancestors, err := s.registryStorage.TagAncestorsStorage(ctx, tenantID, *parentID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, ancestor := range ancestors {
+		if ancestor == id {
+			return fmt.Errorf("%s: %w", op, ErrTagCycle)
+		}
+	}
+
+	return nil
+}
+
+// checkRegistries makes sure the feature and tags of a new revision are
+// registered and not archived.
+func (s *Service) checkRegistries(ctx context.Context, tenantID int64, banner *models.Banner) error {
+	const op = "service.checkRegistries"
+
+	refs := map[string][]int64{
+		models.RegistryFeatures: {banner.FeatureID},
+		models.RegistryTags:     banner.TagIDs,
+	}
+
+	for _, registry := range []string{models.RegistryFeatures, models.RegistryTags} {
+		ids, err := s.registryStorage.UnregisteredIDsStorage(ctx, tenantID, registry, refs[registry])
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if len(ids) != 0 {
+			return fmt.Errorf("%s: %w", op, &UnregisteredError{Registry: registry, IDs: ids})
+		}
+	}
+
+	return nil
+}
+
+// nameBanners fills in the registered names of the features and tags of
+// the banners. IDs that are not registered are left without a name.
+func (s *Service) nameBanners(ctx context.Context, tenantID int64, banners []models.Banner) error {
+	const op = "service.nameBanners"
+
+	var featureIDs, tagIDs []int64
+	for _, banner := range banners {
+		featureIDs = append(featureIDs, banner.FeatureID)
+		tagIDs = append(tagIDs, banner.TagIDs...)
+	}
+
+	featureNames, err := s.registryStorage.RegistryNamesStorage(ctx, tenantID, models.RegistryFeatures, featureIDs)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tagNames, err := s.registryStorage.RegistryNamesStorage(ctx, tenantID, models.RegistryTags, tagIDs)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range banners {
+		banners[i].FeatureName = featureNames[banners[i].FeatureID]
+
+		banners[i].TagNames = map[int64]string{}
+		for _, tagID := range banners[i].TagIDs {
+			if name, ok := tagNames[tagID]; ok {
+				banners[i].TagNames[tagID] = name
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/lib/logger/sl"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+func (s *Service) retentionPolicy() models.RetentionPolicy {
+	return models.RetentionPolicy{
+		KeepLast: s.retention.KeepLast,
+		KeepFor:  s.retention.KeepFor,
+	}
+}
+
+// PreviewPrune lists the revisions of the tenant that the next pruning
+// would delete.
+func (s *Service) PreviewPrune(ctx context.Context, tenantID int64, limit int, offset int) (*models.PrunePreview, error) {
+	const op = "service.PreviewPrune"
+
+	policy := s.retentionPolicy()
+
+	preview := &models.PrunePreview{
+		KeepLast:  policy.KeepLast,
+		KeepFor:   policy.KeepFor.String(),
+		Revisions: []models.PrunableRevision{},
+	}
+
+	if !policy.Enabled() {
+		return preview, nil
+	}
+
+	revisions, err := s.bannerStorage.ListPrunableRevisionsStorage(ctx, tenantID, policy, limit, offset)
+	if err != nil {
+		s.log.Error("failed to list prunable revisions", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	preview.Revisions = revisions
+
+	return preview, nil
+}
+
+// PruneRevisions deletes the revisions of all tenants that the retention
+// policy does not keep.
+func (s *Service) PruneRevisions(ctx context.Context) (int64, error) {
+	const op = "service.PruneRevisions"
+
+	deleted, err := s.bannerStorage.PruneRevisionsStorage(ctx, s.retentionPolicy())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deleted, nil
+}
+
+// RunRetention prunes revisions every retention interval until ctx is done.
+func (s *Service) RunRetention(ctx context.Context) {
+	const op = "service.RunRetention"
+
+	log := s.log.With(slog.String("op", op))
+
+	if !s.retentionPolicy().Enabled() || s.retention.Interval <= 0 {
+		log.Info("revision retention is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.retention.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.PruneRevisions(ctx)
+			if err != nil {
+				log.Error("failed to prune revisions", sl.Err(err))
+				continue
+			}
+
+			if deleted > 0 {
+				log.Info("pruned revisions", slog.Int64("deleted", deleted))
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"banners/lib/logger/sl"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrRoleNotGrantable = errors.New("role has permissions the actor does not have")
+
+// rolesCacheTTL bounds how long a permission change made directly in the
+// database takes to apply. Changes made through the API apply at once.
+const rolesCacheTTL = time.Minute
+
+type RoleStorage interface {
+	ListRolesStorage(ctx context.Context) ([]models.Role, error)
+	SetRolePermissionsStorage(ctx context.Context, role string, permissions []string, entry *models.AuditEntry) error
+	DeleteRoleStorage(ctx context.Context, role string, entry *models.AuditEntry) error
+	UnknownPermissionsStorage(ctx context.Context, permissions []string) ([]string, error)
+}
+
+// roleCache keeps the role to permissions mapping in memory, since it is
+// consulted on every authenticated request.
+type roleCache struct {
+	mu       sync.RWMutex
+	roles    map[string]map[string]struct{}
+	loadedAt time.Time
+}
+
+// HasPermission checks a user against the permissions of their role and an
+// API key against its own scopes.
+func (s *Service) HasPermission(ctx context.Context, identity *models.Identity, permission string) (bool, error) {
+	const op = "service.HasPermission"
+
+	if identity.IsAPIKey() {
+		for _, scope := range identity.Scopes {
+			if scope == permission {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
+
+	roles, err := s.rolePermissions(ctx)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, ok := roles[identity.Role][permission]
+
+	return ok, nil
+}
+
+func (s *Service) ListRoles(ctx context.Context) ([]models.Role, error) {
+	const op = "service.ListRoles"
+
+	roles, err := s.roleStorage.ListRolesStorage(ctx)
+	if err != nil {
+		s.log.Error("failed to list roles", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+func (s *Service) SetRolePermissions(ctx context.Context, actor *models.Identity, role string, permissions []string) error {
+	const op = "service.SetRolePermissions"
+
+	err := s.roleStorage.SetRolePermissionsStorage(ctx, role, permissions, newAuditEntry(actor, models.AuditRoleSetPermissions))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.invalidateRoles()
+
+	return nil
+}
+
+func (s *Service) DeleteRole(ctx context.Context, actor *models.Identity, role string) error {
+	const op = "service.DeleteRole"
+
+	err := s.roleStorage.DeleteRoleStorage(ctx, role, newAuditEntry(actor, models.AuditRoleDelete))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.invalidateRoles()
+
+	return nil
+}
+
+func (s *Service) rolePermissions(ctx context.Context) (map[string]map[string]struct{}, error) {
+	s.roles.mu.RLock()
+	roles, loadedAt := s.roles.roles, s.roles.loadedAt
+	s.roles.mu.RUnlock()
+
+	if roles != nil && time.Since(loadedAt) < rolesCacheTTL {
+		return roles, nil
+	}
+
+	list, err := s.roleStorage.ListRolesStorage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles = make(map[string]map[string]struct{}, len(list))
+	for _, role := range list {
+		permissions := make(map[string]struct{}, len(role.Permissions))
+		for _, permission := range role.Permissions {
+			permissions[permission] = struct{}{}
+		}
+		roles[role.Name] = permissions
+	}
+
+	s.roles.mu.Lock()
+	s.roles.roles = roles
+	s.roles.loadedAt = time.Now()
+	s.roles.mu.Unlock()
+
+	return roles, nil
+}
+
+// checkGrantable makes sure the actor has every permission of the role, so
+// nobody can hand out more than they have.
+func (s *Service) checkGrantable(ctx context.Context, actor *models.Identity, role string) error {
+	roles, err := s.rolePermissions(ctx)
+	if err != nil {
+		return err
+	}
+
+	permissions, ok := roles[role]
+	if !ok {
+		return storage.ErrRoleNotFound
+	}
+
+	for permission := range permissions {
+		allowed, err := s.HasPermission(ctx, actor, permission)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %s", ErrRoleNotGrantable, permission)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) invalidateRoles() {
+	s.roles.mu.Lock()
+	s.roles.roles = nil
+	s.roles.mu.Unlock()
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/lib/bucket"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidPercent = errors.New("percent must be between 0 and 100")
+
+// StartRollout starts serving a revision of the banner to a percentage of
+// its users, alongside the live revision for the others.
+func (s *Service) StartRollout(ctx context.Context, actor *models.Identity, rollout *models.Rollout) (*models.Rollout, error) {
+	const op = "service.StartRollout"
+
+	if rollout.Percent < 0 || rollout.Percent > 100 {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidPercent)
+	}
+
+	err := s.checkBannerAccess(ctx, actor, int(rollout.BannerID))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rollout.CreatedBy = actor.AuthorID()
+
+	created, err := s.bannerStorage.StartRolloutStorage(ctx, actor.TenantID, rollout, newAuditEntry(actor, models.AuditRolloutStart))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.invalidateUserBanners(ctx, actor.TenantID, s.bannerSnapshot(ctx, actor.TenantID, int(created.BannerID)))
+
+	return created, nil
+}
+
+func (s *Service) GetRollout(ctx context.Context, identity *models.Identity, bannerID int) (*models.Rollout, error) {
+	const op = "service.GetRollout"
+
+	err := s.checkBannerAccess(ctx, identity, bannerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rollout, err := s.bannerStorage.GetRolloutStorage(ctx, identity.TenantID, bannerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rollout, nil
+}
+
+// SetRolloutPercent ramps the rollout of the banner up or down. Users keep
+// their bucket, so raising the percentage only adds users to the rollout.
+func (s *Service) SetRolloutPercent(ctx context.Context, actor *models.Identity, bannerID int, percent int) (*models.Rollout, error) {
+	const op = "service.SetRolloutPercent"
+
+	if percent < 0 || percent > 100 {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidPercent)
+	}
+
+	err := s.checkBannerAccess(ctx, actor, bannerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	after, err := s.bannerStorage.SetRolloutPercentStorage(ctx, actor.TenantID, bannerID, percent, newAuditEntry(actor, models.AuditRolloutUpdate))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.invalidateUserBanners(ctx, actor.TenantID, s.bannerSnapshot(ctx, actor.TenantID, bannerID))
+
+	return after, nil
+}
+
+// FinalizeRollout makes the revision being rolled out live for all users,
+// which ends the rollout, and returns its ID.
+func (s *Service) FinalizeRollout(ctx context.Context, actor *models.Identity, bannerID int) (int, error) {
+	const op = "service.FinalizeRollout"
+
+	err := s.checkBannerAccess(ctx, actor, bannerID)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rollout, err := s.bannerStorage.GetRolloutStorage(ctx, actor.TenantID, bannerID)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	before := s.bannerSnapshot(ctx, actor.TenantID, bannerID)
+
+	err = s.bannerStorage.ChooseRevisionStorage(ctx, actor.TenantID, bannerID, int(rollout.RevisionID), actor.AuthorID(), newAuditEntry(actor, models.AuditRolloutFinalize))
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.invalidateUserBanners(ctx, actor.TenantID, before, s.bannerSnapshot(ctx, actor.TenantID, bannerID))
+
+	return int(rollout.RevisionID), nil
+}
+
+// AbortRollout ends the rollout of the banner, the users it reached get the
+// live revision again.
+func (s *Service) AbortRollout(ctx context.Context, actor *models.Identity, bannerID int) error {
+	const op = "service.AbortRollout"
+
+	err := s.checkBannerAccess(ctx, actor, bannerID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.bannerStorage.DeleteRolloutStorage(ctx, actor.TenantID, bannerID, newAuditEntry(actor, models.AuditRolloutAbort))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.invalidateUserBanners(ctx, actor.TenantID, s.bannerSnapshot(ctx, actor.TenantID, bannerID))
+
+	return nil
+}
+
+// pickRevision returns the revision of the user banner the audience gets:
+// the one being rolled out if the user key falls within the percentage of
+// the rollout and the revision targets the audience, the banner otherwise.
+// Keys are bucketed per rollout, and users without a key stay on the live
+// revision.
+func pickRevision(banner *models.Banner, audience *models.Audience, now time.Time) *models.Banner {
+	rollout := banner.Rollout
+	if rollout == nil || rollout.Candidate == nil || audience.UserKey == "" {
+		return banner
+	}
+
+	seed := fmt.Sprintf("rollout:%d:%d", rollout.BannerID, rollout.RevisionID)
+	if bucket.Of(seed, audience.UserKey, 100) >= uint64(rollout.Percent) {
+		return banner
+	}
+
+	// A candidate outside of its schedule or its targeting is not served,
+	// rather than leaving the users it would reach without a banner.
+	if !rollout.Candidate.LiveAt(now) || !matchTargeting(rollout.Candidate.Targeting, audience) {
+		return banner
+	}
+
+	candidate := *rollout.Candidate
+	candidate.MatchedTagID = banner.MatchedTagID
+	candidate.Fallback = banner.Fallback
+	candidate.Canary = true
+
+	return &candidate
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"banners/domain/models"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func rolloutBanner(percent int, candidate *models.Banner) *models.Banner {
+	return &models.Banner{
+		BannerID:     1,
+		Revision:     1,
+		MatchedTagID: 7,
+		Rollout: &models.Rollout{
+			BannerID:   1,
+			RevisionID: 2,
+			Percent:    percent,
+			Candidate:  candidate,
+		},
+	}
+}
+
+func TestPickRevisionFollowsPercent(t *testing.T) {
+	now := time.Now()
+
+	for _, percent := range []int{0, 10, 50, 100} {
+		banner := rolloutBanner(percent, &models.Banner{BannerID: 1, Revision: 2})
+
+		const keys = 10000
+
+		canaries := 0
+		for i := 0; i < keys; i++ {
+			picked := pickRevision(banner, &models.Audience{UserKey: "user-" + strconv.Itoa(i)}, now)
+			if picked.Canary {
+				canaries++
+				if picked.Revision != 2 || picked.MatchedTagID != 7 {
+					t.Fatalf("canary = %+v, want revision 2 matched by tag 7", picked)
+				}
+			} else if picked.Revision != 1 {
+				t.Fatalf("revision = %d, want the live revision 1", picked.Revision)
+			}
+		}
+
+		want := keys * percent / 100
+		if canaries < want-keys/50 || canaries > want+keys/50 {
+			t.Errorf("%d%% rollout reached %d of %d users, want about %d", percent, canaries, keys, want)
+		}
+	}
+}
+
+func TestPickRevisionIsStickyAsPercentGrows(t *testing.T) {
+	now := time.Now()
+	candidate := &models.Banner{BannerID: 1, Revision: 2}
+
+	for i := 0; i < 1000; i++ {
+		audience := &models.Audience{UserKey: "user-" + strconv.Itoa(i)}
+
+		reached := false
+		for _, percent := range []int{5, 20, 50, 80, 100} {
+			canary := pickRevision(rolloutBanner(percent, candidate), audience, now).Canary
+			if reached && !canary {
+				t.Fatalf("user %q left the rollout when it grew to %d%%", audience.UserKey, percent)
+			}
+			reached = canary
+		}
+
+		if !reached {
+			t.Fatalf("user %q is not reached at 100%%", audience.UserKey)
+		}
+	}
+}
+
+func TestPickRevisionKeepsLiveRevision(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		banner   *models.Banner
+		audience *models.Audience
+	}{
+		{
+			name:     "no rollout",
+			banner:   &models.Banner{BannerID: 1, Revision: 1},
+			audience: &models.Audience{UserKey: "user"},
+		},
+		{
+			name:     "no user key",
+			banner:   rolloutBanner(100, &models.Banner{BannerID: 1, Revision: 2}),
+			audience: &models.Audience{},
+		},
+		{
+			name:     "candidate not live yet",
+			banner:   rolloutBanner(100, &models.Banner{BannerID: 1, Revision: 2, StartsAt: &later}),
+			audience: &models.Audience{UserKey: "user"},
+		},
+		{
+			name: "candidate does not target the audience",
+			banner: rolloutBanner(100, &models.Banner{
+				BannerID:  1,
+				Revision:  2,
+				Targeting: &models.Targeting{Platforms: []string{"ios"}},
+			}),
+			audience: &models.Audience{UserKey: "user", Platform: "android"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if picked := pickRevision(tt.banner, tt.audience, now); picked != tt.banner {
+				t.Errorf("pickRevision() = %+v, want the live revision", picked)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"banners/lib/jsonschema"
+	"banners/lib/logger/sl"
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidSchema  = errors.New("schema is not a valid JSON Schema")
+	ErrInvalidContent = errors.New("content does not match the feature schema")
+)
+
+// ContentError lists the parts of banner content that do not match the
+// schema of its feature. It matches ErrInvalidContent.
+type ContentError struct {
+	SchemaVersion int
+	Violations    []jsonschema.Violation
+}
+
+func (e *ContentError) Error() string {
+	return fmt.Sprintf("content does not match version %d of the feature schema", e.SchemaVersion)
+}
+
+func (e *ContentError) Is(target error) bool {
+	return target == ErrInvalidContent
+}
+
+type SchemaStorage interface {
+	CreateFeatureSchemaStorage(ctx context.Context, tenantID int64, schema *models.FeatureSchema, entry *models.AuditEntry) (*models.FeatureSchema, error)
+	LatestFeatureSchemaStorage(ctx context.Context, tenantID int64, featureID int64) (*models.FeatureSchema, error)
+	ListFeatureSchemasStorage(ctx context.Context, tenantID int64, featureID int64) ([]models.FeatureSchema, error)
+}
+
+// CreateFeatureSchema registers the next version of the schema of the
+// feature. Revisions written from then on must match it.
+func (s *Service) CreateFeatureSchema(ctx context.Context, actor *models.Identity, schema *models.FeatureSchema) (*models.FeatureSchema, error) {
+	const op = "service.CreateFeatureSchema"
+
+	err := checkFeatures(actor, schema.FeatureID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = jsonschema.Compile(schema.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", op, ErrInvalidSchema, err)
+	}
+
+	schema.CreatedBy = actor.AuthorID()
+
+	created, err := s.schemaStorage.CreateFeatureSchemaStorage(ctx, actor.TenantID, schema, newAuditEntry(actor, models.AuditFeatureSchemaCreate))
+	if err != nil {
+		s.log.Error("failed to create feature schema", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+func (s *Service) ListFeatureSchemas(ctx context.Context, tenantID int64, featureID int64) ([]models.FeatureSchema, error) {
+	const op = "service.ListFeatureSchemas"
+
+	schemas, err := s.schemaStorage.ListFeatureSchemasStorage(ctx, tenantID, featureID)
+	if err != nil {
+		s.log.Error("failed to list feature schemas", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return schemas, nil
+}
+
+// checkContent validates the content of a new revision against the newest
+// schema of its feature and records the version on the banner. Features
+// without a schema take any content.
+func (s *Service) checkContent(ctx context.Context, tenantID int64, banner *models.Banner) error {
+	const op = "service.checkContent"
+
+	banner.SchemaVersion = nil
+
+	schema, err := s.schemaStorage.LatestFeatureSchemaStorage(ctx, tenantID, banner.FeatureID)
+	if errors.Is(err, storage.ErrSchemaNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	compiled, err := jsonschema.Compile(schema.Schema)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	violations, err := compiled.Validate(banner.Content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if len(violations) != 0 {
+		return fmt.Errorf("%s: %w", op, &ContentError{SchemaVersion: schema.Version, Violations: violations})
+	}
+
+	banner.SchemaVersion = &schema.Version
+
+	return nil
+}
@@ -1,17 +1,74 @@
 package service
 
 import (
+	"banners/internal/config"
 	"banners/internal/storage/redisC"
+	"fmt"
 	"log/slog"
 )
 
 type Service struct {
-	log           *slog.Logger
-	bannerStorage BannerStorage
-	userStorage   UserStorage
-	c             *redisC.Cache
+	log               *slog.Logger
+	keys              *keyring
+	signupEnabled     bool
+	throttle          config.LoginThrottle
+	retention         config.Retention
+	bannerStorage     BannerStorage
+	userStorage       UserStorage
+	tokenStorage      TokenStorage
+	roleStorage       RoleStorage
+	apiKeyStorage     APIKeyStorage
+	auditStorage      AuditStorage
+	tenantStorage     TenantStorage
+	approvalStorage   ApprovalStorage
+	schemaStorage     SchemaStorage
+	registryStorage   RegistryStorage
+	experimentStorage ExperimentStorage
+	roles             roleCache
+	c                 *redisC.Cache
 }
 
-func New(log *slog.Logger, bannerStorage BannerStorage, userStorage UserStorage, c *redisC.Cache) (*Service, error) {
-	return &Service{log: log, bannerStorage: bannerStorage, userStorage: userStorage, c: c}, nil
+// Storages are the storages the service works with. A single database
+// usually implements all of them.
+type Storages struct {
+	Banner     BannerStorage
+	User       UserStorage
+	Token      TokenStorage
+	Role       RoleStorage
+	APIKey     APIKeyStorage
+	Audit      AuditStorage
+	Tenant     TenantStorage
+	Approval   ApprovalStorage
+	Schema     SchemaStorage
+	Registry   RegistryStorage
+	Experiment ExperimentStorage
+}
+
+func New(log *slog.Logger, authCfg config.Auth, retentionCfg config.Retention, storages Storages, c *redisC.Cache) (*Service, error) {
+	const op = "service.New"
+
+	keys, err := newKeyring(authCfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Service{
+		log:               log,
+		keys:              keys,
+		signupEnabled:     authCfg.SignupEnabled,
+		throttle:          authCfg.LoginThrottle,
+		retention:         retentionCfg,
+		bannerStorage:     storages.Banner,
+		userStorage:       storages.User,
+		tokenStorage:      storages.Token,
+		roleStorage:       storages.Role,
+		apiKeyStorage:     storages.APIKey,
+		auditStorage:      storages.Audit,
+		tenantStorage:     storages.Tenant,
+		approvalStorage:   storages.Approval,
+		schemaStorage:     storages.Schema,
+		registryStorage:   storages.Registry,
+		experimentStorage: storages.Experiment,
+		c:                 c,
+	}, nil
 }
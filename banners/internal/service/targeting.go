@@ -0,0 +1,224 @@
+package service
+
+import (
+	"banners/domain/models"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidTargeting  = errors.New("invalid targeting")
+	ErrInvalidAppVersion = errors.New("app version must be dot separated numbers")
+)
+
+// TargetingError tells which rule of a targeting is invalid. It matches
+// ErrInvalidTargeting.
+type TargetingError struct {
+	Reason string
+}
+
+func (e *TargetingError) Error() string {
+	return fmt.Sprintf("invalid targeting: %s", e.Reason)
+}
+
+func (e *TargetingError) Is(target error) bool {
+	return target == ErrInvalidTargeting
+}
+
+// checkTargeting validates the targeting of a revision and returns it in
+// the form it is matched in, nil if it has no rules.
+func checkTargeting(targeting *models.Targeting) (*models.Targeting, error) {
+	if targeting == nil || targeting.IsEmpty() {
+		return nil, nil
+	}
+
+	checked := models.Targeting{
+		MinAppVersion: targeting.MinAppVersion,
+		MaxAppVersion: targeting.MaxAppVersion,
+	}
+
+	for _, platform := range targeting.Platforms {
+		if platform == "" {
+			return nil, &TargetingError{Reason: "platforms must not be empty"}
+		}
+		checked.Platforms = append(checked.Platforms, strings.ToLower(platform))
+	}
+
+	var minVersion, maxVersion []int
+	var err error
+	if targeting.MinAppVersion != "" {
+		minVersion, err = parseVersion(targeting.MinAppVersion)
+		if err != nil {
+			return nil, &TargetingError{Reason: fmt.Sprintf("min_app_version %q is not dot separated numbers", targeting.MinAppVersion)}
+		}
+	}
+
+	if targeting.MaxAppVersion != "" {
+		maxVersion, err = parseVersion(targeting.MaxAppVersion)
+		if err != nil {
+			return nil, &TargetingError{Reason: fmt.Sprintf("max_app_version %q is not dot separated numbers", targeting.MaxAppVersion)}
+		}
+	}
+
+	if minVersion != nil && maxVersion != nil && compareVersions(minVersion, maxVersion) > 0 {
+		return nil, &TargetingError{Reason: "min_app_version is above max_app_version"}
+	}
+
+	for _, locale := range targeting.Locales {
+		if locale == "" {
+			return nil, &TargetingError{Reason: "locales must not be empty"}
+		}
+		checked.Locales = append(checked.Locales, normalizeLocale(locale))
+	}
+
+	for name, values := range targeting.Attributes {
+		if name == "" || len(values) == 0 {
+			return nil, &TargetingError{Reason: "attributes need a name and at least one value"}
+		}
+		if checked.Attributes == nil {
+			checked.Attributes = make(map[string][]string, len(targeting.Attributes))
+		}
+		checked.Attributes[name] = values
+	}
+
+	return &checked, nil
+}
+
+// checkAudience rejects an app version that no targeting could match,
+// rather than serving the banners of users without one.
+func checkAudience(audience *models.Audience) error {
+	if audience.AppVersion == "" {
+		return nil
+	}
+
+	_, err := parseVersion(audience.AppVersion)
+	if err != nil {
+		return ErrInvalidAppVersion
+	}
+
+	return nil
+}
+
+// matchTargeting reports whether the audience matches every rule of the
+// targeting. Users that do not tell the platform, app version, locale or an
+// attribute a rule is about do not match it.
+func matchTargeting(targeting *models.Targeting, audience *models.Audience) bool {
+	if targeting == nil {
+		return true
+	}
+
+	if len(targeting.Platforms) > 0 && !slices.Contains(targeting.Platforms, strings.ToLower(audience.Platform)) {
+		return false
+	}
+
+	if targeting.MinAppVersion != "" || targeting.MaxAppVersion != "" {
+		version, err := parseVersion(audience.AppVersion)
+		if err != nil {
+			return false
+		}
+
+		if targeting.MinAppVersion != "" {
+			minVersion, err := parseVersion(targeting.MinAppVersion)
+			if err != nil || compareVersions(version, minVersion) < 0 {
+				return false
+			}
+		}
+
+		if targeting.MaxAppVersion != "" {
+			maxVersion, err := parseVersion(targeting.MaxAppVersion)
+			if err != nil || compareVersions(version, maxVersion) > 0 {
+				return false
+			}
+		}
+	}
+
+	if len(targeting.Locales) > 0 && !matchLocale(targeting.Locales, audience.Locale) {
+		return false
+	}
+
+	for name, values := range targeting.Attributes {
+		value, ok := audience.Attributes[name]
+		if !ok || !slices.Contains(values, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pickBanner returns the first of the candidates, in order of preference,
+// that is live and targets the audience, nil if none does.
+func pickBanner(candidates []models.Banner, audience *models.Audience, now time.Time) *models.Banner {
+	for i := range candidates {
+		if candidates[i].LiveAt(now) && matchTargeting(candidates[i].Targeting, audience) {
+			banner := candidates[i]
+			return &banner
+		}
+	}
+
+	return nil
+}
+
+func matchLocale(locales []string, locale string) bool {
+	if locale == "" {
+		return false
+	}
+
+	locale = normalizeLocale(locale)
+	for _, l := range locales {
+		if l == locale || strings.HasPrefix(locale, l+"-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ReplaceAll(strings.ToLower(locale), "_", "-")
+}
+
+func parseVersion(version string) ([]int, error) {
+	if version == "" {
+		return nil, ErrInvalidAppVersion
+	}
+
+	parts := strings.Split(version, ".")
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || strings.HasPrefix(part, "+") {
+			return nil, ErrInvalidAppVersion
+		}
+		numbers[i] = n
+	}
+
+	return numbers, nil
+}
+
+// compareVersions compares dotted versions part by part, missing parts
+// counting as zero so that 2.0 and 2 are the same version.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"banners/domain/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"2", "2.0", 0},
+		{"2.0.0", "2", 0},
+		{"1.2", "1.10", -1},
+		{"1.10", "1.9", 1},
+		{"1.2.1", "1.2", 1},
+		{"0.9.9", "1", -1},
+	}
+
+	for _, tt := range tests {
+		a, err := parseVersion(tt.a)
+		if err != nil {
+			t.Fatalf("parseVersion(%q) error = %v", tt.a, err)
+		}
+		b, err := parseVersion(tt.b)
+		if err != nil {
+			t.Fatalf("parseVersion(%q) error = %v", tt.b, err)
+		}
+
+		if got := compareVersions(a, b); got != tt.want {
+			t.Errorf("compareVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseVersionRejects(t *testing.T) {
+	for _, version := range []string{"", "1.", ".1", "1..2", "v1.2", "1.-2", "1.+2", "1.2b"} {
+		if _, err := parseVersion(version); !errors.Is(err, ErrInvalidAppVersion) {
+			t.Errorf("parseVersion(%q) error = %v, want %v", version, err, ErrInvalidAppVersion)
+		}
+	}
+}
+
+func TestMatchTargeting(t *testing.T) {
+	targeting := &models.Targeting{
+		Platforms:     []string{"ios", "android"},
+		MinAppVersion: "2.1",
+		MaxAppVersion: "3",
+		Locales:       []string{"en", "pt-br"},
+		Attributes:    map[string][]string{"tier": {"gold", "silver"}},
+	}
+
+	matching := models.Audience{
+		Platform:   "iOS",
+		AppVersion: "2.10.1",
+		Locale:     "en_US",
+		Attributes: map[string]string{"tier": "gold"},
+	}
+
+	tests := []struct {
+		name   string
+		change func(a *models.Audience)
+		want   bool
+	}{
+		{name: "matching", change: func(a *models.Audience) {}, want: true},
+		{name: "min version is inclusive", change: func(a *models.Audience) { a.AppVersion = "2.1.0" }, want: true},
+		{name: "max version is inclusive", change: func(a *models.Audience) { a.AppVersion = "3.0" }, want: true},
+		{name: "exact locale", change: func(a *models.Audience) { a.Locale = "pt-BR" }, want: true},
+		{name: "other platform", change: func(a *models.Audience) { a.Platform = "web" }},
+		{name: "no platform", change: func(a *models.Audience) { a.Platform = "" }},
+		{name: "below min version", change: func(a *models.Audience) { a.AppVersion = "2.0.9" }},
+		{name: "above max version", change: func(a *models.Audience) { a.AppVersion = "3.0.1" }},
+		{name: "no version", change: func(a *models.Audience) { a.AppVersion = "" }},
+		{name: "other locale", change: func(a *models.Audience) { a.Locale = "pt-PT" }},
+		{name: "locale prefix is not a language", change: func(a *models.Audience) { a.Locale = "eng" }},
+		{name: "no locale", change: func(a *models.Audience) { a.Locale = "" }},
+		{name: "other attribute value", change: func(a *models.Audience) { a.Attributes = map[string]string{"tier": "bronze"} }},
+		{name: "no attribute", change: func(a *models.Audience) { a.Attributes = nil }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audience := matching
+			tt.change(&audience)
+
+			if got := matchTargeting(targeting, &audience); got != tt.want {
+				t.Errorf("matchTargeting() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTargetingWithoutRules(t *testing.T) {
+	if !matchTargeting(nil, &models.Audience{}) {
+		t.Error("matchTargeting() = false for a banner without targeting")
+	}
+}
+
+func TestCheckTargeting(t *testing.T) {
+	tests := []struct {
+		name      string
+		targeting *models.Targeting
+		wantErr   bool
+	}{
+		{name: "no rules", targeting: &models.Targeting{}},
+		{name: "valid", targeting: &models.Targeting{Platforms: []string{"IOS"}, MinAppVersion: "1.0", MaxAppVersion: "1"}},
+		{name: "empty platform", targeting: &models.Targeting{Platforms: []string{""}}, wantErr: true},
+		{name: "invalid version", targeting: &models.Targeting{MinAppVersion: "1.x"}, wantErr: true},
+		{name: "min above max", targeting: &models.Targeting{MinAppVersion: "2", MaxAppVersion: "1.9"}, wantErr: true},
+		{name: "attribute without values", targeting: &models.Targeting{Attributes: map[string][]string{"tier": {}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := checkTargeting(tt.targeting)
+			if tt.wantErr && !errors.Is(err, ErrInvalidTargeting) {
+				t.Errorf("checkTargeting() error = %v, want %v", err, ErrInvalidTargeting)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("checkTargeting() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestPickBanner(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	candidates := []models.Banner{
+		{BannerID: 1, StartsAt: &later},
+		{BannerID: 2, Targeting: &models.Targeting{Platforms: []string{"ios"}}},
+		{BannerID: 3},
+	}
+
+	if got := pickBanner(candidates, &models.Audience{Platform: "ios"}, now); got == nil || got.BannerID != 2 {
+		t.Errorf("pickBanner() = %+v, want banner 2", got)
+	}
+	if got := pickBanner(candidates, &models.Audience{Platform: "android"}, now); got == nil || got.BannerID != 3 {
+		t.Errorf("pickBanner() = %+v, want banner 3", got)
+	}
+	if got := pickBanner(candidates[:2], &models.Audience{}, now); got != nil {
+		t.Errorf("pickBanner() = %+v, want none", got)
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/lib/logger/sl"
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrTenantNameEmpty = errors.New("tenant name is empty")
+
+type TenantStorage interface {
+	CreateTenantStorage(ctx context.Context, name string, entry *models.AuditEntry) (*models.Tenant, error)
+	ListTenantsStorage(ctx context.Context) ([]models.Tenant, error)
+}
+
+func (s *Service) CreateTenant(ctx context.Context, actor *models.Identity, name string) (*models.Tenant, error) {
+	const op = "service.CreateTenant"
+
+	if name == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrTenantNameEmpty)
+	}
+
+	tenant, err := s.tenantStorage.CreateTenantStorage(ctx, name, newAuditEntry(actor, models.AuditTenantCreate))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tenant, nil
+}
+
+func (s *Service) ListTenants(ctx context.Context) ([]models.Tenant, error) {
+	const op = "service.ListTenants"
+
+	tenants, err := s.tenantStorage.ListTenantsStorage(ctx)
+	if err != nil {
+		s.log.Error("failed to list tenants", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tenants, nil
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/internal/config"
+	"banners/internal/storage"
+	"banners/lib/jwt/eddsa"
+	"banners/lib/logger/sl"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"log/slog"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+)
+
+var (
+	ErrNoSigningKeys    = errors.New("no signing keys configured")
+	ErrSigningKeyAbsent = errors.New("signing key is not among configured keys")
+	ErrDuplicateKeyID   = errors.New("key id is configured more than once")
+	ErrUnsupportedKey   = errors.New("unsupported private key type")
+	ErrMissingKeyID     = errors.New("token has no key id")
+	ErrUnknownKeyID     = errors.New("token is signed with an unknown key")
+	ErrTokenRevoked     = errors.New("token has been revoked")
+)
+
+type TokenStorage interface {
+	CreateRefreshTokenStorage(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error
+	ConsumeRefreshTokenStorage(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RevokeRefreshTokenStorage(ctx context.Context, userID int64, tokenHash string) error
+	RevokeUserRefreshTokensStorage(ctx context.Context, userID int64) error
+}
+
+// signingKey is a single key of the keyring. For HMAC keys private and
+// public hold the same secret.
+type signingKey struct {
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// keyring holds the keys used to sign and verify access tokens.
+type keyring struct {
+	signingKeyID string
+	keys         map[string]signingKey
+	jwks         models.JWKS
+	ttl          time.Duration
+	refreshTTL   time.Duration
+}
+
+func newKeyring(cfg config.Auth) (*keyring, error) {
+	const op = "service.newKeyring"
+
+	if len(cfg.Keys) == 0 && len(cfg.PrivateKeys) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoSigningKeys)
+	}
+
+	keys := make(map[string]signingKey, len(cfg.Keys)+len(cfg.PrivateKeys))
+	for kid, secret := range cfg.Keys {
+		keys[kid] = signingKey{
+			method:  jwt.SigningMethodHS256,
+			private: []byte(secret),
+			public:  []byte(secret),
+		}
+	}
+
+	for kid, path := range cfg.PrivateKeys {
+		if _, ok := keys[kid]; ok {
+			return nil, fmt.Errorf("%s: %w: %q", op, ErrDuplicateKeyID, kid)
+		}
+
+		key, err := loadPrivateKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %q: %w", op, kid, err)
+		}
+		keys[kid] = key
+	}
+
+	if _, ok := keys[cfg.SigningKeyID]; !ok {
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrSigningKeyAbsent, cfg.SigningKeyID)
+	}
+
+	return &keyring{
+		signingKeyID: cfg.SigningKeyID,
+		keys:         keys,
+		jwks:         buildJWKS(keys),
+		ttl:          cfg.TokenTTL,
+		refreshTTL:   cfg.RefreshTokenTTL,
+	}, nil
+}
+
+// loadPrivateKey reads a PEM encoded RSA or Ed25519 private key and picks
+// the signing method from the key type.
+func loadPrivateKey(path string) (signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return signingKey{}, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return signingKey{method: jwt.SigningMethodRS256, private: key, public: &key.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return signingKey{method: eddsa.SigningMethodEdDSA, private: key, public: key.Public()}, nil
+	default:
+		return signingKey{}, fmt.Errorf("%w: %T", ErrUnsupportedKey, parsed)
+	}
+}
+
+// buildJWKS collects the public parts of the asymmetric keys. HMAC secrets
+// are never published.
+func buildJWKS(keys map[string]signingKey) models.JWKS {
+	jwks := models.JWKS{Keys: []models.JWK{}}
+	for kid, key := range keys {
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, models.JWK{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, models.JWK{
+				Kty: "OKP",
+				Kid: kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+
+	return jwks
+}
+
+func (k *keyring) sign(claims *models.Token) (string, error) {
+	key := k.keys[k.signingKeyID]
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = k.signingKeyID
+
+	return token.SignedString(key.private)
+}
+
+// verificationKey looks the key up by the "kid" header and makes sure the
+// token is signed with the algorithm of that key, so a public key can never
+// be used as an HMAC secret.
+func (k *keyring) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, ErrMissingKeyID
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.public, nil
+}
+
+// ParseToken verifies the token signature and expiry and checks that the
+// token has not been revoked by a logout.
+func (s *Service) ParseToken(ctx context.Context, tokenString string) (*models.Token, error) {
+	const op = "service.ParseToken"
+
+	claims := &models.Token{StandardClaims: &jwt.StandardClaims{}}
+	_, err := jwt.ParseWithClaims(tokenString, claims, s.keys.verificationKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	revoked, err := s.c.IsRevoked(ctx, claims.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if revoked {
+		return nil, fmt.Errorf("%s: %w", op, ErrTokenRevoked)
+	}
+
+	if claims.UserID != 0 {
+		revokedAt, err := s.c.UserRevokedAt(ctx, claims.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if revokedAt != 0 && claims.IssuedAt <= revokedAt {
+			return nil, fmt.Errorf("%s: %w", op, ErrTokenRevoked)
+		}
+	}
+
+	return claims, nil
+}
+
+// JWKS returns the public keys other services can verify tokens with.
+func (s *Service) JWKS() models.JWKS {
+	return s.keys.jwks
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair. Refresh
+// tokens are single use: the presented one is revoked, and presenting an
+// already revoked token revokes every refresh token of its user, since it
+// means the token has leaked.
+func (s *Service) RefreshTokens(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	const op = "service.RefreshTokens"
+
+	stored, err := s.tokenStorage.ConsumeRefreshTokenStorage(ctx, hashToken(refreshToken))
+	if errors.Is(err, storage.ErrRefreshTokenRevoked) {
+		s.log.Warn("revoked refresh token reused", slog.Int64("user_id", stored.UserID))
+
+		if err := s.tokenStorage.RevokeUserRefreshTokensStorage(ctx, stored.UserID); err != nil {
+			s.log.Error("failed to revoke refresh tokens", sl.Err(err))
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := s.userStorage.GetUserByIDStorage(stored.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.Disabled {
+		return nil, fmt.Errorf("%s: %w", op, ErrUserDisabled)
+	}
+
+	tokens, err := s.issueTokens(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// Logout revokes the access token until it expires and, if given, the
+// refresh token issued together with it.
+func (s *Service) Logout(ctx context.Context, claims *models.Token, refreshToken string) error {
+	const op = "service.Logout"
+
+	ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+	if claims.Id != "" && ttl > 0 {
+		if err := s.c.Revoke(ctx, claims.Id, ttl); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if refreshToken != "" {
+		err := s.tokenStorage.RevokeRefreshTokenStorage(ctx, claims.UserID, hashToken(refreshToken))
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) issueTokens(ctx context.Context, user *models.User) (*models.TokenPair, error) {
+	const op = "service.issueTokens"
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	tk := &models.Token{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Role:     user.Role,
+		TenantID: user.TenantID,
+		StandardClaims: &jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(s.keys.ttl).Unix(),
+		},
+	}
+
+	accessToken, err := s.keys.sign(tk)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.tokenStorage.CreateRefreshTokenStorage(ctx, user.ID, hashToken(refreshToken), now.Add(s.keys.refreshTTL))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &models.TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is used to store refresh tokens. They are random and long, so
+// a fast hash is enough and lets us look them up by the hash.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
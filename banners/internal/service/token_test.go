@@ -0,0 +1,204 @@
+package service
+
+import (
+	"banners/domain/models"
+	"banners/internal/config"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeKey(t *testing.T, key interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func testKeyring(t *testing.T) *keyring {
+	t.Helper()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := newKeyring(config.Auth{
+		SigningKeyID: "ed-1",
+		Keys:         map[string]string{"hs-1": "secret"},
+		PrivateKeys: map[string]string{
+			"ed-1":  writeKey(t, edKey),
+			"rsa-1": writeKey(t, rsaKey),
+		},
+	})
+	if err != nil {
+		t.Fatalf("newKeyring() error = %v", err)
+	}
+
+	return keys
+}
+
+func parse(keys *keyring, token string) (*models.Token, error) {
+	claims := &models.Token{StandardClaims: &jwt.StandardClaims{}}
+	_, err := jwt.ParseWithClaims(token, claims, keys.verificationKey)
+
+	return claims, err
+}
+
+func TestKeyringSignsWithEdDSA(t *testing.T) {
+	keys := testKeyring(t)
+
+	signed, err := keys.sign(&models.Token{UserID: 1, StandardClaims: &jwt.StandardClaims{Id: "jti"}})
+	if err != nil {
+		t.Fatalf("sign() error = %v", err)
+	}
+
+	claims, err := parse(keys, signed)
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+	if claims.UserID != 1 || claims.Id != "jti" {
+		t.Errorf("claims = %+v, want user 1 with jti", claims)
+	}
+
+	token, _, err := new(jwt.Parser).ParseUnverified(signed, &jwt.StandardClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Header["alg"] != "EdDSA" || token.Header["kid"] != "ed-1" {
+		t.Errorf("header = %v, want EdDSA signed by ed-1", token.Header)
+	}
+}
+
+func TestKeyringVerifiesRotatedKeys(t *testing.T) {
+	keys := testKeyring(t)
+
+	for _, kid := range []string{"hs-1", "rsa-1"} {
+		rotated := *keys
+		rotated.signingKeyID = kid
+
+		signed, err := rotated.sign(&models.Token{UserID: 1, StandardClaims: &jwt.StandardClaims{}})
+		if err != nil {
+			t.Fatalf("sign() with %s error = %v", kid, err)
+		}
+
+		if _, err := parse(keys, signed); err != nil {
+			t.Errorf("parse() of a token signed with %s error = %v", kid, err)
+		}
+	}
+}
+
+func TestKeyringRejectsAlgorithmConfusion(t *testing.T) {
+	keys := testKeyring(t)
+
+	// An attacker who knows the public key signs an HMAC token with it under
+	// the kid of the asymmetric key.
+	public := keys.keys["ed-1"].public.(ed25519.PublicKey)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &models.Token{UserID: 1, StandardClaims: &jwt.StandardClaims{}})
+	token.Header["kid"] = "ed-1"
+	signed, err := token.SignedString([]byte(public))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parse(keys, signed); err == nil {
+		t.Error("parse() accepted an HMAC token under an EdDSA key")
+	}
+}
+
+func TestKeyringRejectsUnknownKeys(t *testing.T) {
+	keys := testKeyring(t)
+
+	tests := []struct {
+		name    string
+		kid     interface{}
+		wantErr error
+	}{
+		{name: "no kid", wantErr: ErrMissingKeyID},
+		{name: "unknown kid", kid: "gone", wantErr: ErrUnknownKeyID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, &models.Token{StandardClaims: &jwt.StandardClaims{}})
+			if tt.kid != nil {
+				token.Header["kid"] = tt.kid
+			}
+			signed, err := token.SignedString([]byte("secret"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = parse(keys, signed)
+			var validationErr *jwt.ValidationError
+			if !errors.As(err, &validationErr) || !errors.Is(validationErr.Inner, tt.wantErr) {
+				t.Errorf("parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyringPublishesOnlyPublicKeys(t *testing.T) {
+	keys := testKeyring(t)
+
+	if len(keys.jwks.Keys) != 2 {
+		t.Fatalf("jwks has %d keys, want the 2 asymmetric ones", len(keys.jwks.Keys))
+	}
+
+	edJWK, rsaJWK := keys.jwks.Keys[0], keys.jwks.Keys[1]
+	if edJWK.Kid != "ed-1" || edJWK.Kty != "OKP" || edJWK.Crv != "Ed25519" || edJWK.Alg != "EdDSA" || edJWK.X == "" {
+		t.Errorf("ed25519 jwk = %+v", edJWK)
+	}
+	if rsaJWK.Kid != "rsa-1" || rsaJWK.Kty != "RSA" || rsaJWK.Alg != "RS256" || rsaJWK.N == "" || rsaJWK.E == "" {
+		t.Errorf("rsa jwk = %+v", rsaJWK)
+	}
+}
+
+func TestNewKeyringErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Auth
+		wantErr error
+	}{
+		{
+			name:    "no keys",
+			cfg:     config.Auth{SigningKeyID: "hs-1"},
+			wantErr: ErrNoSigningKeys,
+		},
+		{
+			name:    "signing key not configured",
+			cfg:     config.Auth{SigningKeyID: "hs-2", Keys: map[string]string{"hs-1": "secret"}},
+			wantErr: ErrSigningKeyAbsent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newKeyring(tt.cfg); !errors.Is(err, tt.wantErr) {
+				t.Errorf("newKeyring() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,22 +2,94 @@ package service
 
 import (
 	"banners/domain/models"
+	"banners/internal/storage"
 	"banners/lib/logger/sl"
+	"context"
 	"errors"
 	"fmt"
-	"github.com/dgrijalva/jwt-go"
 	"golang.org/x/crypto/bcrypt"
+	"log/slog"
 	"time"
 )
 
+var (
+	ErrSignupDisabled   = errors.New("signup is disabled")
+	ErrRoleNotAllowed   = errors.New("signup is limited to the user role")
+	ErrUserDisabled     = errors.New("user is disabled")
+	ErrNothingToUpdate  = errors.New("nothing to update")
+	ErrCannotModifySelf = errors.New("admins cannot modify themselves")
+	ErrForeignTenant    = errors.New("users of other tenants can only be created by tenant managers")
+)
+
 type UserStorage interface {
-	CreateUserStorage(email, role string, passHash []byte) error
+	CreateUserStorage(ctx context.Context, email, role string, tenantID int64, passHash []byte, entry *models.AuditEntry) error
 	GetUserStorage(email string) (*models.User, error)
+	GetUserByIDStorage(id int64) (*models.User, error)
+	ListUsersStorage(ctx context.Context, tenantID int64, limit int, offset int) ([]models.User, error)
+	UpdateUserStorage(ctx context.Context, id int64, role *string, disabled *bool, entry *models.AuditEntry) (*models.User, error)
+	DeleteUserStorage(ctx context.Context, id int64, entry *models.AuditEntry) error
 }
 
-func (s *Service) CreateUser(email, role string, password string) error {
+// SignUpUser is the public registration. It may only create plain users
+// of the default tenant and can be turned off in the config.
+func (s *Service) SignUpUser(ctx context.Context, email, role string, password string) error {
+	const op = "service.SignUpUser"
+
+	if !s.signupEnabled {
+		return fmt.Errorf("%s: %w", op, ErrSignupDisabled)
+	}
+
+	if role != models.RoleUser {
+		return fmt.Errorf("%s: %w", op, ErrRoleNotAllowed)
+	}
+
+	err := s.createUser(ctx, email, role, models.DefaultTenantID, password, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// CreateUser creates a user on behalf of an admin. The user joins the
+// tenant of the admin unless tenantID names another one, which only tenant
+// managers may do. The admin can only grant a role whose permissions they
+// have themselves.
+func (s *Service) CreateUser(ctx context.Context, actor *models.Identity, email, role string, password string, tenantID int64) error {
 	const op = "service.CreateUser"
 
+	if tenantID == 0 {
+		tenantID = actor.TenantID
+	}
+
+	if tenantID != actor.TenantID {
+		allowed, err := s.HasPermission(ctx, actor, models.PermTenantManage)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if !allowed {
+			return fmt.Errorf("%s: %w", op, ErrForeignTenant)
+		}
+	}
+
+	err := s.checkGrantable(ctx, actor, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.createUser(ctx, email, role, tenantID, password, newAuditEntry(actor, models.AuditUserCreate))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// createUser creates the user and records entry for them, unless it is
+// nil.
+func (s *Service) createUser(ctx context.Context, email, role string, tenantID int64, password string, entry *models.AuditEntry) error {
+	const op = "service.createUser"
+
 	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		s.log.Error("failed to generate passHash", sl.Err(err))
@@ -25,7 +97,7 @@ func (s *Service) CreateUser(email, role string, password string) error {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = s.userStorage.CreateUserStorage(email, role, passHash)
+	err = s.userStorage.CreateUserStorage(ctx, email, role, tenantID, passHash, entry)
 	if err != nil {
 		s.log.Error("failed to create a user", sl.Err(err))
 
@@ -35,36 +107,208 @@ func (s *Service) CreateUser(email, role string, password string) error {
 	return nil
 }
 
-func (s *Service) LoginUser(email, password string) (string, error) {
+// EnsureAdmin creates the bootstrap admin from the config if it does not
+// exist yet, so the first admin does not have to be inserted by hand. It is
+// a superadmin of the default tenant, so it can also set up tenants.
+func (s *Service) EnsureAdmin(ctx context.Context, email, password string) error {
+	const op = "service.EnsureAdmin"
+
+	if email == "" || password == "" {
+		return nil
+	}
+
+	_, err := s.userStorage.GetUserStorage(email)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.createUser(ctx, email, models.RoleSuperAdmin, models.DefaultTenantID, password, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("bootstrap admin created", slog.String("email", email))
+
+	return nil
+}
+
+// LoginUser checks the credentials and issues a token pair. Failed logins
+// are counted per email and per IP, and both are locked out for a while
+// once they reach their limit. Unknown emails and wrong passwords give the
+// same error and take the same time.
+func (s *Service) LoginUser(ctx context.Context, email, password, ip string) (*models.TokenPair, error) {
 	const op = "service.LoginUser"
 
-	expiresAt := time.Now().Add(time.Minute * 100).Unix()
+	err := s.checkLoginLock(ctx, email, ip)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
 	user, err := s.userStorage.GetUserStorage(email)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		compareDummyHash(password)
+		s.registerLoginFailure(ctx, email, ip)
+
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if err != nil && errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-		return "", fmt.Errorf("%s: %w", op, err)
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		s.registerLoginFailure(ctx, email, ip)
+
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	tk := &models.Token{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
-		StandardClaims: &jwt.StandardClaims{
-			ExpiresAt: expiresAt,
-		},
+	err = s.c.ResetFailures(ctx, loginEmailKey(email))
+	if err != nil {
+		s.log.Error("failed to reset login failures", sl.Err(err))
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, tk)
+	if user.Disabled {
+		return nil, fmt.Errorf("%s: %w", op, ErrUserDisabled)
+	}
+
+	tokens, err := s.issueTokens(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+func (s *Service) ListUsers(ctx context.Context, tenantID int64, limit int, offset int) ([]models.User, error) {
+	const op = "service.ListUsers"
 
-	tokenString, err := token.SignedString([]byte("secret"))
+	users, err := s.userStorage.ListUsersStorage(ctx, tenantID, limit, offset)
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		s.log.Error("failed to list users", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+func (s *Service) GetUser(ctx context.Context, tenantID int64, id int64) (*models.User, error) {
+	const op = "service.GetUser"
+
+	user, err := s.tenantUser(tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// UpdateUser changes the role and/or the disabled flag of a user. Disabling
+// a user also revokes their refresh tokens, so they are logged out once the
+// current access token expires.
+func (s *Service) UpdateUser(ctx context.Context, actor *models.Identity, id int64, role *string, disabled *bool) (*models.User, error) {
+	const op = "service.UpdateUser"
+
+	if role == nil && disabled == nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrNothingToUpdate)
+	}
+
+	if actor.UserID == id {
+		return nil, fmt.Errorf("%s: %w", op, ErrCannotModifySelf)
+	}
+
+	_, err := s.tenantUser(actor.TenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if role != nil {
+		err = s.checkGrantable(ctx, actor, *role)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	user, err := s.userStorage.UpdateUserStorage(ctx, id, role, disabled, newAuditEntry(actor, models.AuditUserUpdate))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.Disabled {
+		err = s.tokenStorage.RevokeUserRefreshTokensStorage(ctx, id)
+		if err != nil {
+			s.log.Error("failed to revoke refresh tokens", sl.Err(err))
+
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// Access tokens carry the role, so they go as well when it changes.
+	if user.Disabled || role != nil {
+		err = s.revokeAccessTokens(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return user, nil
+}
+
+func (s *Service) DeleteUser(ctx context.Context, actor *models.Identity, id int64) error {
+	const op = "service.DeleteUser"
+
+	if actor.UserID == id {
+		return fmt.Errorf("%s: %w", op, ErrCannotModifySelf)
+	}
+
+	_, err := s.tenantUser(actor.TenantID, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.userStorage.DeleteUserStorage(ctx, id, newAuditEntry(actor, models.AuditUserDelete))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.revokeAccessTokens(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// revokeAccessTokens revokes the access tokens the user holds. Their ids
+// are not kept, so every token issued until now is revoked.
+func (s *Service) revokeAccessTokens(ctx context.Context, userID int64) error {
+	err := s.c.RevokeUser(ctx, userID, time.Now(), s.keys.ttl)
+	if err != nil {
+		s.log.Error("failed to revoke access tokens", sl.Err(err))
+
+		return err
+	}
+
+	return nil
+}
+
+// tenantUser returns the user only if they belong to the tenant, so that
+// users of other tenants look like they do not exist.
+func (s *Service) tenantUser(tenantID int64, id int64) (*models.User, error) {
+	user, err := s.userStorage.GetUserByIDStorage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TenantID != tenantID {
+		return nil, storage.ErrUserNotFound
 	}
 
-	return tokenString, nil
+	return user, nil
 }
@@ -0,0 +1,242 @@
+package postgresql
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"strconv"
+	"strings"
+)
+
+// CreateAPIKeyStorage creates the key and records entry with it in the same
+// transaction.
+func (s *Storage) CreateAPIKeyStorage(ctx context.Context, key *models.APIKey, keyHash string, entry *models.AuditEntry) (_ *models.APIKey, err error) {
+	const op = "storage.postgresql.CreateAPIKeyStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	query, args, err := sq.Insert("api_keys").
+		Columns("name", "prefix", "key_hash", "created_by", "tenant_id").
+		Values(key.Name, key.Prefix, keyHash, key.CreatedBy, key.TenantID).
+		Suffix("RETURNING id, created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	created := *key
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&created.ID, &created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(key.Scopes) != 0 {
+		scopesInsert := sq.Insert("api_key_scopes").
+			Columns("api_key_id", "permission")
+
+		for _, scope := range key.Scopes {
+			scopesInsert = scopesInsert.Values(created.ID, scope)
+		}
+
+		query, args, err = scopesInsert.PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		_, err = tx.ExecContext(ctx, query, args...)
+		if pgErrorCode(err) == pgForeignKeyViolation {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrPermissionNotFound)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if len(key.FeatureIDs) != 0 {
+		featuresInsert := sq.Insert("api_key_features").
+			Columns("api_key_id", "feature_id")
+
+		for _, featureID := range key.FeatureIDs {
+			featuresInsert = featuresInsert.Values(created.ID, featureID)
+		}
+
+		query, args, err = featuresInsert.PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if created.FeatureIDs == nil {
+		created.FeatureIDs = []int64{}
+	}
+
+	if entry != nil {
+		entry.Target = strconv.FormatInt(created.ID, 10)
+		entry.After = models.Snapshot(created)
+	}
+
+	err = insertAuditEntry(ctx, tx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &created, nil
+}
+
+func (s *Storage) ListAPIKeysStorage(ctx context.Context, tenantID int64) ([]models.APIKey, error) {
+	const op = "storage.postgresql.ListAPIKeysStorage"
+
+	query, args, err := apiKeySelect().
+		Where(sq.Eq{"k.tenant_id": tenantID}).
+		OrderBy("k.id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// GetActiveAPIKeyStorage looks up a key that has not been revoked by the
+// hash of its secret.
+func (s *Storage) GetActiveAPIKeyStorage(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	const op = "storage.postgresql.GetActiveAPIKeyStorage"
+
+	query, args, err := apiKeySelect().
+		Where(sq.Eq{"k.key_hash": keyHash, "k.revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) RevokeAPIKeyStorage(ctx context.Context, tenantID int64, id int64, entry *models.AuditEntry) (err error) {
+	const op = "storage.postgresql.RevokeAPIKeyStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	query, args, err := sq.Update("api_keys").
+		Set("revoked_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.Eq{"id": id, "tenant_id": tenantID, "revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = insertAuditEntry(ctx, tx, entry)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func apiKeySelect() sq.SelectBuilder {
+	return sq.Select(
+		"k.id", "k.name", "k.prefix", "k.tenant_id", "k.created_by", "k.created_at", "k.revoked_at",
+		"COALESCE((SELECT ARRAY_TO_STRING(ARRAY_AGG(permission), ',') FROM api_key_scopes WHERE api_key_id = k.id), '')",
+		"COALESCE((SELECT ARRAY_TO_STRING(ARRAY_AGG(feature_id), ',') FROM api_key_features WHERE api_key_id = k.id), '')",
+	).From("api_keys k")
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	key := &models.APIKey{Scopes: []string{}, FeatureIDs: []int64{}}
+
+	var scopesStr, featureIDsStr string
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.TenantID, &key.CreatedBy, &key.CreatedAt, &key.RevokedAt, &scopesStr, &featureIDsStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if scopesStr != "" {
+		key.Scopes = strings.Split(scopesStr, ",")
+	}
+
+	if featureIDsStr != "" {
+		key.FeatureIDs, err = parseTagIDs(featureIDsStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
@@ -0,0 +1,347 @@
+package postgresql
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"strconv"
+)
+
+var approvalColumns = []string{"ar.id", "ar.tenant_id", "ar.banner_id", "ar.revision_id", "ar.status", "ar.comment", "ar.requested_by", "ar.decided_by", "ar.created_at", "ar.decided_at", "br.updated_by"}
+
+// SetFeaturePolicyStorage sets whether the feature only publishes approved
+// revisions. Approval can not be required while an experiment runs on the
+// feature, as its variants were never approved, which is
+// storage.ErrExperimentRunning.
+func (s *Storage) SetFeaturePolicyStorage(ctx context.Context, tenantID int64, policy *models.FeaturePolicy, entry *models.AuditEntry) (err error) {
+	const op = "storage.postgresql.SetFeaturePolicyStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if policy.RequiresApproval {
+		query, args, err := sq.Select("COUNT(*)").
+			From("experiments").
+			Where(sq.Eq{"tenant_id": tenantID, "feature_id": policy.FeatureID, "status": models.ExperimentRunning}).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		var running int
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&running)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if running > 0 {
+			return fmt.Errorf("%s: %w", op, storage.ErrExperimentRunning)
+		}
+	}
+
+	query, args, err := sq.Insert("feature_policies").
+		Columns("tenant_id", "feature_id", "requires_approval").
+		Values(tenantID, policy.FeatureID, policy.RequiresApproval).
+		Suffix("ON CONFLICT (tenant_id, feature_id) DO UPDATE SET requires_approval = EXCLUDED.requires_approval").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = insertAuditEntry(ctx, tx, entry)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) CreateApprovalRequestStorage(ctx context.Context, tenantID int64, request *models.ApprovalRequest, entry *models.AuditEntry) (_ *models.ApprovalRequest, err error) {
+	const op = "storage.postgresql.CreateApprovalRequestStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	query, args, err := sq.Insert("approval_requests").
+		Columns("tenant_id", "banner_id", "revision_id", "comment", "requested_by").
+		Select(sq.Select("tenant_id", "banner_id", "revision_id").
+			Column("?::TEXT", request.Comment).
+			Column("?::INT", request.RequestedBy).
+			From("banner_revisions").
+			Where(sq.Eq{"tenant_id": tenantID, "banner_id": request.BannerID, "revision_id": request.RevisionID})).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRevisionDoesNotExist)
+	}
+	if pgErrorCode(err) == pgUniqueViolation {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrApprovalExists)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	created, err := auditApproval(ctx, tx, tenantID, id, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+func (s *Storage) GetApprovalRequestStorage(ctx context.Context, tenantID int64, id int64) (*models.ApprovalRequest, error) {
+	const op = "storage.postgresql.GetApprovalRequestStorage"
+
+	request, err := getApprovalRequest(ctx, s.db, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return request, nil
+}
+
+// getApprovalRequest is GetApprovalRequestStorage on q, so that the request
+// can be read in a transaction.
+func getApprovalRequest(ctx context.Context, q rowQuerier, tenantID int64, id int64) (*models.ApprovalRequest, error) {
+	const op = "storage.postgresql.getApprovalRequest"
+
+	query, args, err := sq.Select(approvalColumns...).
+		From("approval_requests ar").
+		Join("banner_revisions br ON ar.revision_id = br.revision_id").
+		Where(sq.Eq{"ar.id": id, "ar.tenant_id": tenantID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	request, err := scanApprovalRequest(q.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrApprovalNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return request, nil
+}
+
+func (s *Storage) ListApprovalRequestsStorage(ctx context.Context, tenantID int64, bannerID int, limit int, offset int) ([]models.ApprovalRequest, error) {
+	const op = "storage.postgresql.ListApprovalRequestsStorage"
+
+	query, args, err := sq.Select(approvalColumns...).
+		From("approval_requests ar").
+		Join("banner_revisions br ON ar.revision_id = br.revision_id").
+		Where(sq.Eq{"ar.banner_id": bannerID, "ar.tenant_id": tenantID}).
+		OrderBy("ar.id DESC").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	requests := []models.ApprovalRequest{}
+	for rows.Next() {
+		request, err := scanApprovalRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		requests = append(requests, *request)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return requests, nil
+}
+
+// DecideApprovalRequestStorage approves or rejects a pending request and
+// returns it as decided.
+func (s *Storage) DecideApprovalRequestStorage(ctx context.Context, tenantID int64, id int64, status string, decidedBy *int64, comment string, entry *models.AuditEntry) (_ *models.ApprovalRequest, err error) {
+	const op = "storage.postgresql.DecideApprovalRequestStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	updateBuilder := sq.Update("approval_requests").
+		Set("status", status).
+		Set("decided_by", decidedBy).
+		Set("decided_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": id, "tenant_id": tenantID, "status": models.ApprovalPending})
+
+	if comment != "" {
+		updateBuilder = updateBuilder.Set("comment", comment)
+	}
+
+	query, args, err := updateBuilder.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrApprovalNotPending)
+	}
+
+	decided, err := auditApproval(ctx, tx, tenantID, id, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return decided, nil
+}
+
+// auditApproval reads the request in tx and records entry with it.
+func auditApproval(ctx context.Context, tx *sql.Tx, tenantID int64, id int64, entry *models.AuditEntry) (*models.ApprovalRequest, error) {
+	const op = "storage.postgresql.auditApproval"
+
+	request, err := getApprovalRequest(ctx, tx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if entry != nil {
+		entry.BannerID = &request.BannerID
+		entry.RevisionID = &request.RevisionID
+		entry.Target = strconv.FormatInt(request.ID, 10)
+		entry.After = models.Snapshot(request)
+	}
+
+	err = insertAuditEntry(ctx, tx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return request, nil
+}
+
+// featureRequiresApproval reports whether the feature of the tenant only
+// publishes approved revisions.
+func featureRequiresApproval(ctx context.Context, q rowQuerier, tenantID int64, featureID int64) (bool, error) {
+	const op = "storage.postgresql.featureRequiresApproval"
+
+	query, args, err := sq.Select("requires_approval").
+		From("feature_policies").
+		Where(sq.Eq{"tenant_id": tenantID, "feature_id": featureID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var requiresApproval bool
+	err = q.QueryRowContext(ctx, query, args...).Scan(&requiresApproval)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return requiresApproval, nil
+}
+
+// checkApproval returns storage.ErrApprovalRequired if the feature requires
+// approval and the revision has not been approved.
+func checkApproval(ctx context.Context, q rowQuerier, tenantID int64, featureID int64, revisionID int64) error {
+	const op = "storage.postgresql.checkApproval"
+
+	requiresApproval, err := featureRequiresApproval(ctx, q, tenantID, featureID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !requiresApproval {
+		return nil
+	}
+
+	query, args, err := sq.Select("COUNT(*)").
+		From("approval_requests").
+		Where(sq.Eq{"revision_id": revisionID, "status": models.ApprovalApproved}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var approvals int
+	err = q.QueryRowContext(ctx, query, args...).Scan(&approvals)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if approvals == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrApprovalRequired)
+	}
+
+	return nil
+}
+
+func scanApprovalRequest(row rowScanner) (*models.ApprovalRequest, error) {
+	var request models.ApprovalRequest
+	err := row.Scan(&request.ID, &request.TenantID, &request.BannerID, &request.RevisionID, &request.Status, &request.Comment, &request.RequestedBy, &request.DecidedBy, &request.CreatedAt, &request.DecidedAt, &request.RevisionAuthor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
@@ -0,0 +1,156 @@
+package postgresql
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+)
+
+// insertAuditEntry records an action in the transaction that makes it, so
+// that the change and its record commit or fail together. A nil entry is
+// not recorded.
+func insertAuditEntry(ctx context.Context, tx *sql.Tx, entry *models.AuditEntry) error {
+	const op = "storage.postgresql.insertAuditEntry"
+
+	if entry == nil {
+		return nil
+	}
+
+	query, args, err := sq.Insert("audit_log").
+		Columns("tenant_id", "actor_id", "actor_email", "api_key_id", "action", "banner_id", "revision_id", "target_user_id", "target", "before", "after").
+		Values(entry.TenantID, entry.ActorID, nullString(entry.ActorEmail), entry.APIKeyID, entry.Action, entry.BannerID, entry.RevisionID, entry.TargetUserID, nullString(entry.Target), nullJSON(entry.Before), nullJSON(entry.After)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// auditBanner records an action on the banner. before is the banner as it
+// was read in tx ahead of the action, the state after it is read in tx as
+// well. The revision is taken from the state after the action, or from the
+// one before for deletions.
+func auditBanner(ctx context.Context, tx *sql.Tx, tenantID int64, bannerID int64, before *models.Banner, entry *models.AuditEntry) error {
+	const op = "storage.postgresql.auditBanner"
+
+	if entry == nil {
+		return nil
+	}
+
+	after, err := bannerSnapshot(ctx, tx, tenantID, int(bannerID))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	entry.BannerID = &bannerID
+	if before != nil {
+		entry.Before = models.Snapshot(before)
+		entry.RevisionID = &before.Revision
+	}
+	if after != nil {
+		entry.After = models.Snapshot(after)
+		entry.RevisionID = &after.Revision
+	}
+
+	return insertAuditEntry(ctx, tx, entry)
+}
+
+// bannerSnapshot returns the chosen revision of the banner as q sees it, or
+// nil if the banner does not exist.
+func bannerSnapshot(ctx context.Context, q rowQuerier, tenantID int64, bannerID int) (*models.Banner, error) {
+	banner, err := getBanner(ctx, q, tenantID, bannerID)
+	if errors.Is(err, storage.ErrBannerNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return banner, nil
+}
+
+func (s *Storage) ListAuditEntriesStorage(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	const op = "storage.postgresql.ListAuditEntriesStorage"
+
+	selectBuilder := sq.Select(
+		"id", "tenant_id", "actor_id", "COALESCE(actor_email, '')", "api_key_id", "action", "banner_id", "revision_id",
+		"target_user_id", "COALESCE(target, '')", "before", "after", "created_at",
+	).From("audit_log").
+		Where(sq.Eq{"tenant_id": filter.TenantID})
+
+	if filter.ActorID != nil {
+		selectBuilder = selectBuilder.Where(sq.Eq{"actor_id": *filter.ActorID})
+	}
+	if filter.BannerID != nil {
+		selectBuilder = selectBuilder.Where(sq.Eq{"banner_id": *filter.BannerID})
+	}
+	if filter.From != nil {
+		selectBuilder = selectBuilder.Where(sq.GtOrEq{"created_at": *filter.From})
+	}
+	if filter.To != nil {
+		selectBuilder = selectBuilder.Where(sq.Lt{"created_at": *filter.To})
+	}
+
+	query, args, err := selectBuilder.
+		OrderBy("id DESC").
+		Limit(uint64(filter.Limit)).
+		Offset(uint64(filter.Offset)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var before, after []byte
+		err := rows.Scan(&entry.ID, &entry.TenantID, &entry.ActorID, &entry.ActorEmail, &entry.APIKeyID, &entry.Action, &entry.BannerID, &entry.RevisionID,
+			&entry.TargetUserID, &entry.Target, &before, &after, &entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		entry.Before, entry.After = before, after
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+func nullJSON(data []byte) *string {
+	if len(data) == 0 {
+		return nil
+	}
+
+	s := string(data)
+	return &s
+}
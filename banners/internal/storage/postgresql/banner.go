@@ -10,29 +10,184 @@ import (
 	sq "github.com/Masterminds/squirrel"
 	"strconv"
 	"strings"
+	"time"
 )
 
-func (s *Storage) GetUsersBannerStorage(ctx context.Context, tagID int, featureID int) (*models.Banner, error) {
-	const op = "storage.postgresql.GetUsersBanner"
+// revisionState selects the state of the revision br of the banner b.
+const revisionState = "CASE WHEN br.revision_id = b.chosen_revision_id THEN '" + models.RevisionLive + "' " +
+	"WHEN br.published_at IS NOT NULL THEN '" + models.RevisionPublished + "' " +
+	"ELSE '" + models.RevisionDraft + "' END AS state"
 
-	query, args, err := sq.Select("br.content, br.is_active").
+// liveNow keeps the revisions whose schedule includes the current time.
+var liveNow = sq.Expr("(br.starts_at IS NULL OR br.starts_at <= NOW()) AND (br.ends_at IS NULL OR br.ends_at > NOW())")
+
+// UserBannerCandidatesStorage returns the live banners of the feature for
+// the tag and its ancestors, in order of preference: banners of nearer tags
+// first, targeted banners before untargeted ones and newer revisions before
+// older ones. MatchedTagID tells which tag each was found by. It returns
+// storage.ErrBannerNotFound if there are none.
+func (s *Storage) UserBannerCandidatesStorage(ctx context.Context, tenantID int64, tagID int, featureID int) ([]models.Banner, error) {
+	const op = "storage.postgresql.UserBannerCandidatesStorage"
+
+	selectBuilder := sq.Select("br.content, br.is_active, br.starts_at, br.ends_at, rt.tag_id, br.targeting").
+		Prefix(tagAncestors, tagID, tenantID, maxTagDepth).
 		From("banner_revisions br").
 		Join("revision_tags rt ON br.revision_id = rt.revision_id").
+		Join("ancestors a ON rt.tag_id = a.tag_id")
+
+	query, args, err := withRollout(selectBuilder).
 		Where(sq.And{
-			sq.Eq{"rt.tag_id": tagID},
+			sq.Eq{"br.tenant_id": tenantID},
 			sq.Eq{"br.feature_id": featureID},
 		}).
 		Where(sq.Expr("br.banner_id IN (SELECT banner_id FROM banners WHERE chosen_revision_id = br.revision_id)")).
+		Where(liveNow).
+		OrderBy("a.depth", "br.targeting IS NULL", "br.revision_id DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var banners []models.Banner
+	for rows.Next() {
+		var banner models.Banner
+		var targeting []byte
+		var rollout rolloutScan
+		err = rows.Scan(append([]any{&banner.Content, &banner.IsActive, &banner.StartsAt, &banner.EndsAt, &banner.MatchedTagID, &targeting}, rollout.dest()...)...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		banner.Targeting, err = parseTargeting(targeting)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		banner.Rollout, err = rollout.rollout()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		banners = append(banners, banner)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(banners) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+	}
+
+	return banners, nil
+}
+
+// GetUsersBannerAsOfStorage returns the revision that users of the tag saw
+// for the feature at the given time. Like for GetUserBannerStorage, a banner
+// of the tag itself comes before one of its ancestors, which are those of the
+// tag tree as it is now. Publications of banners deleted since are still
+// found, without their revision.
+func (s *Storage) GetUsersBannerAsOfStorage(ctx context.Context, tenantID int64, tagID int, featureID int, at time.Time) (*models.LiveBanner, error) {
+	const op = "storage.postgresql.GetUsersBannerAsOfStorage"
+
+	query, args, err := livePublications(at, "a.tag_id").
+		Prefix(tagAncestors, tagID, tenantID, maxTagDepth).
+		Join("ancestors a ON a.tag_id = ANY(bp.tag_ids)").
+		Where(sq.Eq{"bp.tenant_id": tenantID, "bp.feature_id": featureID}).
+		// Targeted banners only reached some of the users, the one all the
+		// others saw comes first.
+		OrderBy("a.depth", "bp.targeted", "bp.banner_id").
+		Limit(1).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	row := s.db.QueryRowContext(ctx, query, args...)
+	banner, err := scanLiveBanner(s.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return banner, nil
+}
+
+// livePublications selects the publications that were live at the given
+// time, with their revision, for scanLiveBanner. matchedTagID is the column
+// of the tag the banner is found by.
+func livePublications(at time.Time, matchedTagID string) sq.SelectBuilder {
+	return sq.Select("bp.banner_id", "bp.revision_id", "br.is_active", "br.content", "br.starts_at", "br.ends_at", "bp.published_at", "bp.published_by", matchedTagID).
+		From("banner_publications bp").
+		LeftJoin("banner_revisions br ON bp.revision_id = br.revision_id").
+		// The publication that was live at the time: the newest one before
+		// it that had not been rolled back yet.
+		Where(sq.Expr("bp.id = (SELECT MAX(p.id) FROM banner_publications p WHERE p.banner_id = bp.banner_id AND p.published_at <= ? AND (p.rolled_back_at IS NULL OR p.rolled_back_at > ?))", at, at)).
+		Where(sq.Expr("(bp.banner_deleted_at IS NULL OR bp.banner_deleted_at > ?)", at)).
+		Where(sq.Expr("(br.starts_at IS NULL OR br.starts_at <= ?) AND (br.ends_at IS NULL OR br.ends_at > ?)", at, at))
+}
+
+// scanLiveBanner scans a row of livePublications, storage.ErrBannerNotFound
+// if there is none.
+func scanLiveBanner(row *sql.Row) (*models.LiveBanner, error) {
+	const op = "storage.postgresql.scanLiveBanner"
+
+	var banner models.LiveBanner
+	var content []byte
+	var matchedTagID *int64
+	err := row.Scan(&banner.BannerID, &banner.RevisionID, &banner.IsActive, &content, &banner.StartsAt, &banner.EndsAt, &banner.LiveSince, &banner.PublishedBy, &matchedTagID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	banner.Content = content
+	if matchedTagID != nil {
+		banner.MatchedTagID = *matchedTagID
+	}
+
+	return &banner, nil
+}
+
+// GetBannerStorage returns the chosen revision of the banner.
+func (s *Storage) GetBannerStorage(ctx context.Context, tenantID int64, bannerID int) (*models.Banner, error) {
+	const op = "storage.postgresql.GetBannerStorage"
+
+	banner, err := getBanner(ctx, s.db, tenantID, bannerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return banner, nil
+}
+
+// getBanner is GetBannerStorage on q, so that the banner can be read in a
+// transaction.
+func getBanner(ctx context.Context, q rowQuerier, tenantID int64, bannerID int) (*models.Banner, error) {
+	const op = "storage.postgresql.getBanner"
+
+	query, args, err := sq.Select("b.banner_id", "br.revision_id", "br.feature_id", "br.is_active", "br.content", "br.created_at", "br.updated_at", "br.created_by", "br.updated_by", "br.starts_at", "br.ends_at", "br.targeting", "COALESCE(ARRAY_TO_STRING(ARRAY_AGG(rt.tag_id), ', '), '') AS tag_ids").
+		From("banners b").
+		Join("banner_revisions br ON b.chosen_revision_id = br.revision_id").
+		LeftJoin("revision_tags rt ON br.revision_id = rt.revision_id").
+		Where(sq.Eq{"b.banner_id": bannerID, "b.tenant_id": tenantID}).
+		GroupBy("b.banner_id", "br.revision_id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
 	var banner models.Banner
-	err = row.Scan(&banner.Content, &banner.IsActive)
+	var targeting []byte
+	var tagIDsStr string
+	err = q.QueryRowContext(ctx, query, args...).Scan(&banner.BannerID, &banner.Revision, &banner.FeatureID, &banner.IsActive, &banner.Content, &banner.CreatedAt, &banner.UpdatedAT, &banner.CreatedBy, &banner.UpdatedBy, &banner.StartsAt, &banner.EndsAt, &targeting, &tagIDsStr)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
 	}
@@ -40,10 +195,24 @@ func (s *Storage) GetUsersBannerStorage(ctx context.Context, tagID int, featureI
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	banner.Targeting, err = parseTargeting(targeting)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tagIDsStr != "" {
+		banner.TagIDs, err = parseTagIDs(tagIDsStr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	return &banner, nil
 }
 
-func (s *Storage) PostBannerStorage(ctx context.Context, banner *models.Banner) (int, error) {
+// PostBannerStorage creates the banner and records entry in the same
+// transaction.
+func (s *Storage) PostBannerStorage(ctx context.Context, tenantID int64, banner *models.Banner, entry *models.AuditEntry) (_ int, err error) {
 	const op = "storage.postgresql.PostBanner"
 
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -59,7 +228,8 @@ func (s *Storage) PostBannerStorage(ctx context.Context, banner *models.Banner)
 	}()
 
 	bannerInsert := sq.Insert("banners").
-		Values(sq.Expr("DEFAULT")).
+		Columns("tenant_id").
+		Values(tenantID).
 		Suffix("RETURNING banner_id")
 
 	var bannerID int
@@ -68,9 +238,26 @@ func (s *Storage) PostBannerStorage(ctx context.Context, banner *models.Banner)
 		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 
+	// On features that require approval the first revision is a draft, and
+	// the banner goes live once it is approved and published.
+	requiresApproval, err := featureRequiresApproval(ctx, tx, tenantID, banner.FeatureID)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var publishedAt any
+	if !requiresApproval {
+		publishedAt = sq.Expr("NOW()")
+	}
+
+	targeting, err := targetingValue(banner.Targeting)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
 	bannerRevInsert := sq.Insert("banner_revisions").
-		Columns("banner_id", "feature_id", "content", "is_active").
-		Values(bannerID, banner.FeatureID, banner.Content, banner.IsActive).
+		Columns("banner_id", "tenant_id", "feature_id", "content", "is_active", "created_by", "updated_by", "starts_at", "ends_at", "published_at", "schema_version", "targeting").
+		Values(bannerID, tenantID, banner.FeatureID, banner.Content, banner.IsActive, banner.UpdatedBy, banner.UpdatedBy, banner.StartsAt, banner.EndsAt, publishedAt, banner.SchemaVersion, targeting).
 		Suffix("RETURNING revision_id")
 
 	var revisionID int
@@ -91,43 +278,143 @@ func (s *Storage) PostBannerStorage(ctx context.Context, banner *models.Banner)
 		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if !requiresApproval {
+		err = s.publishFirstRevision(ctx, tx, tenantID, bannerID, revisionID, banner)
+		if err != nil {
+			return -1, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	err = auditBanner(ctx, tx, tenantID, int64(bannerID), nil, entry)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bannerID, nil
+}
+
+// publishFirstRevision makes the first revision of a new banner live.
+func (s *Storage) publishFirstRevision(ctx context.Context, tx *sql.Tx, tenantID int64, bannerID int, revisionID int, banner *models.Banner) error {
+	const op = "storage.postgresql.publishFirstRevision"
+
+	err := s.claimFeatureTags(ctx, tx, tenantID, int64(bannerID), banner.FeatureID, banner.TagIDs, banner.Targeting != nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	bannerUpdate, args, err := sq.Update("banners").
 		Set("chosen_revision_id", revisionID).
 		Where(sq.Eq{"banner_id": bannerID}).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 	if err != nil {
-		return -1, fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	_, err = tx.ExecContext(ctx, bannerUpdate, args...)
 	if err != nil {
-		return -1, fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	return bannerID, nil
+	err = recordPublication(ctx, tx, tenantID, int64(bannerID), int64(revisionID), banner.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
 }
 
-func (s *Storage) ChooseRevisionStorage(ctx context.Context, bannerID int, revisionID int) error {
+func (s *Storage) ChooseRevisionStorage(ctx context.Context, tenantID int64, bannerID int, revisionID int, publishedBy *int64, entry *models.AuditEntry) (err error) {
 	const op = "storage.postgresql.ChooseRevision"
 
-	checkQuery := sq.Select("*").
-		From("banner_revisions").
-		Where(sq.Eq{"banner_id": bannerID, "revision_id": revisionID}).
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	before, err := bannerSnapshot(ctx, tx, tenantID, bannerID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err := sq.Select("br.feature_id", "br.targeting IS NOT NULL", "COALESCE(ARRAY_TO_STRING(ARRAY_AGG(rt.tag_id), ', '), '') AS tag_ids").
+		From("banner_revisions br").
+		LeftJoin("revision_tags rt ON br.revision_id = rt.revision_id").
+		Where(sq.Eq{"br.banner_id": bannerID, "br.revision_id": revisionID, "br.tenant_id": tenantID}).
+		GroupBy("br.revision_id").
 		PlaceholderFormat(sq.Dollar).
-		Limit(1)
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
-	err := checkQuery.RunWith(s.db).QueryRowContext(ctx).Scan()
+	var featureID int64
+	var targeted bool
+	var tagIDsStr string
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&featureID, &targeted, &tagIDsStr)
 	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s: %w", op, storage.ErrRevisionDoesNotExist)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var tagIDs []int64
+	if tagIDsStr != "" {
+		tagIDs, err = parseTagIDs(tagIDsStr)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	err = checkApproval(ctx, tx, tenantID, featureID, int64(revisionID))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.claimFeatureTags(ctx, tx, tenantID, int64(bannerID), featureID, tagIDs, targeted)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// A draft is published the first time it is chosen.
+	query, args, err = sq.Update("banner_revisions").
+		Set("published_at", sq.Expr("COALESCE(published_at, NOW())")).
+		Where(sq.Eq{"revision_id": revisionID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	chosenRevInsert := sq.Update("banners").
 		Set("chosen_revision_id", revisionID).
-		Where(sq.Eq{"banner_id": bannerID}).
+		Where(sq.Eq{"banner_id": bannerID, "tenant_id": tenantID}).
 		Suffix("RETURNING chosen_revision_id")
 
-	err = chosenRevInsert.RunWith(s.db).PlaceholderFormat(sq.Dollar).QueryRowContext(ctx).Scan(&revisionID)
+	err = chosenRevInsert.RunWith(tx).PlaceholderFormat(sq.Dollar).QueryRowContext(ctx).Scan(&revisionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = recordPublication(ctx, tx, tenantID, int64(bannerID), int64(revisionID), publishedBy)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = auditBanner(ctx, tx, tenantID, int64(bannerID), before, entry)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -135,14 +422,15 @@ func (s *Storage) ChooseRevisionStorage(ctx context.Context, bannerID int, revis
 	return nil
 }
 
-func (s *Storage) ListRevisionsStorage(ctx context.Context, bannerID int, limit int, offset int) (*[]models.Banner, error) {
+func (s *Storage) ListRevisionsStorage(ctx context.Context, tenantID int64, bannerID int, limit int, offset int) (*[]models.Banner, error) {
 	const op = "storage.postgresql.ListRevisionsStorage"
 
-	query, args, err := sq.Select("br.*, ARRAY_TO_STRING(ARRAY_AGG(rt.tag_id), ', ') AS tags").
+	query, args, err := sq.Select("br.revision_id", "br.banner_id", "br.feature_id", "br.is_active", "br.content", "br.created_at", "br.updated_at", "br.created_by", "br.updated_by", "br.starts_at", "br.ends_at", "br.published_at", "br.schema_version", "br.targeting", revisionState, "ARRAY_TO_STRING(ARRAY_AGG(rt.tag_id), ', ') AS tags").
 		From("banner_revisions br").
+		Join("banners b ON br.banner_id = b.banner_id").
 		Join("revision_tags rt ON br.revision_id = rt.revision_id").
-		Where(sq.Eq{"banner_id": bannerID}).
-		GroupBy("br.revision_id, br.banner_id, br.feature_id, br.is_active, br.content, br.created_at, br.updated_at").
+		Where(sq.Eq{"br.banner_id": bannerID, "br.tenant_id": tenantID}).
+		GroupBy("br.revision_id", "b.chosen_revision_id").
 		Limit(uint64(limit)).
 		Offset(uint64(offset)).
 		PlaceholderFormat(sq.Dollar).
@@ -160,7 +448,13 @@ func (s *Storage) ListRevisionsStorage(ctx context.Context, bannerID int, limit
 	var tagIDsStr string
 	for rows.Next() {
 		var revision models.Banner
-		if err = rows.Scan(&revision.Revision, &revision.BannerID, &revision.FeatureID, &revision.IsActive, &revision.Content, &revision.CreatedAt, &revision.UpdatedAT, &tagIDsStr); err != nil {
+		var targeting []byte
+		if err = rows.Scan(&revision.Revision, &revision.BannerID, &revision.FeatureID, &revision.IsActive, &revision.Content, &revision.CreatedAt, &revision.UpdatedAT, &revision.CreatedBy, &revision.UpdatedBy, &revision.StartsAt, &revision.EndsAt, &revision.PublishedAt, &revision.SchemaVersion, &targeting, &revision.State, &tagIDsStr); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		revision.Targeting, err = parseTargeting(targeting)
+		if err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 
@@ -179,13 +473,16 @@ func (s *Storage) ListRevisionsStorage(ctx context.Context, bannerID int, limit
 	return &revisions, nil
 }
 
-func (s *Storage) ListBannersStorage(ctx context.Context, featureID int, tagID int, limit int, offset int) (*[]models.Banner, error) {
+// ListBannersStorage lists the banners of the feature and tag. Banners
+// outside of their schedule are left out unless includeScheduled is set.
+func (s *Storage) ListBannersStorage(ctx context.Context, tenantID int64, featureID int, tagID int, includeScheduled bool, limit int, offset int) (*[]models.Banner, error) {
 	const op = "storage.postgresql.ListBannersStorage"
 
-	query, args, err := sq.Select("b.banner_id", "br.feature_id", "br.is_active", "br.created_at", "br.updated_at", "br.revision_id", "br.content", "ARRAY_TO_STRING(ARRAY_AGG(rt.tag_id), ', ') AS tag_ids").
+	selectBuilder := sq.Select("b.banner_id", "br.feature_id", "br.is_active", "br.created_at", "br.updated_at", "br.revision_id", "br.content", "br.created_by", "br.updated_by", "br.starts_at", "br.ends_at", "br.targeting", "ARRAY_TO_STRING(ARRAY_AGG(rt.tag_id), ', ') AS tag_ids").
 		From("banners b").
 		Join("banner_revisions br ON b.chosen_revision_id = br.revision_id").
 		Join("revision_tags rt ON br.revision_id = rt.revision_id").
+		Where(sq.Eq{"b.tenant_id": tenantID}).
 		Where(sq.Eq{"br.feature_id": featureID}).
 		Where(sq.Expr("rt.revision_id IN (SELECT revision_id FROM revision_tags WHERE tag_id = ?)", tagID)).
 		Where(sq.Expr("br.revision_id IN (?)",
@@ -195,8 +492,14 @@ func (s *Storage) ListBannersStorage(ctx context.Context, featureID int, tagID i
 				Where(sq.Eq{"br.feature_id": featureID}).
 				Where(sq.Expr("br.revision_id = b.chosen_revision_id")).
 				GroupBy("br.revision_id"),
-		)).
-		GroupBy("b.banner_id", "br.feature_id", "br.is_active", "br.created_at", "br.updated_at", "br.revision_id", "br.content").
+		))
+
+	if !includeScheduled {
+		selectBuilder = selectBuilder.Where(liveNow)
+	}
+
+	query, args, err := selectBuilder.
+		GroupBy("b.banner_id", "br.revision_id").
 		Limit(uint64(limit)).
 		Offset(uint64(offset)).
 		PlaceholderFormat(sq.Dollar).
@@ -215,7 +518,13 @@ func (s *Storage) ListBannersStorage(ctx context.Context, featureID int, tagID i
 	var tagIDsStr string
 	for rows.Next() {
 		var banner models.Banner
-		err := rows.Scan(&banner.BannerID, &banner.FeatureID, &banner.IsActive, &banner.CreatedAt, &banner.UpdatedAT, &banner.Revision, &banner.Content, &tagIDsStr)
+		var targeting []byte
+		err := rows.Scan(&banner.BannerID, &banner.FeatureID, &banner.IsActive, &banner.CreatedAt, &banner.UpdatedAT, &banner.Revision, &banner.Content, &banner.CreatedBy, &banner.UpdatedBy, &banner.StartsAt, &banner.EndsAt, &targeting, &tagIDsStr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		banner.Targeting, err = parseTargeting(targeting)
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
@@ -312,12 +621,14 @@ func (s *Storage) ListBannersStorage(ctx context.Context, featureID int, tagID i
 //	return nil
 //}
 
-func (s *Storage) PatchBannerStorage(ctx context.Context, banner *models.Banner) error {
+// PatchBannerStorage adds a revision to the banner and returns its ID. The
+// revision is a draft unless publish is set, in which case it is chosen.
+func (s *Storage) PatchBannerStorage(ctx context.Context, tenantID int64, banner *models.Banner, publish bool, entry *models.AuditEntry) (_ int, err error) {
 	const op = "storage.postgresql.PatchBannerStorage"
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 	defer func() {
 		if err != nil {
@@ -327,19 +638,54 @@ func (s *Storage) PatchBannerStorage(ctx context.Context, banner *models.Banner)
 		}
 	}()
 
+	// 0. Make sure the banner belongs to the tenant
+	var ownedBannerID int64
+	query, args, err := sq.Select("banner_id").
+		From("banners").
+		Where(sq.Eq{"banner_id": banner.BannerID, "tenant_id": tenantID}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&ownedBannerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return -1, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+	}
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	before, err := bannerSnapshot(ctx, tx, tenantID, int(banner.BannerID))
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
 	// 1. Insert a new revision into banner_revisions
 	var newRevisionID int
+	var publishedAt any
+	if publish {
+		publishedAt = sq.Expr("NOW()")
+	}
+	// The banner keeps the author of its first revision.
+	createdBy := sq.Expr("(SELECT created_by FROM banner_revisions WHERE banner_id = ? ORDER BY revision_id LIMIT 1)", banner.BannerID)
+	targeting, err := targetingValue(banner.Targeting)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
 	insertBuilder := sq.Insert("banner_revisions").
-		Columns("banner_id", "is_active", "feature_id", "content").
-		Values(banner.BannerID, banner.IsActive, banner.FeatureID, banner.Content).
+		Columns("banner_id", "tenant_id", "is_active", "feature_id", "content", "created_by", "updated_by", "starts_at", "ends_at", "published_at", "schema_version", "targeting").
+		Values(banner.BannerID, tenantID, banner.IsActive, banner.FeatureID, banner.Content, createdBy, banner.UpdatedBy, banner.StartsAt, banner.EndsAt, publishedAt, banner.SchemaVersion, targeting).
 		Suffix("RETURNING revision_id") // Retrieve the generated revision_id
-	query, args, err := insertBuilder.PlaceholderFormat(sq.Dollar).ToSql()
+	query, args, err = insertBuilder.PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 	err = tx.QueryRowContext(ctx, query, args...).Scan(&newRevisionID)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 
 	// 2. Insert into revision_tags
@@ -353,37 +699,177 @@ func (s *Storage) PatchBannerStorage(ctx context.Context, banner *models.Banner)
 
 		query, args, err := bannerTagsInsert.PlaceholderFormat(sq.Dollar).ToSql()
 		if err != nil {
-			return fmt.Errorf("%s: %w", op, err)
+			return -1, fmt.Errorf("%s: %w", op, err)
 		}
 
 		_, err = tx.ExecContext(ctx, query, args...)
 		if err != nil {
-			return fmt.Errorf("%s: %w", op, err)
+			return -1, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if !publish {
+		err = auditBanner(ctx, tx, tenantID, banner.BannerID, before, entry)
+		if err != nil {
+			return -1, fmt.Errorf("%s: %w", op, err)
 		}
+
+		return newRevisionID, nil
+	}
+
+	// 3. A new revision cannot have been approved yet
+	err = checkApproval(ctx, tx, tenantID, banner.FeatureID, int64(newRevisionID))
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 
-	// 3. Update the banners table to reference the new revision
+	// 4. Make sure no other banner has the feature and one of the tags
+	err = s.claimFeatureTags(ctx, tx, tenantID, banner.BannerID, banner.FeatureID, banner.TagIDs, banner.Targeting != nil)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// 5. Update the banners table to reference the new revision
 	updateBuilder := sq.Update("banners").
 		Set("chosen_revision_id", newRevisionID).
 		Where(sq.Eq{"banner_id": banner.BannerID})
 	query, args, err = updateBuilder.PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 	_, err = tx.ExecContext(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	// 6. Remember the publication for rollbacks
+	err = recordPublication(ctx, tx, tenantID, banner.BannerID, int64(newRevisionID), banner.UpdatedBy)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// 7. Record the change in the audit log
+	err = auditBanner(ctx, tx, tenantID, banner.BannerID, before, entry)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return newRevisionID, nil
 }
 
-func (s *Storage) DeleteBannerStorage(ctx context.Context, bannerID int) error {
-	const op = "storage.postgresql.DeleteBannerStorage"
+// GetRevisionStorage returns a single revision of the banner.
+func (s *Storage) GetRevisionStorage(ctx context.Context, tenantID int64, bannerID int, revisionID int) (*models.Banner, error) {
+	const op = "storage.postgresql.GetRevisionStorage"
 
-	tx, err := s.db.BeginTx(ctx, nil)
+	query, args, err := sq.Select("br.revision_id", "br.banner_id", "br.feature_id", "br.is_active", "br.content", "br.created_at", "br.updated_at", "br.created_by", "br.updated_by", "br.starts_at", "br.ends_at", "br.published_at", "br.schema_version", "br.targeting", revisionState, "COALESCE(ARRAY_TO_STRING(ARRAY_AGG(rt.tag_id), ', '), '') AS tag_ids").
+		From("banner_revisions br").
+		Join("banners b ON br.banner_id = b.banner_id").
+		LeftJoin("revision_tags rt ON br.revision_id = rt.revision_id").
+		Where(sq.Eq{"br.banner_id": bannerID, "br.revision_id": revisionID, "br.tenant_id": tenantID}).
+		GroupBy("br.revision_id", "b.chosen_revision_id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var revision models.Banner
+	var targeting []byte
+	var tagIDsStr string
+	err = s.db.QueryRowContext(ctx, query, args...).Scan(&revision.Revision, &revision.BannerID, &revision.FeatureID, &revision.IsActive, &revision.Content, &revision.CreatedAt, &revision.UpdatedAT, &revision.CreatedBy, &revision.UpdatedBy, &revision.StartsAt, &revision.EndsAt, &revision.PublishedAt, &revision.SchemaVersion, &targeting, &revision.State, &tagIDsStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRevisionDoesNotExist)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	revision.Targeting, err = parseTargeting(targeting)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tagIDsStr != "" {
+		revision.TagIDs, err = parseTagIDs(tagIDsStr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return &revision, nil
+}
+
+// LatestRevisionStorage returns the ID of the newest revision of the banner.
+func (s *Storage) LatestRevisionStorage(ctx context.Context, tenantID int64, bannerID int) (int, error) {
+	const op = "storage.postgresql.LatestRevisionStorage"
+
+	query, args, err := sq.Select("MAX(revision_id)").
+		From("banner_revisions").
+		Where(sq.Eq{"banner_id": bannerID, "tenant_id": tenantID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var revisionID sql.NullInt64
+	err = s.db.QueryRowContext(ctx, query, args...).Scan(&revisionID)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+	if !revisionID.Valid {
+		return -1, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+	}
+
+	return int(revisionID.Int64), nil
+}
+
+// BannerFeaturesStorage returns every feature the revisions of the banner
+// have had.
+func (s *Storage) BannerFeaturesStorage(ctx context.Context, tenantID int64, bannerID int) ([]int64, error) {
+	const op = "storage.postgresql.BannerFeaturesStorage"
+
+	query, args, err := sq.Select("DISTINCT feature_id").
+		From("banner_revisions").
+		Where(sq.Eq{"banner_id": bannerID, "tenant_id": tenantID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var featureIDs []int64
+	for rows.Next() {
+		var featureID int64
+		if err := rows.Scan(&featureID); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		featureIDs = append(featureIDs, featureID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(featureIDs) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+	}
+
+	return featureIDs, nil
+}
+
+func (s *Storage) DeleteBannerStorage(ctx context.Context, tenantID int64, bannerID int, entry *models.AuditEntry) (err error) {
+	const op = "storage.postgresql.DeleteBannerStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 	defer func() {
 		if err != nil {
@@ -393,8 +879,16 @@ func (s *Storage) DeleteBannerStorage(ctx context.Context, bannerID int) error {
 		}
 	}()
 
+	before, err := bannerSnapshot(ctx, tx, tenantID, bannerID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if before == nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+	}
+
 	query, args, err := sq.Delete("banners").
-		Where(sq.Eq{"banner_id": bannerID}).
+		Where(sq.Eq{"banner_id": bannerID, "tenant_id": tenantID}).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 	if err != nil {
@@ -406,12 +900,24 @@ func (s *Storage) DeleteBannerStorage(ctx context.Context, bannerID int) error {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	err = endPublications(ctx, tx, []int64{int64(bannerID)})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = auditBanner(ctx, tx, tenantID, int64(bannerID), before, entry)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
 
-func (s *Storage) DeleteUserBannerByFeatureTagStorage(ctx context.Context, tagID int, featureID int) error {
+// DeleteUserBannerByFeatureTagStorage deletes the banners of the feature and
+// tag in the background. entry is recorded along with the deletion.
+func (s *Storage) DeleteUserBannerByFeatureTagStorage(ctx context.Context, tenantID int64, tagID int, featureID int, entry *models.AuditEntry) error {
 	const op = "storage.postgresql.DeleteUserBannerByFeatureTagStorage"
-	task := func() error {
+	task := func() (err error) {
 		tx, err := s.db.BeginTx(ctx, nil)
 		if err != nil {
 			return fmt.Errorf("%s: %w", op, err)
@@ -425,14 +931,41 @@ func (s *Storage) DeleteUserBannerByFeatureTagStorage(ctx context.Context, tagID
 		}()
 
 		query, args, err := sq.Delete("banners").
+			Where(sq.Eq{"tenant_id": tenantID}).
 			Where(sq.Expr("chosen_revision_id IN (SELECT br.revision_id FROM banner_revisions br JOIN revision_tags rt ON br.revision_id = rt.revision_id WHERE br.feature_id = ? AND rt.tag_id = ?)", featureID, tagID)).
+			Suffix("RETURNING banner_id").
 			PlaceholderFormat(sq.Dollar).
 			ToSql()
 		if err != nil {
 			return fmt.Errorf("%s: %w", op, err)
 		}
 
-		_, err = tx.ExecContext(ctx, query, args...)
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		var bannerIDs []int64
+		for rows.Next() {
+			var bannerID int64
+			if err = rows.Scan(&bannerID); err != nil {
+				rows.Close()
+				return fmt.Errorf("%s: %w", op, err)
+			}
+
+			bannerIDs = append(bannerIDs, bannerID)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		err = endPublications(ctx, tx, bannerIDs)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		err = insertAuditEntry(ctx, tx, entry)
 		if err != nil {
 			return fmt.Errorf("%s: %w", op, err)
 		}
@@ -445,6 +978,316 @@ func (s *Storage) DeleteUserBannerByFeatureTagStorage(ctx context.Context, tagID
 	return nil
 }
 
+// RollbackBannerStorage makes the revision that was live steps publications
+// ago the chosen one again and returns its ID. The publications in between
+// are marked as rolled back.
+func (s *Storage) RollbackBannerStorage(ctx context.Context, tenantID int64, bannerID int, steps int, entry *models.AuditEntry) (_ int, err error) {
+	const op = "storage.postgresql.RollbackBannerStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	query, args, err := sq.Select("banner_id").
+		From("banners").
+		Where(sq.Eq{"banner_id": bannerID, "tenant_id": tenantID}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var lockedBannerID int64
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&lockedBannerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return -1, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+	}
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	before, err := bannerSnapshot(ctx, tx, tenantID, bannerID)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err = sq.Select("bp.id", "bp.revision_id", "br.feature_id", "br.targeting IS NOT NULL", "COALESCE(ARRAY_TO_STRING(ARRAY_AGG(rt.tag_id), ', '), '') AS tag_ids").
+		From("banner_publications bp").
+		Join("banner_revisions br ON bp.revision_id = br.revision_id").
+		LeftJoin("revision_tags rt ON br.revision_id = rt.revision_id").
+		Where(sq.Eq{"bp.banner_id": bannerID, "bp.rolled_back_at": nil}).
+		GroupBy("bp.id", "br.revision_id").
+		OrderBy("bp.id DESC").
+		Limit(uint64(steps) + 1).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	type publication struct {
+		id         int64
+		revisionID int64
+		featureID  int64
+		targeted   bool
+		tagIDs     string
+	}
+
+	var publications []publication
+	for rows.Next() {
+		var p publication
+		if err = rows.Scan(&p.id, &p.revisionID, &p.featureID, &p.targeted, &p.tagIDs); err != nil {
+			rows.Close()
+			return -1, fmt.Errorf("%s: %w", op, err)
+		}
+
+		publications = append(publications, p)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(publications) <= steps {
+		err = storage.ErrNothingToRollBack
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	target := publications[steps]
+
+	var tagIDs []int64
+	if target.tagIDs != "" {
+		tagIDs, err = parseTagIDs(target.tagIDs)
+		if err != nil {
+			return -1, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	err = s.claimFeatureTags(ctx, tx, tenantID, int64(bannerID), target.featureID, tagIDs, target.targeted)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err = sq.Update("banners").
+		Set("chosen_revision_id", target.revisionID).
+		Where(sq.Eq{"banner_id": bannerID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = endRollout(ctx, tx, int64(bannerID))
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	undone := make([]int64, 0, steps)
+	for _, p := range publications[:steps] {
+		undone = append(undone, p.id)
+	}
+
+	query, args, err = sq.Update("banner_publications").
+		Set("rolled_back_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": undone}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = auditBanner(ctx, tx, tenantID, int64(bannerID), before, entry)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return int(target.revisionID), nil
+}
+
+// endPublications marks the publication history of the deleted banners as
+// ended, so that lookups of the past stop finding them from now on.
+func endPublications(ctx context.Context, tx *sql.Tx, bannerIDs []int64) error {
+	const op = "storage.postgresql.endPublications"
+
+	if len(bannerIDs) == 0 {
+		return nil
+	}
+
+	query, args, err := sq.Update("banner_publications").
+		Set("banner_deleted_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"banner_id": bannerIDs, "banner_deleted_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// recordPublication adds the revision that has just been chosen for the
+// banner to its publication history and ends the rollout of the banner. The
+// publication keeps the feature and tags of the revision, so that it still
+// tells what was live once the banner is deleted.
+func recordPublication(ctx context.Context, tx *sql.Tx, tenantID int64, bannerID int64, revisionID int64, publishedBy *int64) error {
+	const op = "storage.postgresql.recordPublication"
+
+	query, args, err := sq.Insert("banner_publications").
+		Columns("tenant_id", "banner_id", "revision_id", "feature_id", "tag_ids", "targeted", "published_by").
+		Values(
+			tenantID,
+			bannerID,
+			revisionID,
+			sq.Expr("(SELECT feature_id FROM banner_revisions WHERE revision_id = ?)", revisionID),
+			sq.Expr("ARRAY(SELECT tag_id FROM revision_tags WHERE revision_id = ? ORDER BY tag_id)", revisionID),
+			sq.Expr("(SELECT targeting IS NOT NULL FROM banner_revisions WHERE revision_id = ?)", revisionID),
+			publishedBy,
+		).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = endRollout(ctx, tx, bannerID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// claimFeatureTags makes the feature and tags of the chosen revision of the
+// banner its own in banner_feature_tags. It returns a
+// storage.BannerConflictError if another banner of the tenant already has
+// the feature and one of the tags. Targeted revisions share their feature
+// and tags with other banners and claim none.
+func (s *Storage) claimFeatureTags(ctx context.Context, tx *sql.Tx, tenantID int64, bannerID int64, featureID int64, tagIDs []int64, targeted bool) error {
+	const op = "storage.postgresql.claimFeatureTags"
+
+	query, args, err := sq.Delete("banner_feature_tags").
+		Where(sq.Eq{"banner_id": bannerID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(tagIDs) == 0 || targeted {
+		return nil
+	}
+
+	err = findFeatureTagConflict(ctx, tx, tenantID, featureID, tagIDs)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	insert := sq.Insert("banner_feature_tags").
+		Columns("tenant_id", "feature_id", "tag_id", "banner_id")
+
+	for _, tagID := range tagIDs {
+		insert = insert.Values(tenantID, featureID, tagID, bannerID)
+	}
+
+	query, args, err = insert.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if pgErrorCode(err) == pgUniqueViolation {
+		// A concurrent transaction claimed the pair after the check above.
+		// It has committed by now, so it is visible outside of tx.
+		if conflictErr := findFeatureTagConflict(ctx, s.db, tenantID, featureID, tagIDs); conflictErr != nil {
+			return fmt.Errorf("%s: %w", op, conflictErr)
+		}
+		return fmt.Errorf("%s: %w", op, storage.ErrBannerConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// rowQuerier is implemented by both *sql.DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	rowQuerier
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// findFeatureTagConflict returns a storage.BannerConflictError naming a
+// banner of the tenant that has the feature and one of the tags.
+func findFeatureTagConflict(ctx context.Context, q rowQuerier, tenantID int64, featureID int64, tagIDs []int64) error {
+	const op = "storage.postgresql.findFeatureTagConflict"
+
+	query, args, err := sq.Select("banner_id", "tag_id").
+		From("banner_feature_tags").
+		Where(sq.Eq{"tenant_id": tenantID, "feature_id": featureID, "tag_id": tagIDs}).
+		OrderBy("tag_id").
+		Limit(1).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	conflict := storage.BannerConflictError{FeatureID: featureID}
+	err = q.QueryRowContext(ctx, query, args...).Scan(&conflict.BannerID, &conflict.TagID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &conflict
+}
+
 func parseTagIDs(tagIDsStr string) ([]int64, error) {
 	var tagIDs []int64
 	tags := strings.Split(tagIDsStr, ",")
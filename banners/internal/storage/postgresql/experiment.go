@@ -0,0 +1,398 @@
+package postgresql
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"strconv"
+)
+
+var experimentColumns = []string{"id", "feature_id", "tag_id", "name", "status", "created_by", "created_at", "stopped_at"}
+
+// CreateExperimentStorage starts the experiment with its variants. Only one
+// experiment may run for a feature and tag.
+func (s *Storage) CreateExperimentStorage(ctx context.Context, tenantID int64, experiment *models.Experiment, entry *models.AuditEntry) (_ *models.Experiment, err error) {
+	const op = "storage.postgresql.CreateExperimentStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	// Variants are served without going through an approval, so features
+	// that only publish approved revisions have no experiments.
+	requiresApproval, err := featureRequiresApproval(ctx, tx, tenantID, experiment.FeatureID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if requiresApproval {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrApprovalRequired)
+	}
+
+	query, args, err := sq.Insert("experiments").
+		Columns("tenant_id", "feature_id", "tag_id", "name", "created_by").
+		Values(tenantID, experiment.FeatureID, experiment.TagID, experiment.Name, experiment.CreatedBy).
+		Suffix("RETURNING id, status, created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	created := *experiment
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&created.ID, &created.Status, &created.CreatedAt)
+	if pgErrorCode(err) == pgUniqueViolation {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrExperimentRunning)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	variantsInsert := sq.Insert("experiment_variants").
+		Columns("experiment_id", "key", "weight", "content")
+
+	for _, variant := range experiment.Variants {
+		variantsInsert = variantsInsert.Values(created.ID, variant.Key, variant.Weight, []byte(variant.Content))
+	}
+
+	query, args, err = variantsInsert.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if entry != nil {
+		entry.Target = strconv.FormatInt(created.ID, 10)
+		entry.After = models.Snapshot(created)
+	}
+
+	err = insertAuditEntry(ctx, tx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &created, nil
+}
+
+func (s *Storage) GetExperimentStorage(ctx context.Context, tenantID int64, id int64) (*models.Experiment, error) {
+	const op = "storage.postgresql.GetExperimentStorage"
+
+	experiment, err := getExperiment(ctx, s.db, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return experiment, nil
+}
+
+// getExperiment is GetExperimentStorage on q, so that the experiment can be
+// read in a transaction.
+func getExperiment(ctx context.Context, q querier, tenantID int64, id int64) (*models.Experiment, error) {
+	const op = "storage.postgresql.getExperiment"
+
+	query, args, err := sq.Select(experimentColumns...).
+		From("experiments").
+		Where(sq.Eq{"id": id, "tenant_id": tenantID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	experiment, err := scanExperiment(q.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrExperimentNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = fillVariants(ctx, q, []*models.Experiment{experiment}, true)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return experiment, nil
+}
+
+// ListExperimentsStorage lists the experiments of the tenant, newest first.
+// A zero featureID or tagID does not filter on it.
+func (s *Storage) ListExperimentsStorage(ctx context.Context, tenantID int64, featureID int64, tagID int64, limit int, offset int) ([]models.Experiment, error) {
+	const op = "storage.postgresql.ListExperimentsStorage"
+
+	selectBuilder := sq.Select(experimentColumns...).
+		From("experiments").
+		Where(sq.Eq{"tenant_id": tenantID})
+
+	if featureID != 0 {
+		selectBuilder = selectBuilder.Where(sq.Eq{"feature_id": featureID})
+	}
+
+	if tagID != 0 {
+		selectBuilder = selectBuilder.Where(sq.Eq{"tag_id": tagID})
+	}
+
+	query, args, err := selectBuilder.
+		OrderBy("id DESC").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var found []*models.Experiment
+	for rows.Next() {
+		experiment, err := scanExperiment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		found = append(found, experiment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = fillVariants(ctx, s.db, found, true)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	experiments := make([]models.Experiment, 0, len(found))
+	for _, experiment := range found {
+		experiments = append(experiments, *experiment)
+	}
+
+	return experiments, nil
+}
+
+// RunningExperimentStorage returns the experiment running for the feature
+// and tag. Its variants come without their content, which is fetched per
+// variant with VariantContentStorage.
+func (s *Storage) RunningExperimentStorage(ctx context.Context, tenantID int64, featureID int64, tagID int64) (*models.Experiment, error) {
+	const op = "storage.postgresql.RunningExperimentStorage"
+
+	query, args, err := sq.Select(experimentColumns...).
+		From("experiments").
+		Where(sq.Eq{"tenant_id": tenantID, "feature_id": featureID, "tag_id": tagID, "status": models.ExperimentRunning}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	experiment, err := scanExperiment(s.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrExperimentNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = fillVariants(ctx, s.db, []*models.Experiment{experiment}, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return experiment, nil
+}
+
+func (s *Storage) VariantContentStorage(ctx context.Context, tenantID int64, experimentID int64, key string) (json.RawMessage, error) {
+	const op = "storage.postgresql.VariantContentStorage"
+
+	query, args, err := sq.Select("ev.content").
+		From("experiment_variants ev").
+		Join("experiments e ON ev.experiment_id = e.id").
+		Where(sq.Eq{"ev.experiment_id": experimentID, "ev.key": key, "e.tenant_id": tenantID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var content []byte
+	err = s.db.QueryRowContext(ctx, query, args...).Scan(&content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrExperimentNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return content, nil
+}
+
+// StopExperimentStorage stops a running experiment and returns it stopped.
+// Stopped experiments are kept for their record.
+func (s *Storage) StopExperimentStorage(ctx context.Context, tenantID int64, id int64, entry *models.AuditEntry) (_ *models.Experiment, err error) {
+	const op = "storage.postgresql.StopExperimentStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	before, err := getExperiment(ctx, tx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err := sq.Update("experiments").
+		Set("status", models.ExperimentStopped).
+		Set("stopped_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": id, "tenant_id": tenantID, "status": models.ExperimentRunning}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrExperimentStopped)
+	}
+
+	after, err := getExperiment(ctx, tx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if entry != nil {
+		entry.Target = strconv.FormatInt(id, 10)
+		entry.Before = models.Snapshot(before)
+		entry.After = models.Snapshot(after)
+	}
+
+	err = insertAuditEntry(ctx, tx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return after, nil
+}
+
+// fillVariants loads the variants of the experiments, ordered by key, with
+// their content only if withContent is set.
+func fillVariants(ctx context.Context, q querier, experiments []*models.Experiment, withContent bool) error {
+	const op = "storage.postgresql.fillVariants"
+
+	if len(experiments) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*models.Experiment, len(experiments))
+	ids := make([]int64, 0, len(experiments))
+	for _, experiment := range experiments {
+		experiment.Variants = []models.Variant{}
+		byID[experiment.ID] = experiment
+		ids = append(ids, experiment.ID)
+	}
+
+	columns := []string{"experiment_id", "key", "weight"}
+	if withContent {
+		columns = append(columns, "content")
+	}
+
+	query, args, err := sq.Select(columns...).
+		From("experiment_variants").
+		Where(sq.Eq{"experiment_id": ids}).
+		OrderBy("experiment_id", "key").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var experimentID int64
+		var variant models.Variant
+		var content []byte
+
+		dest := []any{&experimentID, &variant.Key, &variant.Weight}
+		if withContent {
+			dest = append(dest, &content)
+		}
+
+		err = rows.Scan(dest...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if content != nil {
+			variant.Content = content
+		}
+
+		experiment := byID[experimentID]
+		experiment.Variants = append(experiment.Variants, variant)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func scanExperiment(row rowScanner) (*models.Experiment, error) {
+	experiment := &models.Experiment{}
+
+	err := row.Scan(
+		&experiment.ID,
+		&experiment.FeatureID,
+		&experiment.TagID,
+		&experiment.Name,
+		&experiment.Status,
+		&experiment.CreatedBy,
+		&experiment.CreatedAt,
+		&experiment.StoppedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return experiment, nil
+}
@@ -0,0 +1,274 @@
+package postgresql
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"strconv"
+	"time"
+)
+
+// SetDefaultBannerStorage makes the banner the default of the feature. The
+// chosen revision of the banner must be on the feature. entry records the
+// previous default, if any, and the new one.
+func (s *Storage) SetDefaultBannerStorage(ctx context.Context, tenantID int64, featureDefault *models.FeatureDefault, entry *models.AuditEntry) (err error) {
+	const op = "storage.postgresql.SetDefaultBannerStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	query, args, err := sq.Select("br.feature_id").
+		From("banners b").
+		LeftJoin("banner_revisions br ON b.chosen_revision_id = br.revision_id").
+		Where(sq.Eq{"b.banner_id": featureDefault.BannerID, "b.tenant_id": tenantID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var featureID *int64
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&featureID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if featureID == nil || *featureID != featureDefault.FeatureID {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotOnFeature)
+	}
+
+	before, err := getDefaultBannerID(ctx, tx, tenantID, featureDefault.FeatureID)
+	if err != nil && !errors.Is(err, storage.ErrDefaultNotFound) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err = sq.Insert("feature_defaults").
+		Columns("tenant_id", "feature_id", "banner_id").
+		Values(tenantID, featureDefault.FeatureID, featureDefault.BannerID).
+		Suffix("ON CONFLICT (tenant_id, feature_id) DO UPDATE SET banner_id = EXCLUDED.banner_id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = recordDefaultChange(ctx, tx, tenantID, featureDefault.FeatureID, &featureDefault.BannerID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if entry != nil {
+		entry.BannerID = &featureDefault.BannerID
+		entry.Target = strconv.FormatInt(featureDefault.FeatureID, 10)
+		entry.After = models.Snapshot(featureDefault)
+		if before != 0 {
+			entry.Before = models.Snapshot(&models.FeatureDefault{FeatureID: featureDefault.FeatureID, BannerID: before})
+		}
+	}
+
+	err = insertAuditEntry(ctx, tx, entry)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetDefaultBannerIDStorage(ctx context.Context, tenantID int64, featureID int64) (int64, error) {
+	const op = "storage.postgresql.GetDefaultBannerIDStorage"
+
+	bannerID, err := getDefaultBannerID(ctx, s.db, tenantID, featureID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bannerID, nil
+}
+
+// getDefaultBannerID is GetDefaultBannerIDStorage on q, so that the default
+// can be read in a transaction.
+func getDefaultBannerID(ctx context.Context, q rowQuerier, tenantID int64, featureID int64) (int64, error) {
+	const op = "storage.postgresql.getDefaultBannerID"
+
+	query, args, err := sq.Select("banner_id").
+		From("feature_defaults").
+		Where(sq.Eq{"tenant_id": tenantID, "feature_id": featureID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var bannerID int64
+	err = q.QueryRowContext(ctx, query, args...).Scan(&bannerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrDefaultNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bannerID, nil
+}
+
+func (s *Storage) DeleteDefaultBannerStorage(ctx context.Context, tenantID int64, featureID int64, entry *models.AuditEntry) (err error) {
+	const op = "storage.postgresql.DeleteDefaultBannerStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	before, err := getDefaultBannerID(ctx, tx, tenantID, featureID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err := sq.Delete("feature_defaults").
+		Where(sq.Eq{"tenant_id": tenantID, "feature_id": featureID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = recordDefaultChange(ctx, tx, tenantID, featureID, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if entry != nil {
+		entry.BannerID = &before
+		entry.Target = strconv.FormatInt(featureID, 10)
+		entry.Before = models.Snapshot(&models.FeatureDefault{FeatureID: featureID, BannerID: before})
+	}
+
+	err = insertAuditEntry(ctx, tx, entry)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetDefaultBannerStorage returns the live revision of the default banner
+// of the feature, storage.ErrBannerNotFound if there is none.
+func (s *Storage) GetDefaultBannerStorage(ctx context.Context, tenantID int64, featureID int) (*models.Banner, error) {
+	const op = "storage.postgresql.GetDefaultBannerStorage"
+
+	selectBuilder := sq.Select("br.content", "br.is_active", "br.starts_at", "br.ends_at", "br.targeting").
+		From("feature_defaults fd").
+		Join("banners b ON fd.banner_id = b.banner_id").
+		Join("banner_revisions br ON b.chosen_revision_id = br.revision_id")
+
+	query, args, err := withRollout(selectBuilder).
+		Where(sq.Eq{"fd.tenant_id": tenantID, "fd.feature_id": featureID}).
+		Where("br.feature_id = fd.feature_id").
+		Where(liveNow).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	banner := models.Banner{Fallback: true}
+	var targeting []byte
+	var rollout rolloutScan
+	err = s.db.QueryRowContext(ctx, query, args...).Scan(append([]any{&banner.Content, &banner.IsActive, &banner.StartsAt, &banner.EndsAt, &targeting}, rollout.dest()...)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	banner.Targeting, err = parseTargeting(targeting)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	banner.Rollout, err = rollout.rollout()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &banner, nil
+}
+
+// GetDefaultBannerAsOfStorage returns the revision of the default of the
+// feature that was live at the given time, storage.ErrBannerNotFound if the
+// feature had no default then or its revision was not on the feature.
+func (s *Storage) GetDefaultBannerAsOfStorage(ctx context.Context, tenantID int64, featureID int, at time.Time) (*models.LiveBanner, error) {
+	const op = "storage.postgresql.GetDefaultBannerAsOfStorage"
+
+	query, args, err := livePublications(at, "NULL::INT").
+		Where(sq.Eq{"bp.tenant_id": tenantID, "bp.feature_id": featureID}).
+		Where(sq.Expr("bp.banner_id = (SELECT c.banner_id FROM feature_default_changes c WHERE c.tenant_id = ? AND c.feature_id = ? AND c.changed_at <= ? ORDER BY c.id DESC LIMIT 1)", tenantID, featureID, at)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	banner, err := scanLiveBanner(s.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	banner.Fallback = true
+
+	return banner, nil
+}
+
+// recordDefaultChange adds the new default of the feature, nil once it is
+// removed, to the history of its defaults.
+func recordDefaultChange(ctx context.Context, tx *sql.Tx, tenantID int64, featureID int64, bannerID *int64) error {
+	const op = "storage.postgresql.recordDefaultChange"
+
+	query, args, err := sq.Insert("feature_default_changes").
+		Columns("tenant_id", "feature_id", "banner_id").
+		Values(tenantID, featureID, bannerID).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
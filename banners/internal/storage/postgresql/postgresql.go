@@ -3,11 +3,18 @@ package postgresql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"log"
 )
 
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
 type Storage struct {
 	db         *sql.DB
 	workerPool *WorkerPool
@@ -42,6 +49,28 @@ func (s *Storage) Close() error {
 	return nil
 }
 
+// pgErrorCode returns the SQLSTATE code of a postgres error, or "" if err
+// did not come from postgres.
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+
+	return ""
+}
+
+// pgConstraintName returns the constraint a postgres error is about, or ""
+// if there is none.
+func pgConstraintName(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.ConstraintName
+	}
+
+	return ""
+}
+
 type WorkerPool struct {
 	workerCount int
 	tasks       chan func() error
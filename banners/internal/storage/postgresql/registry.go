@@ -0,0 +1,540 @@
+package postgresql
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"strconv"
+	"strings"
+)
+
+// The tables of the registries are named after them. registryUsage finds
+// the revisions that refer to an entry.
+var registryUsage = map[string]string{
+	models.RegistryFeatures: "SELECT 1 FROM banner_revisions br WHERE br.tenant_id = ? AND br.feature_id = ?",
+	models.RegistryTags:     "SELECT 1 FROM revision_tags rt JOIN banner_revisions br ON rt.revision_id = br.revision_id WHERE br.tenant_id = ? AND rt.tag_id = ?",
+}
+
+// maxTagDepth bounds the walks through the tag hierarchy.
+const maxTagDepth = 32
+
+// tagAncestors lists a tag and its ancestors with their distance from it.
+// It takes the tag, the tenant and maxTagDepth.
+const tagAncestors = "WITH RECURSIVE ancestors(tag_id, depth) AS (" +
+	"SELECT ?::INT, 0 " +
+	"UNION ALL " +
+	"SELECT t.parent_id, a.depth + 1 FROM tags t JOIN ancestors a ON t.id = a.tag_id " +
+	"WHERE t.tenant_id = ? AND t.parent_id IS NOT NULL AND a.depth < ?)"
+
+// tagDescendants lists the tags and all the tags nested in them. It takes
+// an array of tags, the tenant and maxTagDepth.
+const tagDescendants = "WITH RECURSIVE descendants(tag_id, depth) AS (" +
+	"SELECT UNNEST(?::INT[]), 0 " +
+	"UNION ALL " +
+	"SELECT t.id, d.depth + 1 FROM tags t JOIN descendants d ON t.parent_id = d.tag_id " +
+	"WHERE t.tenant_id = ? AND d.depth < ?)"
+
+// registryColumns are the columns of an entry. Features have no parents.
+func registryColumns(registry string) []string {
+	parent := "parent_id"
+	if registry != models.RegistryTags {
+		parent = "NULL::INT AS parent_id"
+	}
+
+	return []string{"id", "name", "description", "owner_team", "archived", parent, "created_at"}
+}
+
+func registryTable(registry string) (string, error) {
+	if _, ok := registryUsage[registry]; !ok {
+		return "", fmt.Errorf("unknown registry %q", registry)
+	}
+
+	return registry, nil
+}
+
+func (s *Storage) CreateRegistryEntryStorage(ctx context.Context, tenantID int64, registry string, entry *models.RegistryEntry, audit *models.AuditEntry) (_ *models.RegistryEntry, err error) {
+	const op = "storage.postgresql.CreateRegistryEntryStorage"
+
+	table, err := registryTable(registry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	insertBuilder := sq.Insert(table).
+		Columns("tenant_id", "id", "name", "description", "owner_team", "archived").
+		Values(tenantID, entry.ID, entry.Name, entry.Description, entry.OwnerTeam, entry.Archived)
+
+	if registry == models.RegistryTags {
+		insertBuilder = sq.Insert(table).
+			Columns("tenant_id", "id", "name", "description", "owner_team", "archived", "parent_id").
+			Values(tenantID, entry.ID, entry.Name, entry.Description, entry.OwnerTeam, entry.Archived, entry.ParentID)
+	}
+
+	query, args, err := insertBuilder.
+		Suffix("RETURNING " + strings.Join(registryColumns(registry), ", ")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	created, err := scanRegistryEntry(tx.QueryRowContext(ctx, query, args...))
+	if pgErrorCode(err) == pgUniqueViolation {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRegistryExists)
+	}
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrParentNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = auditRegistry(ctx, tx, registry, created.ID, nil, created, audit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+func (s *Storage) GetRegistryEntryStorage(ctx context.Context, tenantID int64, registry string, id int64) (*models.RegistryEntry, error) {
+	const op = "storage.postgresql.GetRegistryEntryStorage"
+
+	entry, err := getRegistryEntry(ctx, s.db, tenantID, registry, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entry, nil
+}
+
+// getRegistryEntry is GetRegistryEntryStorage on q, so that the entry can be
+// read in a transaction.
+func getRegistryEntry(ctx context.Context, q rowQuerier, tenantID int64, registry string, id int64) (*models.RegistryEntry, error) {
+	const op = "storage.postgresql.getRegistryEntry"
+
+	table, err := registryTable(registry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err := sq.Select(registryColumns(registry)...).
+		From(table).
+		Where(sq.Eq{"tenant_id": tenantID, "id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	entry, err := scanRegistryEntry(q.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRegistryNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entry, nil
+}
+
+func (s *Storage) ListRegistryEntriesStorage(ctx context.Context, tenantID int64, registry string, includeArchived bool, limit int, offset int) ([]models.RegistryEntry, error) {
+	const op = "storage.postgresql.ListRegistryEntriesStorage"
+
+	table, err := registryTable(registry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	selectBuilder := sq.Select(registryColumns(registry)...).
+		From(table).
+		Where(sq.Eq{"tenant_id": tenantID}).
+		OrderBy("id").
+		Limit(uint64(limit)).
+		Offset(uint64(offset))
+
+	if !includeArchived {
+		selectBuilder = selectBuilder.Where(sq.Eq{"archived": false})
+	}
+
+	query, args, err := selectBuilder.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	entries := []models.RegistryEntry{}
+	for rows.Next() {
+		entry, err := scanRegistryEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		entries = append(entries, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+func (s *Storage) UpdateRegistryEntryStorage(ctx context.Context, tenantID int64, registry string, id int64, update *models.RegistryUpdate, audit *models.AuditEntry) (_ *models.RegistryEntry, err error) {
+	const op = "storage.postgresql.UpdateRegistryEntryStorage"
+
+	table, err := registryTable(registry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	before, err := getRegistryEntry(ctx, tx, tenantID, registry, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	updateBuilder := sq.Update(table).
+		Where(sq.Eq{"tenant_id": tenantID, "id": id}).
+		Suffix("RETURNING " + strings.Join(registryColumns(registry), ", "))
+
+	if update.Name != nil {
+		updateBuilder = updateBuilder.Set("name", *update.Name)
+	}
+
+	if update.Description != nil {
+		updateBuilder = updateBuilder.Set("description", *update.Description)
+	}
+
+	if update.OwnerTeam != nil {
+		updateBuilder = updateBuilder.Set("owner_team", *update.OwnerTeam)
+	}
+
+	if update.Archived != nil {
+		updateBuilder = updateBuilder.Set("archived", *update.Archived)
+	}
+
+	if update.ParentID != nil && registry == models.RegistryTags {
+		var parentID *int64
+		if *update.ParentID != 0 {
+			parentID = update.ParentID
+		}
+
+		updateBuilder = updateBuilder.Set("parent_id", parentID)
+	}
+
+	query, args, err := updateBuilder.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	entry, err := scanRegistryEntry(tx.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRegistryNotFound)
+	}
+	if pgErrorCode(err) == pgUniqueViolation {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRegistryExists)
+	}
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrParentNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = auditRegistry(ctx, tx, registry, id, before, entry, audit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entry, nil
+}
+
+// DeleteRegistryEntryStorage deletes an entry that no revision refers to.
+// Entries in use can only be archived.
+func (s *Storage) DeleteRegistryEntryStorage(ctx context.Context, tenantID int64, registry string, id int64, audit *models.AuditEntry) (err error) {
+	const op = "storage.postgresql.DeleteRegistryEntryStorage"
+
+	table, err := registryTable(registry)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	before, err := getRegistryEntry(ctx, tx, tenantID, registry, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err := sq.Delete(table).
+		Where(sq.Eq{"tenant_id": tenantID, "id": id}).
+		Where(sq.Expr("NOT EXISTS ("+registryUsage[registry]+")", tenantID, id)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRegistryInUse)
+	}
+
+	err = auditRegistry(ctx, tx, registry, id, before, nil, audit)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// auditRegistry records a change of the entry of the registry in tx.
+func auditRegistry(ctx context.Context, tx *sql.Tx, registry string, id int64, before, after *models.RegistryEntry, audit *models.AuditEntry) error {
+	if audit == nil {
+		return nil
+	}
+
+	audit.Target = registry + "/" + strconv.FormatInt(id, 10)
+	if before != nil {
+		audit.Before = models.Snapshot(before)
+	}
+	if after != nil {
+		audit.After = models.Snapshot(after)
+	}
+
+	return insertAuditEntry(ctx, tx, audit)
+}
+
+// UnregisteredIDsStorage returns the IDs that are not registered or are
+// archived. Nothing is returned while the registry of the tenant is empty,
+// so tenants that have not started using it are not held up.
+func (s *Storage) UnregisteredIDsStorage(ctx context.Context, tenantID int64, registry string, ids []int64) ([]int64, error) {
+	const op = "storage.postgresql.UnregisteredIDsStorage"
+
+	table, err := registryTable(registry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err := sq.Select("COUNT(*) > 0").
+		From(table).
+		Where(sq.Eq{"tenant_id": tenantID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var inUse bool
+	err = s.db.QueryRowContext(ctx, query, args...).Scan(&inUse)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !inUse {
+		return nil, nil
+	}
+
+	entries, err := s.registryEntries(ctx, registry, tenantID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var unregistered []int64
+	for _, id := range ids {
+		if entry, ok := entries[id]; !ok || entry.Archived {
+			unregistered = append(unregistered, id)
+		}
+	}
+
+	return unregistered, nil
+}
+
+// RegistryNamesStorage returns the names of the registered IDs, archived
+// ones included.
+func (s *Storage) RegistryNamesStorage(ctx context.Context, tenantID int64, registry string, ids []int64) (map[int64]string, error) {
+	const op = "storage.postgresql.RegistryNamesStorage"
+
+	entries, err := s.registryEntries(ctx, registry, tenantID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	names := make(map[int64]string, len(entries))
+	for id, entry := range entries {
+		names[id] = entry.Name
+	}
+
+	return names, nil
+}
+
+func (s *Storage) registryEntries(ctx context.Context, registry string, tenantID int64, ids []int64) (map[int64]models.RegistryEntry, error) {
+	const op = "storage.postgresql.registryEntries"
+
+	table, err := registryTable(registry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	entries := map[int64]models.RegistryEntry{}
+	if len(ids) == 0 {
+		return entries, nil
+	}
+
+	query, args, err := sq.Select(registryColumns(registry)...).
+		From(table).
+		Where(sq.Eq{"tenant_id": tenantID, "id": ids}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanRegistryEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		entries[entry.ID] = *entry
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+// TagAncestorsStorage returns the tag followed by its ancestors, nearest
+// first.
+func (s *Storage) TagAncestorsStorage(ctx context.Context, tenantID int64, tagID int64) ([]int64, error) {
+	const op = "storage.postgresql.TagAncestorsStorage"
+
+	query, args, err := sq.Select("tag_id").
+		Prefix(tagAncestors, tagID, tenantID, maxTagDepth).
+		From("ancestors").
+		OrderBy("depth").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tagIDs, err := s.queryTagIDs(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tagIDs, nil
+}
+
+// TagDescendantsStorage returns the tags together with all the tags nested
+// in them.
+func (s *Storage) TagDescendantsStorage(ctx context.Context, tenantID int64, tagIDs []int64) ([]int64, error) {
+	const op = "storage.postgresql.TagDescendantsStorage"
+
+	if len(tagIDs) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := sq.Select("DISTINCT tag_id").
+		Prefix(tagDescendants, tagIDs, tenantID, maxTagDepth).
+		From("descendants").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	descendants, err := s.queryTagIDs(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return descendants, nil
+}
+
+func (s *Storage) queryTagIDs(ctx context.Context, query string, args []any) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tagIDs []int64
+	for rows.Next() {
+		var tagID int64
+		if err := rows.Scan(&tagID); err != nil {
+			return nil, err
+		}
+
+		tagIDs = append(tagIDs, tagID)
+	}
+
+	return tagIDs, rows.Err()
+}
+
+func scanRegistryEntry(row rowScanner) (*models.RegistryEntry, error) {
+	var entry models.RegistryEntry
+	err := row.Scan(&entry.ID, &entry.Name, &entry.Description, &entry.OwnerTeam, &entry.Archived, &entry.ParentID, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
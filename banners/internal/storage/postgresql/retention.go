@@ -0,0 +1,105 @@
+package postgresql
+
+import (
+	"banners/domain/models"
+	"context"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+)
+
+// prunableRevisions selects the revisions the policy would delete, of a
+// single tenant or of all of them if tenantID is nil. Revisions that are
+// waiting for an approval, approved but not published yet, or being rolled
+// out are kept like the chosen ones, and so are the revisions that were
+// ever published, which the publication history refers to.
+func prunableRevisions(policy models.RetentionPolicy, tenantID *int64, columns ...string) sq.SelectBuilder {
+	selectBuilder := sq.Select(columns...).
+		From("banner_revisions br").
+		Join("banners b ON br.banner_id = b.banner_id").
+		Where("b.chosen_revision_id IS DISTINCT FROM br.revision_id").
+		Where("NOT EXISTS (SELECT 1 FROM banner_publications bp WHERE bp.revision_id = br.revision_id)").
+		Where(sq.Expr("NOT EXISTS (SELECT 1 FROM approval_requests ar WHERE ar.revision_id = br.revision_id AND ar.status = ?)", models.ApprovalPending)).
+		Where(sq.Expr("NOT (br.published_at IS NULL AND EXISTS (SELECT 1 FROM approval_requests ar WHERE ar.revision_id = br.revision_id AND ar.status = ?))", models.ApprovalApproved)).
+		// Deleting the revision would end the rollout through the cascade.
+		Where("NOT EXISTS (SELECT 1 FROM banner_rollouts ro WHERE ro.revision_id = br.revision_id)")
+
+	if policy.KeepLast > 0 {
+		selectBuilder = selectBuilder.Where(sq.Expr("br.revision_id NOT IN (SELECT revision_id FROM (SELECT revision_id, ROW_NUMBER() OVER (PARTITION BY banner_id ORDER BY revision_id DESC) AS rn FROM banner_revisions) ranked WHERE rn <= ?)", policy.KeepLast))
+	}
+
+	if policy.KeepFor > 0 {
+		selectBuilder = selectBuilder.Where(sq.Expr("br.created_at < NOW() - make_interval(secs => ?)", policy.KeepFor.Seconds()))
+	}
+
+	if tenantID != nil {
+		selectBuilder = selectBuilder.Where(sq.Eq{"br.tenant_id": *tenantID})
+	}
+
+	return selectBuilder
+}
+
+func (s *Storage) ListPrunableRevisionsStorage(ctx context.Context, tenantID int64, policy models.RetentionPolicy, limit int, offset int) ([]models.PrunableRevision, error) {
+	const op = "storage.postgresql.ListPrunableRevisionsStorage"
+
+	query, args, err := prunableRevisions(policy, &tenantID, "br.revision_id", "br.banner_id", "br.created_at").
+		OrderBy("br.revision_id").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	revisions := []models.PrunableRevision{}
+	for rows.Next() {
+		var revision models.PrunableRevision
+		if err := rows.Scan(&revision.RevisionID, &revision.BannerID, &revision.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revisions, nil
+}
+
+// PruneRevisionsStorage deletes the revisions of every tenant that the
+// policy does not keep and returns how many were deleted.
+func (s *Storage) PruneRevisionsStorage(ctx context.Context, policy models.RetentionPolicy) (int64, error) {
+	const op = "storage.postgresql.PruneRevisionsStorage"
+
+	// Without any rule every revision but the chosen ones would go.
+	if !policy.Enabled() {
+		return 0, nil
+	}
+
+	query, args, err := sq.Delete("banner_revisions").
+		Where(sq.Expr("revision_id IN (?)", prunableRevisions(policy, nil, "br.revision_id"))).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,289 @@
+package postgresql
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"context"
+	"database/sql"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+)
+
+func (s *Storage) ListRolesStorage(ctx context.Context) ([]models.Role, error) {
+	const op = "storage.postgresql.ListRolesStorage"
+
+	query, args, err := sq.Select("r.name", "rp.permission").
+		From("roles r").
+		LeftJoin("role_permissions rp ON r.name = rp.role").
+		OrderBy("r.name", "rp.permission").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var name string
+		var permission *string
+		if err := rows.Scan(&name, &permission); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if len(roles) == 0 || roles[len(roles)-1].Name != name {
+			roles = append(roles, models.Role{Name: name, Permissions: []string{}})
+		}
+
+		if permission != nil {
+			role := &roles[len(roles)-1]
+			role.Permissions = append(role.Permissions, *permission)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+// UnknownPermissionsStorage returns the permissions that are not in the
+// permissions table.
+func (s *Storage) UnknownPermissionsStorage(ctx context.Context, permissions []string) ([]string, error) {
+	const op = "storage.postgresql.UnknownPermissionsStorage"
+
+	query, args, err := sq.Select("name").
+		From("permissions").
+		Where(sq.Eq{"name": permissions}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	known := make(map[string]bool, len(permissions))
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		known[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var unknown []string
+	for _, permission := range permissions {
+		if !known[permission] {
+			unknown = append(unknown, permission)
+		}
+	}
+
+	return unknown, nil
+}
+
+// SetRolePermissionsStorage creates the role if needed and replaces its
+// permissions. entry records the role before and after.
+func (s *Storage) SetRolePermissionsStorage(ctx context.Context, role string, permissions []string, entry *models.AuditEntry) (err error) {
+	const op = "storage.postgresql.SetRolePermissionsStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	before, err := getRole(ctx, tx, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err := sq.Insert("roles").
+		Columns("name").
+		Values(role).
+		Suffix("ON CONFLICT DO NOTHING").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err = sq.Delete("role_permissions").
+		Where(sq.Eq{"role": role}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(permissions) != 0 {
+		permissionsInsert := sq.Insert("role_permissions").
+			Columns("role", "permission")
+
+		for _, permission := range permissions {
+			permissionsInsert = permissionsInsert.Values(role, permission)
+		}
+
+		query, args, err = permissionsInsert.PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		_, err = tx.ExecContext(ctx, query, args...)
+		if pgErrorCode(err) == pgForeignKeyViolation {
+			return fmt.Errorf("%s: %w", op, storage.ErrPermissionNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	after, err := getRole(ctx, tx, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = auditRole(ctx, tx, role, before, after, entry)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteRoleStorage(ctx context.Context, role string, entry *models.AuditEntry) (err error) {
+	const op = "storage.postgresql.DeleteRoleStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	before, err := getRole(ctx, tx, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if before == nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+	}
+
+	query, args, err := sq.Delete("roles").
+		Where(sq.Eq{"name": role}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return fmt.Errorf("%s: %w", op, storage.ErrRoleInUse)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = auditRole(ctx, tx, role, before, nil, entry)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// getRole returns the role with its permissions as q sees it, or nil if it
+// does not exist.
+func getRole(ctx context.Context, q querier, name string) (*models.Role, error) {
+	const op = "storage.postgresql.getRole"
+
+	query, args, err := sq.Select("rp.permission").
+		From("roles r").
+		LeftJoin("role_permissions rp ON r.name = rp.role").
+		Where(sq.Eq{"r.name": name}).
+		OrderBy("rp.permission").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var role *models.Role
+	for rows.Next() {
+		var permission *string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if role == nil {
+			role = &models.Role{Name: name, Permissions: []string{}}
+		}
+
+		if permission != nil {
+			role.Permissions = append(role.Permissions, *permission)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return role, nil
+}
+
+// auditRole records a change of the role in tx.
+func auditRole(ctx context.Context, tx *sql.Tx, name string, before, after *models.Role, entry *models.AuditEntry) error {
+	if entry == nil {
+		return nil
+	}
+
+	entry.Target = name
+	if before != nil {
+		entry.Before = models.Snapshot(before)
+	}
+	if after != nil {
+		entry.After = models.Snapshot(after)
+	}
+
+	return insertAuditEntry(ctx, tx, entry)
+}
@@ -0,0 +1,367 @@
+package postgresql
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"slices"
+	"strconv"
+	"time"
+)
+
+var rolloutColumns = []string{"banner_id", "revision_id", "percent", "created_by", "created_at", "updated_at"}
+
+// StartRolloutStorage starts serving the revision to a percentage of the
+// users of the banner. The revision must have the feature and tags of the
+// live one, so that it reaches the users of the banner and no others, and
+// it must be approved if the feature requires approval.
+func (s *Storage) StartRolloutStorage(ctx context.Context, tenantID int64, rollout *models.Rollout, entry *models.AuditEntry) (_ *models.Rollout, err error) {
+	const op = "storage.postgresql.StartRolloutStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	query, args, err := sq.Select("chosen_revision_id").
+		From("banners").
+		Where(sq.Eq{"banner_id": rollout.BannerID, "tenant_id": tenantID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var chosenID *int64
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&chosenID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if chosenID != nil && *chosenID == rollout.RevisionID {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRolloutOfLive)
+	}
+
+	featureID, tagIDs, err := revisionTargets(ctx, tx, tenantID, rollout.BannerID, rollout.RevisionID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if chosenID == nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRolloutTargets)
+	}
+
+	liveFeatureID, liveTagIDs, err := revisionTargets(ctx, tx, tenantID, rollout.BannerID, *chosenID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if featureID != liveFeatureID || !slices.Equal(tagIDs, liveTagIDs) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRolloutTargets)
+	}
+
+	err = checkApproval(ctx, tx, tenantID, featureID, rollout.RevisionID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err = sq.Insert("banner_rollouts").
+		Columns("banner_id", "tenant_id", "revision_id", "percent", "created_by").
+		Values(rollout.BannerID, tenantID, rollout.RevisionID, rollout.Percent, rollout.CreatedBy).
+		Suffix("RETURNING created_at, updated_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	created := *rollout
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&created.CreatedAt, &created.UpdatedAt)
+	if pgErrorCode(err) == pgUniqueViolation {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRolloutExists)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = auditRollout(ctx, tx, nil, &created, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &created, nil
+}
+
+func (s *Storage) GetRolloutStorage(ctx context.Context, tenantID int64, bannerID int) (*models.Rollout, error) {
+	const op = "storage.postgresql.GetRolloutStorage"
+
+	rollout, err := getRollout(ctx, s.db, tenantID, bannerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rollout, nil
+}
+
+// getRollout is GetRolloutStorage on q, so that the rollout can be read in a
+// transaction.
+func getRollout(ctx context.Context, q rowQuerier, tenantID int64, bannerID int) (*models.Rollout, error) {
+	const op = "storage.postgresql.getRollout"
+
+	query, args, err := sq.Select(rolloutColumns...).
+		From("banner_rollouts").
+		Where(sq.Eq{"banner_id": bannerID, "tenant_id": tenantID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var rollout models.Rollout
+	err = q.QueryRowContext(ctx, query, args...).Scan(&rollout.BannerID, &rollout.RevisionID, &rollout.Percent, &rollout.CreatedBy, &rollout.CreatedAt, &rollout.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRolloutNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &rollout, nil
+}
+
+// SetRolloutPercentStorage changes the percentage of the rollout and
+// returns the rollout as changed.
+func (s *Storage) SetRolloutPercentStorage(ctx context.Context, tenantID int64, bannerID int, percent int, entry *models.AuditEntry) (_ *models.Rollout, err error) {
+	const op = "storage.postgresql.SetRolloutPercentStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	before, err := getRollout(ctx, tx, tenantID, bannerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err := sq.Update("banner_rollouts").
+		Set("percent", percent).
+		Set("updated_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"banner_id": bannerID, "tenant_id": tenantID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	after, err := getRollout(ctx, tx, tenantID, bannerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = auditRollout(ctx, tx, before, after, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return after, nil
+}
+
+// DeleteRolloutStorage aborts the rollout, the users it reached go back to
+// the live revision.
+func (s *Storage) DeleteRolloutStorage(ctx context.Context, tenantID int64, bannerID int, entry *models.AuditEntry) (err error) {
+	const op = "storage.postgresql.DeleteRolloutStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	before, err := getRollout(ctx, tx, tenantID, bannerID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err := sq.Delete("banner_rollouts").
+		Where(sq.Eq{"banner_id": bannerID, "tenant_id": tenantID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = auditRollout(ctx, tx, before, nil, entry)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// auditRollout records a change of the rollout of a banner in tx.
+func auditRollout(ctx context.Context, tx *sql.Tx, before, after *models.Rollout, entry *models.AuditEntry) error {
+	if entry == nil {
+		return nil
+	}
+
+	if before != nil {
+		entry.BannerID = &before.BannerID
+		entry.Before = models.Snapshot(before)
+	}
+	if after != nil {
+		entry.BannerID = &after.BannerID
+		entry.After = models.Snapshot(after)
+	}
+
+	entry.Target = strconv.FormatInt(*entry.BannerID, 10)
+
+	return insertAuditEntry(ctx, tx, entry)
+}
+
+// endRollout drops the rollout of the banner when another revision is
+// chosen for it. The revision that was rolled out is then either live or
+// superseded.
+func endRollout(ctx context.Context, tx *sql.Tx, bannerID int64) error {
+	const op = "storage.postgresql.endRollout"
+
+	query, args, err := sq.Delete("banner_rollouts").
+		Where(sq.Eq{"banner_id": bannerID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// revisionTargets returns the feature and the sorted tags of the revision.
+func revisionTargets(ctx context.Context, q rowQuerier, tenantID int64, bannerID int64, revisionID int64) (int64, []int64, error) {
+	const op = "storage.postgresql.revisionTargets"
+
+	query, args, err := sq.Select("br.feature_id", "COALESCE(ARRAY_TO_STRING(ARRAY_AGG(rt.tag_id), ', '), '') AS tag_ids").
+		From("banner_revisions br").
+		LeftJoin("revision_tags rt ON br.revision_id = rt.revision_id").
+		Where(sq.Eq{"br.banner_id": bannerID, "br.revision_id": revisionID, "br.tenant_id": tenantID}).
+		GroupBy("br.revision_id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var featureID int64
+	var tagIDsStr string
+	err = q.QueryRowContext(ctx, query, args...).Scan(&featureID, &tagIDsStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil, fmt.Errorf("%s: %w", op, storage.ErrRevisionDoesNotExist)
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var tagIDs []int64
+	if tagIDsStr != "" {
+		tagIDs, err = parseTagIDs(tagIDsStr)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	slices.Sort(tagIDs)
+
+	return featureID, tagIDs, nil
+}
+
+// withRollout adds the rollout of the banner of the revision br, and the
+// revision it rolls out, to a user banner query. The columns are read with
+// rolloutScan.
+func withRollout(selectBuilder sq.SelectBuilder) sq.SelectBuilder {
+	return selectBuilder.
+		Columns("br.banner_id", "ro.revision_id", "ro.percent", "cr.content", "cr.is_active", "cr.starts_at", "cr.ends_at", "cr.targeting").
+		LeftJoin("banner_rollouts ro ON ro.banner_id = br.banner_id").
+		LeftJoin("banner_revisions cr ON ro.revision_id = cr.revision_id")
+}
+
+// rolloutScan receives the columns added by withRollout.
+type rolloutScan struct {
+	bannerID   int64
+	revisionID *int64
+	percent    *int
+	content    []byte
+	isActive   *bool
+	startsAt   *time.Time
+	endsAt     *time.Time
+	targeting  []byte
+}
+
+func (r *rolloutScan) dest() []any {
+	return []any{&r.bannerID, &r.revisionID, &r.percent, &r.content, &r.isActive, &r.startsAt, &r.endsAt, &r.targeting}
+}
+
+// rollout returns the scanned rollout, nil if the banner has none.
+func (r *rolloutScan) rollout() (*models.Rollout, error) {
+	if r.revisionID == nil || r.percent == nil || r.isActive == nil {
+		return nil, nil
+	}
+
+	targeting, err := parseTargeting(r.targeting)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Rollout{
+		BannerID:   r.bannerID,
+		RevisionID: *r.revisionID,
+		Percent:    *r.percent,
+		Candidate: &models.Banner{
+			BannerID:  r.bannerID,
+			Revision:  *r.revisionID,
+			IsActive:  *r.isActive,
+			StartsAt:  r.startsAt,
+			EndsAt:    r.endsAt,
+			Content:   r.content,
+			Targeting: targeting,
+		},
+	}, nil
+}
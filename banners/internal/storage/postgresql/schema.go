@@ -0,0 +1,138 @@
+package postgresql
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"strconv"
+	"strings"
+)
+
+var schemaColumns = []string{"feature_id", "version", "schema", "created_by", "created_at"}
+
+// CreateFeatureSchemaStorage adds the next version of the schema of the
+// feature and returns it.
+func (s *Storage) CreateFeatureSchemaStorage(ctx context.Context, tenantID int64, schema *models.FeatureSchema, entry *models.AuditEntry) (_ *models.FeatureSchema, err error) {
+	const op = "storage.postgresql.CreateFeatureSchemaStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	nextVersion := sq.Expr("(SELECT COALESCE(MAX(version), 0) + 1 FROM feature_schemas WHERE tenant_id = ? AND feature_id = ?)", tenantID, schema.FeatureID)
+
+	query, args, err := sq.Insert("feature_schemas").
+		Columns("tenant_id", "feature_id", "version", "schema", "created_by").
+		Values(tenantID, schema.FeatureID, nextVersion, schema.Schema, schema.CreatedBy).
+		Suffix("RETURNING " + strings.Join(schemaColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	created, err := scanFeatureSchema(tx.QueryRowContext(ctx, query, args...))
+	// Schemas registered at the same time get the same version.
+	if pgErrorCode(err) == pgUniqueViolation {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrSchemaVersionExists)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if entry != nil {
+		entry.Target = strconv.FormatInt(created.FeatureID, 10)
+		entry.After = models.Snapshot(created)
+	}
+
+	err = insertAuditEntry(ctx, tx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+// LatestFeatureSchemaStorage returns the newest version of the schema of the
+// feature, storage.ErrSchemaNotFound if it has none.
+func (s *Storage) LatestFeatureSchemaStorage(ctx context.Context, tenantID int64, featureID int64) (*models.FeatureSchema, error) {
+	const op = "storage.postgresql.LatestFeatureSchemaStorage"
+
+	query, args, err := sq.Select(schemaColumns...).
+		From("feature_schemas").
+		Where(sq.Eq{"tenant_id": tenantID, "feature_id": featureID}).
+		OrderBy("version DESC").
+		Limit(1).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	schema, err := scanFeatureSchema(s.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrSchemaNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return schema, nil
+}
+
+func (s *Storage) ListFeatureSchemasStorage(ctx context.Context, tenantID int64, featureID int64) ([]models.FeatureSchema, error) {
+	const op = "storage.postgresql.ListFeatureSchemasStorage"
+
+	query, args, err := sq.Select(schemaColumns...).
+		From("feature_schemas").
+		Where(sq.Eq{"tenant_id": tenantID, "feature_id": featureID}).
+		OrderBy("version DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	schemas := []models.FeatureSchema{}
+	for rows.Next() {
+		schema, err := scanFeatureSchema(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		schemas = append(schemas, *schema)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return schemas, nil
+}
+
+func scanFeatureSchema(row rowScanner) (*models.FeatureSchema, error) {
+	var schema models.FeatureSchema
+	err := row.Scan(&schema.FeatureID, &schema.Version, &schema.Schema, &schema.CreatedBy, &schema.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schema, nil
+}
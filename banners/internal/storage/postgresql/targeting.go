@@ -0,0 +1,34 @@
+package postgresql
+
+import (
+	"banners/domain/models"
+	"encoding/json"
+)
+
+// targetingValue returns the targeting as it is stored, NULL for none.
+func targetingValue(targeting *models.Targeting) (any, error) {
+	if targeting == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(targeting)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func parseTargeting(data []byte) (*models.Targeting, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	var targeting models.Targeting
+	err := json.Unmarshal(data, &targeting)
+	if err != nil {
+		return nil, err
+	}
+
+	return &targeting, nil
+}
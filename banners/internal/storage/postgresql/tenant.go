@@ -0,0 +1,91 @@
+package postgresql
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"context"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"strconv"
+)
+
+func (s *Storage) CreateTenantStorage(ctx context.Context, name string, entry *models.AuditEntry) (_ *models.Tenant, err error) {
+	const op = "storage.postgresql.CreateTenantStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	query, args, err := sq.Insert("tenants").
+		Columns("name").
+		Values(name).
+		Suffix("RETURNING id, name, created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tenant := &models.Tenant{}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&tenant.ID, &tenant.Name, &tenant.CreatedAt)
+	if pgErrorCode(err) == pgUniqueViolation {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrTenantExists)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if entry != nil {
+		entry.Target = strconv.FormatInt(tenant.ID, 10)
+		entry.After = models.Snapshot(tenant)
+	}
+
+	err = insertAuditEntry(ctx, tx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tenant, nil
+}
+
+func (s *Storage) ListTenantsStorage(ctx context.Context) ([]models.Tenant, error) {
+	const op = "storage.postgresql.ListTenantsStorage"
+
+	query, args, err := sq.Select("id", "name", "created_at").
+		From("tenants").
+		OrderBy("id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	tenants := []models.Tenant{}
+	for rows.Next() {
+		var tenant models.Tenant
+		if err := rows.Scan(&tenant.ID, &tenant.Name, &tenant.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		tenants = append(tenants, tenant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tenants, nil
+}
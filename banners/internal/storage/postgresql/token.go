@@ -0,0 +1,145 @@
+package postgresql
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"time"
+)
+
+func (s *Storage) CreateRefreshTokenStorage(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	const op = "storage.postgresql.CreateRefreshTokenStorage"
+
+	query, args, err := sq.Insert("refresh_tokens").
+		Columns("user_id", "token_hash", "expires_at").
+		Values(userID, tokenHash, expiresAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ConsumeRefreshTokenStorage revokes an active refresh token and returns it.
+// If the token is already revoked, it is returned together with
+// storage.ErrRefreshTokenRevoked so the caller knows whose token was reused.
+// The token is only consumed once the revocation has committed.
+func (s *Storage) ConsumeRefreshTokenStorage(ctx context.Context, tokenHash string) (_ *models.RefreshToken, err error) {
+	const op = "storage.postgresql.ConsumeRefreshTokenStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		if commitErr := tx.Commit(); commitErr != nil {
+			err = fmt.Errorf("%s: %w", op, commitErr)
+		}
+	}()
+
+	query, args, err := sq.Select("id", "user_id", "expires_at", "revoked_at").
+		From("refresh_tokens").
+		Where(sq.Eq{"token_hash": tokenHash}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	token := &models.RefreshToken{}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&token.ID, &token.UserID, &token.ExpiresAt, &token.RevokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if token.RevokedAt != nil {
+		return token, fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenRevoked)
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenExpired)
+	}
+
+	query, args, err = sq.Update("refresh_tokens").
+		Set("revoked_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.Eq{"id": token.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+func (s *Storage) RevokeRefreshTokenStorage(ctx context.Context, userID int64, tokenHash string) error {
+	const op = "storage.postgresql.RevokeRefreshTokenStorage"
+
+	query, args, err := sq.Update("refresh_tokens").
+		Set("revoked_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.Eq{"user_id": userID, "token_hash": tokenHash, "revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeUserRefreshTokensStorage(ctx context.Context, userID int64) error {
+	const op = "storage.postgresql.RevokeUserRefreshTokensStorage"
+
+	query, args, err := sq.Update("refresh_tokens").
+		Set("revoked_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.Eq{"user_id": userID, "revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
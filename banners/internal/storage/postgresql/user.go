@@ -3,6 +3,7 @@ package postgresql
 import (
 	"banners/domain/models"
 	"banners/internal/storage"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -12,7 +13,7 @@ import (
 func (s *Storage) GetUserStorage(email string) (*models.User, error) {
 	const op = "storage.postgresql.GetUserStorage"
 
-	query, args, err := sq.Select("id", "email", "role", "password_hash").
+	query, args, err := sq.Select("id", "email", "role", "password_hash", "disabled", "tenant_id").
 		From("users").
 		Where(sq.Eq{"email": email}).
 		PlaceholderFormat(sq.Dollar).
@@ -24,7 +25,7 @@ func (s *Storage) GetUserStorage(email string) (*models.User, error) {
 	row := s.db.QueryRow(query, args...)
 
 	user := &models.User{}
-	err = row.Scan(&user.ID, &user.Email, &user.Role, &user.Password)
+	err = row.Scan(&user.ID, &user.Email, &user.Role, &user.Password, &user.Disabled, &user.TenantID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
@@ -35,22 +36,257 @@ func (s *Storage) GetUserStorage(email string) (*models.User, error) {
 	return user, nil
 }
 
-func (s *Storage) CreateUserStorage(email, role string, passHash []byte) error {
+func (s *Storage) GetUserByIDStorage(id int64) (*models.User, error) {
+	const op = "storage.postgresql.GetUserByIDStorage"
+
+	query, args, err := sq.Select("id", "email", "role", "password_hash", "disabled", "tenant_id").
+		From("users").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	row := s.db.QueryRow(query, args...)
+
+	user := &models.User{}
+	err = row.Scan(&user.ID, &user.Email, &user.Role, &user.Password, &user.Disabled, &user.TenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// CreateUserStorage creates the user and records entry for them in the
+// same transaction.
+func (s *Storage) CreateUserStorage(ctx context.Context, email, role string, tenantID int64, passHash []byte, entry *models.AuditEntry) (err error) {
 	const op = "storage.postgresql.CreateUserStorage"
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
 	query, args, err := sq.Insert("users").
-		Columns("email", "role", "password_hash").
-		Values(email, role, passHash).
+		Columns("email", "role", "tenant_id", "password_hash").
+		Values(email, role, tenantID, passHash).
+		Suffix("RETURNING id").
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = s.db.Exec(query, args...)
+	user := &models.User{Email: email, Role: role, TenantID: tenantID}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&user.ID)
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		if pgConstraintName(err) == "users_tenant_id_fkey" {
+			return fmt.Errorf("%s: %w", op, storage.ErrTenantNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if entry != nil {
+		entry.TargetUserID = &user.ID
+		entry.After = user.Snapshot()
+	}
+
+	err = insertAuditEntry(ctx, tx, entry)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
+
+func (s *Storage) ListUsersStorage(ctx context.Context, tenantID int64, limit int, offset int) ([]models.User, error) {
+	const op = "storage.postgresql.ListUsersStorage"
+
+	query, args, err := sq.Select("id", "email", "role", "disabled", "tenant_id").
+		From("users").
+		Where(sq.Eq{"tenant_id": tenantID}).
+		OrderBy("id").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Email, &user.Role, &user.Disabled, &user.TenantID); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+// UpdateUserStorage changes the role and/or the disabled flag of the user
+// and records entry with the user before and after the change.
+func (s *Storage) UpdateUserStorage(ctx context.Context, id int64, role *string, disabled *bool, entry *models.AuditEntry) (_ *models.User, err error) {
+	const op = "storage.postgresql.UpdateUserStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	before, err := lockUser(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	updateBuilder := sq.Update("users").
+		Where(sq.Eq{"id": id}).
+		Suffix("RETURNING id, email, role, disabled, tenant_id")
+
+	if role != nil {
+		updateBuilder = updateBuilder.Set("role", *role)
+	}
+
+	if disabled != nil {
+		updateBuilder = updateBuilder.Set("disabled", *disabled)
+	}
+
+	query, args, err := updateBuilder.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user := &models.User{}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.Email, &user.Role, &user.Disabled, &user.TenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if entry != nil {
+		entry.TargetUserID = &id
+		entry.Before = before.Snapshot()
+		entry.After = user.Snapshot()
+	}
+
+	err = insertAuditEntry(ctx, tx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// DeleteUserStorage deletes the user and records entry with the user as
+// they were.
+func (s *Storage) DeleteUserStorage(ctx context.Context, id int64, entry *models.AuditEntry) (err error) {
+	const op = "storage.postgresql.DeleteUserStorage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	before, err := lockUser(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query, args, err := sq.Delete("users").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if entry != nil {
+		entry.TargetUserID = &id
+		entry.Before = before.Snapshot()
+	}
+
+	err = insertAuditEntry(ctx, tx, entry)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// lockUser returns the user, without the password hash, and locks them
+// until the end of tx.
+func lockUser(ctx context.Context, tx *sql.Tx, id int64) (*models.User, error) {
+	const op = "storage.postgresql.lockUser"
+
+	query, args, err := sq.Select("id", "email", "role", "disabled", "tenant_id").
+		From("users").
+		Where(sq.Eq{"id": id}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user := &models.User{}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.Email, &user.Role, &user.Disabled, &user.TenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
@@ -39,12 +39,7 @@ func (c *Cache) Get(ctx context.Context, key string) (*models.Banner, error) {
 	const op = "storage.redisC.Get"
 
 	var banner models.Banner
-	bannerJSON, err := c.Client.Get(ctx, key).Bytes()
-	if errors.Is(err, redis.Nil) {
-		return nil, fmt.Errorf("%s: %w", op, storage.ErrNotFoundInCache)
-	}
-
-	err = json.Unmarshal(bannerJSON, &banner)
+	err := c.GetValue(ctx, key, &banner)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -55,15 +50,198 @@ func (c *Cache) Get(ctx context.Context, key string) (*models.Banner, error) {
 func (c *Cache) Set(ctx context.Context, key string, value models.Banner) error {
 	const op = "storage.redisC.Set"
 
-	bannerJSON, err := json.Marshal(value)
+	err := c.SetValue(ctx, key, value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetValue decodes the JSON cached under the key into value. It returns
+// storage.ErrNotFoundInCache if the key is not cached.
+func (c *Cache) GetValue(ctx context.Context, key string, value any) error {
+	const op = "storage.redisC.GetValue"
+
+	valueJSON, err := c.Client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFoundInCache)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = json.Unmarshal(valueJSON, value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetValue caches value as JSON under the key for five minutes.
+func (c *Cache) SetValue(ctx context.Context, key string, value any) error {
+	const op = "storage.redisC.SetValue"
+
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = c.Client.Set(ctx, key, valueJSON, 5*time.Minute).Err()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = c.Client.Set(ctx, key, bannerJSON, 5*time.Minute).Err()
+	return nil
+}
+
+// Del removes the keys from the cache. Missing keys are ignored.
+func (c *Cache) Del(ctx context.Context, keys ...string) error {
+	const op = "storage.redisC.Del"
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	err := c.Client.Del(ctx, keys...).Err()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
+
+// Revoke puts a token id on the denylist until the token expires.
+func (c *Cache) Revoke(ctx context.Context, jti string, ttl time.Duration) error {
+	const op = "storage.redisC.Revoke"
+
+	err := c.Client.Set(ctx, revokedKey(jti), 1, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (c *Cache) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	const op = "storage.redisC.IsRevoked"
+
+	if jti == "" {
+		return false, nil
+	}
+
+	n, err := c.Client.Exists(ctx, revokedKey(jti)).Result()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n > 0, nil
+}
+
+func revokedKey(jti string) string {
+	return "revoked:" + jti
+}
+
+// RevokeUser revokes every token of the user issued up to at, for as long
+// as such tokens may still be valid.
+func (c *Cache) RevokeUser(ctx context.Context, userID int64, at time.Time, ttl time.Duration) error {
+	const op = "storage.redisC.RevokeUser"
+
+	err := c.Client.Set(ctx, revokedUserKey(userID), at.Unix(), ttl).Err()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UserRevokedAt returns the time up to which the tokens of the user are
+// revoked as a Unix timestamp, zero if they are not.
+func (c *Cache) UserRevokedAt(ctx context.Context, userID int64) (int64, error) {
+	const op = "storage.redisC.UserRevokedAt"
+
+	at, err := c.Client.Get(ctx, revokedUserKey(userID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return at, nil
+}
+
+func revokedUserKey(userID int64) string {
+	return fmt.Sprintf("revoked_user:%d", userID)
+}
+
+// RegisterFailure counts a failed attempt for the key and returns the
+// number of failures within the window, which starts at the first one.
+func (c *Cache) RegisterFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	const op = "storage.redisC.RegisterFailure"
+
+	var incr *redis.IntCmd
+	_, err := c.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, failuresKey(key))
+		pipe.ExpireNX(ctx, failuresKey(key), window)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return incr.Val(), nil
+}
+
+// Lock locks the key out for ttl and starts counting failures anew.
+func (c *Cache) Lock(ctx context.Context, key string, ttl time.Duration) error {
+	const op = "storage.redisC.Lock"
+
+	_, err := c.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, lockoutKey(key), 1, ttl)
+		pipe.Del(ctx, failuresKey(key))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// LockedFor returns how long the key is still locked out, or zero.
+func (c *Cache) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	const op = "storage.redisC.LockedFor"
+
+	ttl, err := c.Client.PTTL(ctx, lockoutKey(key)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// PTTL is negative if the key does not exist.
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+func (c *Cache) ResetFailures(ctx context.Context, key string) error {
+	const op = "storage.redisC.ResetFailures"
+
+	err := c.Client.Del(ctx, failuresKey(key)).Err()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func failuresKey(key string) string {
+	return "login_failures:" + key
+}
+
+func lockoutKey(key string) string {
+	return "login_lockout:" + key
+}
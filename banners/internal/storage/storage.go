@@ -1,6 +1,9 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrUserNotFound         = errors.New("user not found")
@@ -8,4 +11,50 @@ var (
 	ErrFailedRevisionChange = errors.New("failed to choose a revision")
 	ErrRevisionDoesNotExist = errors.New("chosen revision does not exist for this banner")
 	ErrNotFoundInCache      = errors.New("value not found in cache")
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenRevoked  = errors.New("refresh token has been revoked")
+	ErrRefreshTokenExpired  = errors.New("refresh token has expired")
+	ErrRoleNotFound         = errors.New("role not found")
+	ErrPermissionNotFound   = errors.New("permission not found")
+	ErrRoleInUse            = errors.New("role is assigned to users")
+	ErrAPIKeyNotFound       = errors.New("api key not found")
+	ErrTenantNotFound       = errors.New("tenant not found")
+	ErrTenantExists         = errors.New("tenant already exists")
+	ErrBannerConflict       = errors.New("another banner has the same feature and tag")
+	ErrApprovalRequired     = errors.New("revision needs an approval to be published")
+	ErrApprovalNotFound     = errors.New("approval request not found")
+	ErrApprovalExists       = errors.New("revision already has a pending approval request")
+	ErrApprovalNotPending   = errors.New("approval request has already been decided")
+	ErrNothingToRollBack    = errors.New("banner has no earlier publication to roll back to")
+	ErrSchemaNotFound       = errors.New("feature has no schema")
+	ErrSchemaVersionExists  = errors.New("schema version already exists")
+	ErrRegistryNotFound     = errors.New("feature or tag not found")
+	ErrRegistryExists       = errors.New("feature or tag with this id or name already exists")
+	ErrRegistryInUse        = errors.New("feature or tag is used by banners")
+	ErrParentNotFound       = errors.New("parent tag not found")
+	ErrNotOnFeature         = errors.New("chosen revision of the banner is not on the feature")
+	ErrDefaultNotFound      = errors.New("feature has no default banner")
+	ErrExperimentNotFound   = errors.New("experiment not found")
+	ErrExperimentRunning    = errors.New("another experiment is running for the feature and tag")
+	ErrExperimentStopped    = errors.New("experiment has already been stopped")
+	ErrRolloutNotFound      = errors.New("banner has no rollout")
+	ErrRolloutExists        = errors.New("banner already has a rollout")
+	ErrRolloutOfLive        = errors.New("revision is already live")
+	ErrRolloutTargets       = errors.New("revision must have the feature and tags of the live revision")
 )
+
+// BannerConflictError is returned when a banner would share a feature and a
+// tag with another banner of the tenant. It matches ErrBannerConflict.
+type BannerConflictError struct {
+	BannerID  int64
+	FeatureID int64
+	TagID     int64
+}
+
+func (e *BannerConflictError) Error() string {
+	return fmt.Sprintf("banner %d already has feature %d and tag %d", e.BannerID, e.FeatureID, e.TagID)
+}
+
+func (e *BannerConflictError) Is(target error) bool {
+	return target == ErrBannerConflict
+}
@@ -0,0 +1,35 @@
+// Package bucket spreads keys over buckets deterministically, so that the
+// same user always lands in the same bucket of the same experiment.
+package bucket
+
+import "hash/fnv"
+
+// Of returns the bucket in [0, n) of the key under the seed. Different
+// seeds spread the same keys independently of each other.
+func Of(seed string, key string, n uint64) uint64 {
+	if n == 0 {
+		return 0
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(seed))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+
+	return mix(h.Sum64()) % n
+}
+
+// mix spreads every bit of the hash over the low bits the bucket is taken
+// from. The low bits of FNV-1a only depend on the low bits of the input
+// bytes, so without it the buckets of different seeds would be correlated
+// whenever n is even.
+func mix(h uint64) uint64 {
+	// The finalizer of MurmurHash3.
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+
+	return h
+}
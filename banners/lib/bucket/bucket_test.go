@@ -0,0 +1,65 @@
+package bucket
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestOfIsDeterministic(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		key := "user-" + strconv.Itoa(i)
+		if Of("seed", key, 10) != Of("seed", key, 10) {
+			t.Fatalf("Of(%q) differs between calls", key)
+		}
+	}
+}
+
+func TestOfRange(t *testing.T) {
+	if got := Of("seed", "user", 0); got != 0 {
+		t.Errorf("Of() with no buckets = %d, want 0", got)
+	}
+
+	for i := 0; i < 1000; i++ {
+		if got := Of("seed", strconv.Itoa(i), 7); got >= 7 {
+			t.Fatalf("Of() = %d, want below 7", got)
+		}
+	}
+}
+
+func TestOfSpreadsKeysEvenly(t *testing.T) {
+	const keys, n = 100000, 10
+
+	var counts [n]int
+	for i := 0; i < keys; i++ {
+		counts[Of("seed", "user-"+strconv.Itoa(i), n)]++
+	}
+
+	for b, count := range counts {
+		if count < keys/n*9/10 || count > keys/n*11/10 {
+			t.Errorf("bucket %d got %d keys, want about %d", b, count, keys/n)
+		}
+	}
+}
+
+func TestOfSeedsAreIndependent(t *testing.T) {
+	const keys = 10000
+
+	same := 0
+	for i := 0; i < keys; i++ {
+		key := "user-" + strconv.Itoa(i)
+		if Of("a", key, 2) == Of("b", key, 2) {
+			same++
+		}
+	}
+
+	// Independent seeds put about half of the keys into the same bucket.
+	if same < keys*45/100 || same > keys*55/100 {
+		t.Errorf("%d of %d keys share the bucket under both seeds, want about half", same, keys)
+	}
+}
+
+func TestOfSeparatesSeedFromKey(t *testing.T) {
+	if Of("ab", "c", 1<<32) == Of("a", "bc", 1<<32) {
+		t.Error("Of() hashes the seed and the key as one string")
+	}
+}
@@ -0,0 +1,159 @@
+// Package jsonpatch computes the RFC 6902 JSON Patch that turns one JSON
+// document into another.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+)
+
+// Operation is a single operation of a JSON Patch. Value is left out for
+// removals.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Diff returns the operations that turn from into to. An empty document is
+// treated as null. Arrays are compared index by index, so an insertion in
+// the middle of an array shows up as replacements followed by an add.
+func Diff(from, to json.RawMessage) ([]Operation, error) {
+	a, err := decode(from)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := decode(to)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := []Operation{}
+	return diff(ops, "", a, b)
+}
+
+func diff(ops []Operation, path string, a, b any) ([]Operation, error) {
+	switch a := a.(type) {
+	case map[string]any:
+		if b, ok := b.(map[string]any); ok {
+			return diffObjects(ops, path, a, b)
+		}
+	case []any:
+		if b, ok := b.([]any); ok {
+			return diffArrays(ops, path, a, b)
+		}
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return ops, nil
+	}
+
+	return appendValue(ops, OpReplace, path, b)
+}
+
+func diffObjects(ops []Operation, path string, a, b map[string]any) ([]Operation, error) {
+	var err error
+
+	for _, key := range sortedKeys(a) {
+		if _, ok := b[key]; !ok {
+			ops = append(ops, Operation{Op: OpRemove, Path: path + "/" + escape(key)})
+		}
+	}
+
+	for _, key := range sortedKeys(b) {
+		keyPath := path + "/" + escape(key)
+
+		aValue, ok := a[key]
+		if !ok {
+			ops, err = appendValue(ops, OpAdd, keyPath, b[key])
+		} else {
+			ops, err = diff(ops, keyPath, aValue, b[key])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ops, nil
+}
+
+// diffArrays compares the common elements first, then removes the extra
+// elements of a from the end, so that the indices of earlier operations
+// stay valid, and finally appends the extra elements of b.
+func diffArrays(ops []Operation, path string, a, b []any) ([]Operation, error) {
+	var err error
+
+	common := min(len(a), len(b))
+	for i := 0; i < common; i++ {
+		ops, err = diff(ops, path+"/"+strconv.Itoa(i), a[i], b[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := len(a) - 1; i >= common; i-- {
+		ops = append(ops, Operation{Op: OpRemove, Path: path + "/" + strconv.Itoa(i)})
+	}
+
+	for i := common; i < len(b); i++ {
+		ops, err = appendValue(ops, OpAdd, path+"/"+strconv.Itoa(i), b[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ops, nil
+}
+
+func appendValue(ops []Operation, op string, path string, value any) ([]Operation, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ops, Operation{Op: op, Path: path, Value: data}), nil
+}
+
+func decode(doc json.RawMessage) (any, error) {
+	if len(bytes.TrimSpace(doc)) == 0 {
+		return nil, nil
+	}
+
+	// Numbers are kept as written, so that large integers are compared
+	// exactly.
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// escape encodes a key as a JSON Pointer reference token (RFC 6901).
+func escape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
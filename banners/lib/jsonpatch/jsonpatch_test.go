@@ -0,0 +1,134 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []Operation
+	}{
+		{
+			name: "equal documents",
+			from: `{"title": "a", "tags": [1, 2]}`,
+			to:   `{"tags": [1, 2], "title": "a"}`,
+			want: []Operation{},
+		},
+		{
+			name: "empty document is null",
+			from: ``,
+			to:   `{"title": "a"}`,
+			want: []Operation{
+				{Op: OpReplace, Path: "", Value: json.RawMessage(`{"title":"a"}`)},
+			},
+		},
+		{
+			name: "object keys in order",
+			from: `{"b": 1, "c": 2}`,
+			to:   `{"a": 0, "b": 3}`,
+			want: []Operation{
+				{Op: OpRemove, Path: "/c"},
+				{Op: OpAdd, Path: "/a", Value: json.RawMessage(`0`)},
+				{Op: OpReplace, Path: "/b", Value: json.RawMessage(`3`)},
+			},
+		},
+		{
+			name: "nested objects",
+			from: `{"banner": {"title": "a", "style": {"color": "red"}}}`,
+			to:   `{"banner": {"title": "a", "style": {"color": "blue", "size": 2}}}`,
+			want: []Operation{
+				{Op: OpReplace, Path: "/banner/style/color", Value: json.RawMessage(`"blue"`)},
+				{Op: OpAdd, Path: "/banner/style/size", Value: json.RawMessage(`2`)},
+			},
+		},
+		{
+			name: "escaped keys",
+			from: `{"a/b": 1, "c~d": 1}`,
+			to:   `{"a/b": 2, "c~d": 2}`,
+			want: []Operation{
+				{Op: OpReplace, Path: "/a~1b", Value: json.RawMessage(`2`)},
+				{Op: OpReplace, Path: "/c~0d", Value: json.RawMessage(`2`)},
+			},
+		},
+		{
+			name: "array grows",
+			from: `{"items": [1, 2]}`,
+			to:   `{"items": [1, 3, 4, 5]}`,
+			want: []Operation{
+				{Op: OpReplace, Path: "/items/1", Value: json.RawMessage(`3`)},
+				{Op: OpAdd, Path: "/items/2", Value: json.RawMessage(`4`)},
+				{Op: OpAdd, Path: "/items/3", Value: json.RawMessage(`5`)},
+			},
+		},
+		{
+			name: "array shrinks from the end",
+			from: `[1, 2, 3, 4]`,
+			to:   `[0, 2]`,
+			want: []Operation{
+				{Op: OpReplace, Path: "/0", Value: json.RawMessage(`0`)},
+				{Op: OpRemove, Path: "/3"},
+				{Op: OpRemove, Path: "/2"},
+			},
+		},
+		{
+			name: "objects in arrays",
+			from: `[{"id": 1, "name": "a"}]`,
+			to:   `[{"id": 1, "name": "b"}]`,
+			want: []Operation{
+				{Op: OpReplace, Path: "/0/name", Value: json.RawMessage(`"b"`)},
+			},
+		},
+		{
+			name: "type change",
+			from: `{"value": [1]}`,
+			to:   `{"value": {"0": 1}}`,
+			want: []Operation{
+				{Op: OpReplace, Path: "/value", Value: json.RawMessage(`{"0":1}`)},
+			},
+		},
+		{
+			name: "large integers",
+			from: `{"id": 9007199254740993}`,
+			to:   `{"id": 9007199254740992}`,
+			want: []Operation{
+				{Op: OpReplace, Path: "/id", Value: json.RawMessage(`9007199254740992`)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(json.RawMessage(tt.from), json.RawMessage(tt.to))
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %s, want %s", marshal(t, got), marshal(t, tt.want))
+			}
+		})
+	}
+}
+
+func TestDiffInvalidDocument(t *testing.T) {
+	_, err := Diff(json.RawMessage(`{"a": 1}`), json.RawMessage(`{"a":`))
+	if err == nil {
+		t.Error("Diff() error = nil, want an error")
+	}
+}
+
+func marshal(t *testing.T, ops []Operation) string {
+	t.Helper()
+
+	data, err := json.Marshal(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
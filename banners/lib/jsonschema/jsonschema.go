@@ -0,0 +1,122 @@
+// Package jsonschema validates JSON documents against a JSON Schema and
+// reports the failures as JSON Pointers (RFC 6901).
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/xeipuuv/gojsonschema"
+	"strings"
+)
+
+// rootContext is the name gojsonschema gives to the root of a document.
+const rootContext = "(root)"
+
+// ErrRemoteRef is returned for schemas that reference anything but
+// themselves. gojsonschema would otherwise fetch such references over the
+// network or from the filesystem of the server.
+var ErrRemoteRef = errors.New("only local $ref are allowed")
+
+// Violation is a part of a document that does not match the schema.
+type Violation struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+type Schema struct {
+	schema *gojsonschema.Schema
+}
+
+// Compile parses a JSON Schema. The schema may only reference itself, see
+// ErrRemoteRef.
+func Compile(schema json.RawMessage) (*Schema, error) {
+	compiled, err := gojsonschema.NewSchema(localLoader{gojsonschema.NewBytesLoader(schema)})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Schema{schema: compiled}, nil
+}
+
+// localLoader loads a schema whose references are resolved only within the
+// schema itself. gojsonschema asks the factory of the root loader for every
+// document that is not part of the schema.
+type localLoader struct {
+	gojsonschema.JSONLoader
+}
+
+func (l localLoader) LoaderFactory() gojsonschema.JSONLoaderFactory {
+	return refusingFactory{}
+}
+
+type refusingFactory struct{}
+
+func (refusingFactory) New(source string) gojsonschema.JSONLoader {
+	return refusingLoader{JSONLoader: gojsonschema.NewStringLoader("null"), source: source}
+}
+
+// refusingLoader fails to load the document it is asked for.
+type refusingLoader struct {
+	gojsonschema.JSONLoader
+	source string
+}
+
+func (l refusingLoader) LoadJSON() (any, error) {
+	return nil, fmt.Errorf("%w: %s", ErrRemoteRef, l.source)
+}
+
+// Validate returns the violations of the document, which are empty if the
+// document matches the schema. An empty document is treated as null.
+func (s *Schema) Validate(doc json.RawMessage) ([]Violation, error) {
+	if len(strings.TrimSpace(string(doc))) == 0 {
+		doc = json.RawMessage("null")
+	}
+
+	result, err := s.schema.Validate(gojsonschema.NewBytesLoader(doc))
+	if err != nil {
+		return nil, err
+	}
+
+	violations := []Violation{}
+	for _, resultErr := range result.Errors() {
+		pointer := pointerOf(resultErr.Context())
+
+		// A missing property is reported on its parent, point at the
+		// property itself.
+		if resultErr.Type() == "required" {
+			if property, ok := resultErr.Details()["property"].(string); ok {
+				pointer += "/" + escape(property)
+			}
+		}
+
+		violations = append(violations, Violation{Pointer: pointer, Message: resultErr.Description()})
+	}
+
+	return violations, nil
+}
+
+func pointerOf(context *gojsonschema.JsonContext) string {
+	if context == nil {
+		return ""
+	}
+
+	// Keys may contain dots, so the context is joined with NUL instead.
+	tokens := strings.Split(context.String("\x00"), "\x00")
+	if tokens[0] == rootContext {
+		tokens = tokens[1:]
+	}
+
+	var pointer strings.Builder
+	for _, token := range tokens {
+		pointer.WriteString("/")
+		pointer.WriteString(escape(token))
+	}
+
+	return pointer.String()
+}
+
+// escape encodes a key as a JSON Pointer reference token (RFC 6901).
+func escape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
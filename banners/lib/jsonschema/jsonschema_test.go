@@ -0,0 +1,165 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const bannerSchema = `{
+	"type": "object",
+	"required": ["title", "a/b"],
+	"properties": {
+		"title": {"type": "string"},
+		"a/b": {"type": "integer"},
+		"x.y": {"type": "integer"},
+		"links": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"required": ["url"],
+				"properties": {"url": {"type": "string"}}
+			}
+		}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile(json.RawMessage(bannerSchema))
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		doc      string
+		pointers []string
+	}{
+		{
+			name:     "valid",
+			doc:      `{"title": "a", "a/b": 1}`,
+			pointers: []string{},
+		},
+		{
+			name:     "wrong type",
+			doc:      `{"title": 1, "a/b": 1}`,
+			pointers: []string{"/title"},
+		},
+		{
+			name:     "missing property points at the property",
+			doc:      `{"a/b": 1}`,
+			pointers: []string{"/title"},
+		},
+		{
+			name:     "escaped keys",
+			doc:      `{"title": "a", "a/b": "1"}`,
+			pointers: []string{"/a~1b"},
+		},
+		{
+			name:     "dotted keys",
+			doc:      `{"title": "a", "a/b": 1, "x.y": "1"}`,
+			pointers: []string{"/x.y"},
+		},
+		{
+			name:     "array items",
+			doc:      `{"title": "a", "a/b": 1, "links": [{"url": "u"}, {"url": 1}, {}]}`,
+			pointers: []string{"/links/1/url", "/links/2/url"},
+		},
+		{
+			name:     "root",
+			doc:      `[]`,
+			pointers: []string{""},
+		},
+		{
+			name:     "empty document is null",
+			doc:      ` `,
+			pointers: []string{""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := schema.Validate(json.RawMessage(tt.doc))
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			pointers := []string{}
+			for _, violation := range violations {
+				pointers = append(pointers, violation.Pointer)
+			}
+
+			if !equalSets(pointers, tt.pointers) {
+				t.Errorf("Validate() pointers = %q, want %q", pointers, tt.pointers)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr error
+	}{
+		{
+			name:   "local ref",
+			schema: `{"definitions": {"url": {"type": "string"}}, "properties": {"url": {"$ref": "#/definitions/url"}}}`,
+		},
+		{
+			name:    "remote ref",
+			schema:  `{"$ref": "http://127.0.0.1:1/schema.json"}`,
+			wantErr: ErrRemoteRef,
+		},
+		{
+			name:    "file ref",
+			schema:  `{"properties": {"a": {"$ref": "file:///etc/passwd"}}}`,
+			wantErr: ErrRemoteRef,
+		},
+		{
+			name:    "remote ref under a keyword named property",
+			schema:  `{"properties": {"enum": {"$ref": "https://127.0.0.1:1/schema.json#/a"}}}`,
+			wantErr: ErrRemoteRef,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(json.RawMessage(tt.schema))
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Compile() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompileInvalidSchema(t *testing.T) {
+	for _, schema := range []string{`{"type": 1}`, `{"type":`} {
+		_, err := Compile(json.RawMessage(schema))
+		if err == nil {
+			t.Errorf("Compile(%s) error = nil, want an error", schema)
+		}
+	}
+}
+
+func equalSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	seen := make(map[string]int, len(a))
+	for _, s := range a {
+		seen[s]++
+	}
+	for _, s := range b {
+		seen[s]--
+		if seen[s] < 0 {
+			return false
+		}
+	}
+
+	return true
+}
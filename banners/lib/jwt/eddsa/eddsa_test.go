@@ -0,0 +1,100 @@
+package eddsa
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"strings"
+	"testing"
+)
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return public, private
+}
+
+func TestSignAndParse(t *testing.T) {
+	public, private := newKey(t)
+
+	signed, err := jwt.NewWithClaims(SigningMethodEdDSA, jwt.StandardClaims{Subject: "user"}).SignedString(private)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	claims := &jwt.StandardClaims{}
+	token, err := jwt.ParseWithClaims(signed, claims, func(token *jwt.Token) (interface{}, error) {
+		return public, nil
+	})
+	if err != nil {
+		t.Fatalf("ParseWithClaims() error = %v", err)
+	}
+
+	if token.Header["alg"] != "EdDSA" || token.Method != SigningMethodEdDSA {
+		t.Errorf("alg = %v, want EdDSA", token.Header["alg"])
+	}
+	if claims.Subject != "user" {
+		t.Errorf("subject = %q, want user", claims.Subject)
+	}
+}
+
+func TestVerifyRejectsTamperedTokens(t *testing.T) {
+	public, private := newKey(t)
+	otherPublic, _ := newKey(t)
+
+	signingString := "header.payload"
+	signature, err := SigningMethodEdDSA.Sign(signingString, private)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	if err := SigningMethodEdDSA.Verify(signingString, signature, public); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if err := SigningMethodEdDSA.Verify("header.other", signature, public); !errors.Is(err, ErrEd25519Verification) {
+		t.Errorf("Verify() of another payload error = %v, want %v", err, ErrEd25519Verification)
+	}
+
+	if err := SigningMethodEdDSA.Verify(signingString, signature, otherPublic); !errors.Is(err, ErrEd25519Verification) {
+		t.Errorf("Verify() with another key error = %v, want %v", err, ErrEd25519Verification)
+	}
+
+	tampered := strings.Repeat("A", len(signature))
+	if err := SigningMethodEdDSA.Verify(signingString, tampered, public); !errors.Is(err, ErrEd25519Verification) {
+		t.Errorf("Verify() of another signature error = %v, want %v", err, ErrEd25519Verification)
+	}
+}
+
+func TestWrongKeyType(t *testing.T) {
+	public, private := newKey(t)
+
+	if _, err := SigningMethodEdDSA.Sign("header.payload", public); !errors.Is(err, jwt.ErrInvalidKeyType) {
+		t.Errorf("Sign() with a public key error = %v, want %v", err, jwt.ErrInvalidKeyType)
+	}
+
+	if _, err := SigningMethodEdDSA.Sign("header.payload", []byte("secret")); !errors.Is(err, jwt.ErrInvalidKeyType) {
+		t.Errorf("Sign() with a secret error = %v, want %v", err, jwt.ErrInvalidKeyType)
+	}
+
+	signature, err := SigningMethodEdDSA.Sign("header.payload", private)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	if err := SigningMethodEdDSA.Verify("header.payload", signature, private); !errors.Is(err, jwt.ErrInvalidKeyType) {
+		t.Errorf("Verify() with a private key error = %v, want %v", err, jwt.ErrInvalidKeyType)
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	if jwt.GetSigningMethod("EdDSA") != SigningMethodEdDSA {
+		t.Error("EdDSA is not registered with jwt-go")
+	}
+}
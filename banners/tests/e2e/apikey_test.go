@@ -0,0 +1,79 @@
+package e2e
+
+import (
+	"fmt"
+	"github.com/ozontech/cute/asserts/json"
+	"net/http"
+	"testing"
+)
+
+type createdAPIKey struct {
+	ID  int64  `json:"id"`
+	Key string `json:"key"`
+}
+
+func Test_APIKey(t *testing.T) {
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 201, 201)
+	register(t, adminToken, 202)
+
+	createBanner(t, adminToken, 201, []int64{201}, `{"title":"allowed"}`)
+	createBanner(t, adminToken, 202, []int64{201}, `{"title":"restricted"}`)
+
+	body := step(t, "create api key", call{
+		method: http.MethodPost,
+		path:   "/api_keys",
+		auth:   bearer(adminToken),
+		body: map[string]any{
+			"name":        "e2e reader",
+			"scopes":      []string{"banner:read"},
+			"feature_ids": []int64{201},
+		},
+	}, http.StatusCreated, json.Present("key"), json.Equal("name", "e2e reader"))
+	key := decode[createdAPIKey](t, body)
+
+	getUserBanner(t, apiKey(key.Key), 201, 201, "", http.StatusOK, json.Equal("content.title", "allowed"))
+
+	getUserBanner(t, apiKey(key.Key), 202, 201, "", http.StatusForbidden,
+		json.Equal("error", "feature is not allowed"))
+
+	step(t, "api key is limited to its scopes", call{
+		method: http.MethodGet,
+		path:   "/features",
+		auth:   apiKey(key.Key),
+	}, http.StatusForbidden, json.Equal("error", "permission denied"))
+
+	getUserBanner(t, apiKey("not-a-key"), 201, 201, "", http.StatusUnauthorized,
+		json.Equal("error", "Invalid api key"))
+
+	step(t, "unknown scope", call{
+		method: http.MethodPost,
+		path:   "/api_keys",
+		auth:   bearer(adminToken),
+		body:   map[string]any{"name": "e2e unknown", "scopes": []string{"banner:fly"}},
+	}, http.StatusBadRequest, json.Equal("error", "scopes must be existing banner permissions"))
+
+	step(t, "create key manager role", call{
+		method: http.MethodPut,
+		path:   "/roles/e2e-key-manager",
+		auth:   bearer(adminToken),
+		body:   map[string]any{"permissions": []string{"apikey:manage", "banner:read"}},
+	}, http.StatusNoContent)
+	manager := createUser(t, adminToken, "key-manager@e2e.com", "e2e-key-manager").Token
+
+	step(t, "scope beyond the permissions of the actor", call{
+		method: http.MethodPost,
+		path:   "/api_keys",
+		auth:   bearer(manager),
+		body:   map[string]any{"name": "e2e editor", "scopes": []string{"banner:edit"}},
+	}, http.StatusForbidden, json.Equal("error", "scopes must be permissions you have"))
+
+	step(t, "revoke api key", call{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("/api_keys/%d", key.ID),
+		auth:   bearer(adminToken),
+	}, http.StatusNoContent)
+
+	getUserBanner(t, apiKey(key.Key), 201, 201, "", http.StatusUnauthorized,
+		json.Equal("error", "Invalid api key"))
+}
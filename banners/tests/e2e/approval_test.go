@@ -0,0 +1,101 @@
+package e2e
+
+import (
+	"fmt"
+	"github.com/ozontech/cute/asserts/json"
+	"net/http"
+	"testing"
+)
+
+// requestApproval asks for an approval of the revision and returns the ID
+// of the request.
+func requestApproval(t *testing.T, token string, bannerID, revisionID int) int {
+	t.Helper()
+
+	body := step(t, "request approval", call{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/banner/%d/approvals", bannerID),
+		query:  map[string][]string{"revision_id": {fmt.Sprint(revisionID)}},
+		auth:   bearer(token),
+		body:   map[string]string{"comment": "please review"},
+	}, http.StatusCreated, json.Equal("status", "pending"))
+
+	return decode[struct {
+		ID int `json:"id"`
+	}](t, body).ID
+}
+
+func Test_Approval(t *testing.T) {
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 501, 501)
+
+	step(t, "require approval", call{
+		method: http.MethodPut,
+		path:   "/features/501/policy",
+		auth:   bearer(adminToken),
+		body:   map[string]bool{"requires_approval": true},
+	}, http.StatusOK)
+
+	author := createUser(t, adminToken, "approval-author@e2e.com", "admin").Token
+	reviewer := createUser(t, adminToken, "approval-reviewer@e2e.com", "admin").Token
+
+	// The first revision of a banner under approval is a draft.
+	bannerID := createBanner(t, author, 501, []int64{501}, `{"title":"first"}`)
+	first := latestRevision(t, author, bannerID)
+	getUserBanner(t, bearer(author), 501, 501, "", http.StatusNotFound, json.Equal("error", "banner not found"))
+
+	publish := call{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/banner/%d/publish", bannerID),
+		query:  map[string][]string{"revision_id": {fmt.Sprint(first)}},
+		auth:   bearer(author),
+	}
+	step(t, "publish without approval", publish, http.StatusForbidden,
+		json.Equal("error", "revision needs an approval to be published"))
+
+	requestID := requestApproval(t, author, bannerID, first)
+
+	step(t, "duplicate approval request", call{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/banner/%d/approvals", bannerID),
+		query:  map[string][]string{"revision_id": {fmt.Sprint(first)}},
+		auth:   bearer(author),
+	}, http.StatusConflict, json.Equal("error", "revision already has a pending approval request"))
+
+	approve := call{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/approvals/%d/approve", requestID),
+		auth:   bearer(author),
+	}
+	step(t, "author cannot approve", approve, http.StatusForbidden,
+		json.Equal("error", "revision cannot be approved by its author or requester"))
+
+	step(t, "editor cannot approve", call{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/approvals/%d/approve", requestID),
+		auth:   bearer(createUser(t, adminToken, "approval-editor@e2e.com", "editor").Token),
+	}, http.StatusForbidden, json.Equal("error", "permission denied"))
+
+	approve.auth = bearer(reviewer)
+	step(t, "reviewer approves", approve, http.StatusOK, json.Equal("status", "approved"))
+	step(t, "approve twice", approve, http.StatusConflict,
+		json.Equal("error", "approval request has already been decided"))
+
+	step(t, "publish approved revision", publish, http.StatusOK)
+	getUserBanner(t, bearer(author), 501, 501, "", http.StatusOK, json.Equal("content.title", "first"))
+
+	second := draftRevision(t, author, bannerID, 501, []int64{501}, `{"title":"second"}`)
+	requestID = requestApproval(t, author, bannerID, second)
+
+	step(t, "reviewer rejects", call{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/approvals/%d/reject", requestID),
+		auth:   bearer(reviewer),
+		body:   map[string]string{"comment": "not yet"},
+	}, http.StatusOK, json.Equal("status", "rejected"))
+
+	publish.query = map[string][]string{"revision_id": {fmt.Sprint(second)}}
+	step(t, "publish rejected revision", publish, http.StatusForbidden,
+		json.Equal("error", "revision needs an approval to be published"))
+	getUserBanner(t, bearer(author), 501, 501, "", http.StatusOK, json.Equal("content.title", "first"))
+}
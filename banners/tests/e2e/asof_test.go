@@ -0,0 +1,133 @@
+package e2e
+
+import (
+	"fmt"
+	"github.com/ozontech/cute"
+	"github.com/ozontech/cute/asserts/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// The far past and future bound every publication the scenario makes,
+// whatever the clock of the server.
+const (
+	farPast   = "2000-01-01T00:00:00Z"
+	farFuture = "2100-01-01T00:00:00Z"
+)
+
+type liveBanner struct {
+	RevisionID   int       `json:"revision_id"`
+	LiveSince    time.Time `json:"live_since"`
+	MatchedTagID int64     `json:"matched_tag_id"`
+	Fallback     bool      `json:"fallback"`
+}
+
+func asOf(t *testing.T, token string, featureID, tagID int64, at string, status int, asserts ...cute.AssertBody) liveBanner {
+	t.Helper()
+
+	body := step(t, "get user banner as of "+at, call{
+		method: http.MethodGet,
+		path:   "/user_banner/as_of",
+		query:  map[string][]string{"feature_id": {fmt.Sprint(featureID)}, "tag_id": {fmt.Sprint(tagID)}, "at": {at}},
+		auth:   bearer(token),
+	}, status, asserts...)
+	if status != http.StatusOK {
+		return liveBanner{}
+	}
+
+	return decode[liveBanner](t, body)
+}
+
+func Test_AsOf(t *testing.T) {
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 651, 651)
+
+	bannerID := createBanner(t, adminToken, 651, []int64{651}, `{"title":"first"}`)
+	first := asOf(t, adminToken, 651, 651, farFuture, http.StatusOK, json.Equal("content.title", "first"))
+
+	publishRevision(t, adminToken, bannerID, 651, []int64{651}, `{"title":"second"}`)
+	second := asOf(t, adminToken, 651, 651, farFuture, http.StatusOK, json.Equal("content.title", "second"))
+
+	asOf(t, adminToken, 651, 651, first.LiveSince.Format(time.RFC3339Nano), http.StatusOK, json.Equal("content.title", "first"))
+	asOf(t, adminToken, 651, 651, farPast, http.StatusNotFound, json.Equal("error", "banner not found"))
+
+	// A rollback does not rewrite what was live before it.
+	step(t, "rollback", call{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/banner/%d/rollback", bannerID),
+		auth:   bearer(adminToken),
+	}, http.StatusOK)
+
+	if got := asOf(t, adminToken, 651, 651, farFuture, http.StatusOK).RevisionID; got != first.RevisionID {
+		t.Fatalf("live revision after rollback = %d, want %d", got, first.RevisionID)
+	}
+	asOf(t, adminToken, 651, 651, second.LiveSince.Format(time.RFC3339Nano), http.StatusOK, json.Equal("content.title", "second"))
+
+	// The history outlives the banner, without its revisions.
+	step(t, "delete banner", call{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("/banner/%d", bannerID),
+		auth:   bearer(adminToken),
+	}, http.StatusOK)
+
+	asOf(t, adminToken, 651, 651, farFuture, http.StatusNotFound, json.Equal("error", "banner not found"))
+	if got := asOf(t, adminToken, 651, 651, second.LiveSince.Format(time.RFC3339Nano), http.StatusOK).RevisionID; got != second.RevisionID {
+		t.Fatalf("revision live before deletion = %d, want %d", got, second.RevisionID)
+	}
+
+	asOf(t, adminToken, 651, 651, "yesterday", http.StatusBadRequest, json.Equal("error", "at is not a RFC 3339 time"))
+
+	editor := createUser(t, adminToken, "asof-editor@e2e.com", "editor").Token
+	asOf(t, editor, 651, 651, farFuture, http.StatusForbidden, json.Equal("error", "permission denied"))
+}
+
+// Test_AsOfFallback checks that the past is looked up like the present: a
+// banner of the tag comes before one of its ancestors, and the default of
+// the feature of the time answers for tags without one.
+func Test_AsOfFallback(t *testing.T) {
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 1201, 1201, 1202, 1203, 1204)
+
+	step(t, "nest tag", call{
+		method: http.MethodPatch,
+		path:   "/tags/1202",
+		auth:   bearer(adminToken),
+		body:   map[string]int{"parent_id": 1201},
+	}, http.StatusOK)
+
+	createBanner(t, adminToken, 1201, []int64{1201}, `{"title":"parent"}`)
+	parent := asOf(t, adminToken, 1201, 1202, farFuture, http.StatusOK, json.Equal("content.title", "parent"))
+	if parent.MatchedTagID != 1201 || parent.Fallback {
+		t.Fatalf("banner of the parent tag matched tag %d with fallback %v, want tag 1201 and no fallback", parent.MatchedTagID, parent.Fallback)
+	}
+
+	asOf(t, adminToken, 1201, 1203, farFuture, http.StatusNotFound, json.Equal("error", "banner not found"))
+
+	defaultID := createBanner(t, adminToken, 1201, []int64{1204}, `{"title":"default"}`)
+	featureDefault := call{
+		method: http.MethodPut,
+		path:   "/features/1201/default",
+		auth:   bearer(adminToken),
+		body:   map[string]int{"banner_id": defaultID},
+	}
+	step(t, "set default banner", featureDefault, http.StatusOK)
+
+	got := asOf(t, adminToken, 1201, 1203, farFuture, http.StatusOK, json.Equal("content.title", "default"))
+	if !got.Fallback {
+		t.Fatal("default banner is not marked as the fallback")
+	}
+	// The feature had no default yet when the parent banner went live.
+	asOf(t, adminToken, 1201, 1203, parent.LiveSince.Format(time.RFC3339Nano), http.StatusNotFound, json.Equal("error", "banner not found"))
+
+	featureDefault.method = http.MethodDelete
+	featureDefault.body = nil
+	step(t, "delete default banner", featureDefault, http.StatusNoContent)
+	asOf(t, adminToken, 1201, 1203, farFuture, http.StatusNotFound, json.Equal("error", "banner not found"))
+
+	// A banner of the tag itself comes before the one of its parent.
+	createBanner(t, adminToken, 1201, []int64{1202}, `{"title":"child"}`)
+	if got := asOf(t, adminToken, 1201, 1202, farFuture, http.StatusOK, json.Equal("content.title", "child")); got.MatchedTagID != 1202 {
+		t.Fatalf("banner of the tag matched tag %d, want 1202", got.MatchedTagID)
+	}
+}
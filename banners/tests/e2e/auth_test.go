@@ -0,0 +1,82 @@
+package e2e
+
+import (
+	"github.com/ozontech/cute/asserts/json"
+	"net/http"
+	"testing"
+)
+
+func refresh(t *testing.T, title, refreshToken string, status int) tokens {
+	t.Helper()
+
+	asserts := json.Present("token")
+	if status != http.StatusOK {
+		asserts = json.Equal("error", "invalid refresh token")
+	}
+
+	body := step(t, title, call{
+		method: http.MethodPost,
+		path:   "/token/refresh",
+		body:   map[string]string{"refresh_token": refreshToken},
+	}, status, asserts)
+	if status != http.StatusOK {
+		return tokens{}
+	}
+
+	return decode[tokens](t, body)
+}
+
+func Test_RefreshToken(t *testing.T) {
+	first := createUser(t, loginAdmin(t), "refresh@e2e.com", "viewer")
+
+	second := refresh(t, "refresh rotates the token", first.RefreshToken, http.StatusOK)
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh returned the same refresh token")
+	}
+
+	step(t, "refreshed access token works", call{
+		method: http.MethodGet,
+		path:   "/features",
+		auth:   bearer(second.Token),
+	}, http.StatusOK)
+
+	third := refresh(t, "refresh the rotated token", second.RefreshToken, http.StatusOK)
+
+	// Presenting a rotated token again means it leaked, so every refresh
+	// token of the user is revoked, including the latest one.
+	refresh(t, "reuse of a rotated token", first.RefreshToken, http.StatusUnauthorized)
+	refresh(t, "latest token is revoked after reuse", third.RefreshToken, http.StatusUnauthorized)
+
+	refresh(t, "unknown token", "not-a-refresh-token", http.StatusUnauthorized)
+}
+
+func Test_Logout(t *testing.T) {
+	session := createUser(t, loginAdmin(t), "logout@e2e.com", "viewer")
+
+	step(t, "logout without token", call{
+		method: http.MethodPost,
+		path:   "/logout",
+	}, http.StatusUnauthorized, json.Equal("error", "Authorization header missing"))
+
+	step(t, "logout", call{
+		method: http.MethodPost,
+		path:   "/logout",
+		auth:   bearer(session.Token),
+		body:   map[string]string{"refresh_token": session.RefreshToken},
+	}, http.StatusOK, json.Equal("message", "Successfully logged out."))
+
+	step(t, "access token is revoked", call{
+		method: http.MethodGet,
+		path:   "/features",
+		auth:   bearer(session.Token),
+	}, http.StatusUnauthorized, json.Equal("error", "Invalid token"))
+
+	refresh(t, "refresh token is revoked", session.RefreshToken, http.StatusUnauthorized)
+
+	other := login(t, "logout@e2e.com", userPassword)
+	step(t, "other sessions stay valid", call{
+		method: http.MethodGet,
+		path:   "/features",
+		auth:   bearer(other.Token),
+	}, http.StatusOK)
+}
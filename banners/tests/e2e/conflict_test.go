@@ -0,0 +1,60 @@
+package e2e
+
+import (
+	"fmt"
+	"github.com/ozontech/cute/asserts/json"
+	"net/http"
+	"testing"
+)
+
+func Test_FeatureTagConflict(t *testing.T) {
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 301, 301, 302, 303)
+	register(t, adminToken, 302)
+
+	first := createBanner(t, adminToken, 301, []int64{301, 302}, `{"title":"first"}`)
+	conflict := json.Equal("error", fmt.Sprintf("banner %d already has feature 301 and tag 302", first))
+
+	step(t, "create banner with a taken pair", call{
+		method: http.MethodPost,
+		path:   "/banner",
+		auth:   bearer(adminToken),
+		body:   bannerRequest{FeatureID: 301, TagIDs: []int64{302, 303}, Content: []byte(`{"title":"second"}`), IsActive: true},
+	}, http.StatusConflict, conflict)
+
+	second := createBanner(t, adminToken, 302, []int64{302, 303}, `{"title":"second"}`)
+	taken := bannerRequest{FeatureID: 301, TagIDs: []int64{302}, Content: []byte(`{"title":"second"}`), IsActive: true}
+
+	step(t, "publish patch with a taken pair", call{
+		method: http.MethodPatch,
+		path:   fmt.Sprintf("/banner/%d", second),
+		query:  map[string][]string{"publish": {"true"}},
+		auth:   bearer(adminToken),
+		body:   taken,
+	}, http.StatusConflict, conflict)
+
+	// A draft claims nothing until it is published.
+	revisionID := draftRevision(t, adminToken, second, taken.FeatureID, taken.TagIDs, string(taken.Content))
+
+	step(t, "publish draft with a taken pair", call{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/banner/%d/publish", second),
+		query:  map[string][]string{"revision_id": {fmt.Sprint(revisionID)}},
+		auth:   bearer(adminToken),
+	}, http.StatusConflict, conflict)
+
+	getUserBanner(t, bearer(adminToken), 302, 303, "", http.StatusOK, json.Equal("content.title", "second"))
+
+	step(t, "targeted banner shares the pair", call{
+		method: http.MethodPost,
+		path:   "/banner",
+		auth:   bearer(adminToken),
+		body: map[string]any{
+			"feature_id": 301,
+			"tag_ids":    []int64{302},
+			"content":    map[string]string{"title": "targeted"},
+			"is_active":  true,
+			"targeting":  map[string][]string{"platforms": {"ios"}},
+		},
+	}, http.StatusOK, json.Present("banner_id"))
+}
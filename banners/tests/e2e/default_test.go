@@ -0,0 +1,62 @@
+package e2e
+
+import (
+	"github.com/ozontech/cute/asserts/json"
+	"net/http"
+	"testing"
+)
+
+func Test_DefaultBanner(t *testing.T) {
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 701, 701, 702)
+	register(t, adminToken, 702)
+
+	fallbackID := createBanner(t, adminToken, 701, []int64{701}, `{"title":"default"}`)
+	otherFeatureID := createBanner(t, adminToken, 702, []int64{701}, `{"title":"other feature"}`)
+
+	getUserBanner(t, bearer(adminToken), 701, 702, "", http.StatusNotFound, json.Equal("error", "banner not found"))
+
+	defaultBanner := call{
+		method: http.MethodGet,
+		path:   "/features/701/default",
+		auth:   bearer(adminToken),
+	}
+	step(t, "no default banner", defaultBanner, http.StatusNotFound,
+		json.Equal("error", "feature has no default banner"))
+
+	step(t, "default banner must be on the feature", call{
+		method: http.MethodPut,
+		path:   "/features/701/default",
+		auth:   bearer(adminToken),
+		body:   map[string]int{"banner_id": otherFeatureID},
+	}, http.StatusConflict, json.Equal("error", "chosen revision of the banner is not on the feature"))
+
+	step(t, "set default banner", call{
+		method: http.MethodPut,
+		path:   "/features/701/default",
+		auth:   bearer(adminToken),
+		body:   map[string]int{"banner_id": fallbackID},
+	}, http.StatusOK, json.Present("banner_id"))
+	step(t, "get default banner", defaultBanner, http.StatusOK, json.Present("banner_id"))
+
+	got := getUserBanner(t, bearer(adminToken), 701, 702, "", http.StatusOK, json.Equal("content.title", "default"))
+	if !got.Fallback {
+		t.Fatal("banner of an untagged pair is not marked as the fallback")
+	}
+
+	got = getUserBanner(t, bearer(adminToken), 701, 701, "", http.StatusOK, json.Equal("content.title", "default"))
+	if got.Fallback {
+		t.Fatal("banner found by its tag is marked as the fallback")
+	}
+
+	deleteDefault := call{
+		method: http.MethodDelete,
+		path:   "/features/701/default",
+		auth:   bearer(adminToken),
+	}
+	step(t, "delete default banner", deleteDefault, http.StatusNoContent)
+	step(t, "delete default banner twice", deleteDefault, http.StatusNotFound,
+		json.Equal("error", "feature has no default banner"))
+
+	getUserBanner(t, bearer(adminToken), 701, 702, "", http.StatusNotFound, json.Equal("error", "banner not found"))
+}
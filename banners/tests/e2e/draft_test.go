@@ -0,0 +1,81 @@
+package e2e
+
+import (
+	"fmt"
+	"github.com/ozontech/cute/asserts/json"
+	"net/http"
+	"testing"
+)
+
+// revisionStates maps the revisions of the banner to their states.
+func revisionStates(t *testing.T, token string, bannerID int) map[int]string {
+	t.Helper()
+
+	body := step(t, "list revisions", call{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/banner_revisions/%d", bannerID),
+		auth:   bearer(token),
+	}, http.StatusOK)
+
+	states := map[int]string{}
+	for _, revision := range decode[[]struct {
+		RevisionID int    `json:"revision_id"`
+		State      string `json:"state"`
+	}](t, body) {
+		states[revision.RevisionID] = revision.State
+	}
+
+	return states
+}
+
+func Test_DraftPublish(t *testing.T) {
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 401, 401)
+
+	editor := createUser(t, adminToken, "draft-editor@e2e.com", "editor").Token
+	publisher := createUser(t, adminToken, "draft-publisher@e2e.com", "publisher").Token
+
+	bannerID := createBanner(t, editor, 401, []int64{401}, `{"title":"first"}`)
+	first := latestRevision(t, editor, bannerID)
+
+	second := draftRevision(t, editor, bannerID, 401, []int64{401}, `{"title":"second"}`)
+	if states := revisionStates(t, editor, bannerID); states[first] != "live" || states[second] != "draft" {
+		t.Fatalf("states = %v, want %d live and %d draft", states, first, second)
+	}
+
+	getUserBanner(t, bearer(editor), 401, 401, "", http.StatusOK, json.Equal("content.title", "first"))
+
+	step(t, "diff draft against live", call{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/banner/%d/diff", bannerID),
+		query:  map[string][]string{"from": {fmt.Sprint(first)}, "to": {fmt.Sprint(second)}},
+		auth:   bearer(editor),
+	}, http.StatusOK, json.Equal("content[0].op", "replace"), json.Equal("content[0].path", "/title"))
+
+	publish := call{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/banner/%d/publish", bannerID),
+		auth:   bearer(publisher),
+	}
+
+	publish.query = map[string][]string{"revision_id": {"999999"}}
+	step(t, "publish unknown revision", publish, http.StatusNotFound, json.Equal("error", "revision not found"))
+
+	publish.query = map[string][]string{"revision_id": {fmt.Sprint(second)}}
+	step(t, "publish draft", publish, http.StatusOK, json.Equal("message", "Successfully published a revision"))
+
+	if states := revisionStates(t, editor, bannerID); states[first] != "published" || states[second] != "live" {
+		t.Fatalf("states = %v, want %d published and %d live", states, first, second)
+	}
+	getUserBanner(t, bearer(editor), 401, 401, "", http.StatusOK, json.Equal("content.title", "second"))
+
+	// Without a revision the newest one is published.
+	third := draftRevision(t, editor, bannerID, 401, []int64{401}, `{"title":"third"}`)
+	publish.query = nil
+	step(t, "publish newest draft", publish, http.StatusOK)
+
+	if states := revisionStates(t, editor, bannerID); states[third] != "live" {
+		t.Fatalf("states = %v, want %d live", states, third)
+	}
+	getUserBanner(t, bearer(editor), 401, 401, "", http.StatusOK, json.Equal("content.title", "third"))
+}
@@ -9,10 +9,28 @@ import (
 	"github.com/ozontech/cute/asserts/json"
 	"io"
 	"net/http"
+	"os"
 	"strings"
 	"testing"
 )
 
+// The suite logs in as the bootstrap admin of the server under test, so it
+// needs the credentials the server was started with. make test passes the
+// same BOOTSTRAP_ADMIN_EMAIL and BOOTSTRAP_ADMIN_PASSWORD to both.
+var (
+	adminEmail    = os.Getenv("BOOTSTRAP_ADMIN_EMAIL")
+	adminPassword = os.Getenv("BOOTSTRAP_ADMIN_PASSWORD")
+)
+
+func TestMain(m *testing.M) {
+	if adminEmail == "" || adminPassword == "" {
+		fmt.Fprintln(os.Stderr, "e2e: BOOTSTRAP_ADMIN_EMAIL and BOOTSTRAP_ADMIN_PASSWORD must be set")
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
 func Test_CreateUser(t *testing.T) {
 	tests := []*cute.Test{
 		{
@@ -69,7 +87,7 @@ func Test_CreateUser(t *testing.T) {
 			},
 		},
 		{
-			Name:       "register admin",
+			Name:       "register admin is forbidden",
 			Middleware: nil,
 			Request: &cute.Request{
 				Builders: []cute.RequestBuilder{
@@ -87,9 +105,9 @@ func Test_CreateUser(t *testing.T) {
 				},
 			},
 			Expect: &cute.Expect{
-				Code: http.StatusCreated,
+				Code: http.StatusForbidden,
 				AssertBody: []cute.AssertBody{
-					json.Equal("message", "Successfully created user."),
+					json.Equal("error", "signup is limited to the user role"),
 				},
 				AssertHeaders: []cute.AssertHeaders{
 					headers.Present("Content-Type"),
@@ -240,7 +258,7 @@ func Test_LoginUser(t *testing.T) {
 			},
 		},
 		{
-			Name:       "register admin",
+			Name:       "register admin is forbidden",
 			Middleware: nil,
 			Request: &cute.Request{
 				Builders: []cute.RequestBuilder{
@@ -258,9 +276,9 @@ func Test_LoginUser(t *testing.T) {
 				},
 			},
 			Expect: &cute.Expect{
-				Code: http.StatusCreated,
+				Code: http.StatusForbidden,
 				AssertBody: []cute.AssertBody{
-					json.Equal("message", "Successfully created user."),
+					json.Equal("error", "signup is limited to the user role"),
 				},
 				AssertHeaders: []cute.AssertHeaders{
 					headers.Present("Content-Type"),
@@ -312,9 +330,9 @@ func Test_LoginUser(t *testing.T) {
 				},
 			},
 			Expect: &cute.Expect{
-				Code: http.StatusBadRequest,
+				Code: http.StatusUnauthorized,
 				AssertBody: []cute.AssertBody{
-					json.Equal("error", "failed to login user"),
+					json.Equal("error", "invalid email or password"),
 					json.NotPresent("token"),
 				},
 				AssertHeaders: []cute.AssertHeaders{
@@ -411,6 +429,11 @@ func Test_LoginUser(t *testing.T) {
 }
 
 func Test_GetUser(t *testing.T) {
+	// Banners may only refer to registered features and tags.
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 3, 1, 2, 3, 4)
+	register(t, adminToken, 4)
+
 	tests := []*cute.Test{
 		{
 			Name:       "not authenticated",
@@ -465,7 +488,7 @@ func Test_GetUser(t *testing.T) {
 			},
 		},
 		{
-			Name:       "register admin",
+			Name:       "register admin is forbidden",
 			Middleware: nil,
 			Request: &cute.Request{
 				Builders: []cute.RequestBuilder{
@@ -483,9 +506,9 @@ func Test_GetUser(t *testing.T) {
 				},
 			},
 			Expect: &cute.Expect{
-				Code: http.StatusCreated,
+				Code: http.StatusForbidden,
 				AssertBody: []cute.AssertBody{
-					json.Equal("message", "Successfully created user."),
+					json.Equal("error", "signup is limited to the user role"),
 				},
 				AssertHeaders: []cute.AssertHeaders{
 					headers.Present("Content-Type"),
@@ -547,8 +570,8 @@ func Test_GetUser(t *testing.T) {
 						Email    string `json:"email"`
 						Password string `json:"password"`
 					}{
-						Email:    "admin3@admin.com",
-						Password: "opopop111",
+						Email:    adminEmail,
+						Password: adminPassword,
 					}),
 					cute.WithMethod(http.MethodPost),
 				},
@@ -618,8 +641,8 @@ func Test_GetUser(t *testing.T) {
 						Email    string `json:"email"`
 						Password string `json:"password"`
 					}{
-						Email:    "admin3@admin.com",
-						Password: "opopop111",
+						Email:    adminEmail,
+						Password: adminPassword,
 					}),
 					cute.WithMethod(http.MethodPost),
 				},
@@ -684,8 +707,8 @@ func Test_GetUser(t *testing.T) {
 						Email    string `json:"email"`
 						Password string `json:"password"`
 					}{
-						Email:    "admin3@admin.com",
-						Password: "opopop111",
+						Email:    adminEmail,
+						Password: adminPassword,
 					}),
 					cute.WithMethod(http.MethodPost),
 				},
@@ -755,8 +778,8 @@ func Test_GetUser(t *testing.T) {
 						Email    string `json:"email"`
 						Password string `json:"password"`
 					}{
-						Email:    "admin3@admin.com",
-						Password: "opopop111",
+						Email:    adminEmail,
+						Password: adminPassword,
 					}),
 					cute.WithMethod(http.MethodPost),
 				},
@@ -834,8 +857,8 @@ func Test_GetUser(t *testing.T) {
 						Email    string `json:"email"`
 						Password string `json:"password"`
 					}{
-						Email:    "admin3@admin.com",
-						Password: "opopop111",
+						Email:    adminEmail,
+						Password: adminPassword,
 					}),
 					cute.WithMethod(http.MethodPost),
 				},
@@ -906,8 +929,8 @@ func Test_GetUser(t *testing.T) {
 						Email    string `json:"email"`
 						Password string `json:"password"`
 					}{
-						Email:    "admin3@admin.com",
-						Password: "opopop111",
+						Email:    adminEmail,
+						Password: adminPassword,
 					}),
 					cute.WithMethod(http.MethodPost),
 				},
@@ -984,8 +1007,8 @@ func Test_GetUser(t *testing.T) {
 						Email    string `json:"email"`
 						Password string `json:"password"`
 					}{
-						Email:    "admin3@admin.com",
-						Password: "opopop111",
+						Email:    adminEmail,
+						Password: adminPassword,
 					}),
 					cute.WithMethod(http.MethodPost),
 				},
@@ -1056,8 +1079,8 @@ func Test_GetUser(t *testing.T) {
 						Email    string `json:"email"`
 						Password string `json:"password"`
 					}{
-						Email:    "admin3@admin.com",
-						Password: "opopop111",
+						Email:    adminEmail,
+						Password: adminPassword,
 					}),
 					cute.WithMethod(http.MethodPost),
 				},
@@ -1112,7 +1135,7 @@ func Test_GetUser(t *testing.T) {
 							ExpectStatus(http.StatusUnauthorized).
 							AssertBody(
 								json.NotPresent("content"),
-								json.Equal("error", "you are not admin")).
+								json.Equal("error", "not allowed to view inactive banners")).
 							AssertHeaders(
 								headers.Present("Content-Type")).
 							ExecuteTest(context.Background(), t)
@@ -0,0 +1,125 @@
+package e2e
+
+import (
+	"fmt"
+	"github.com/ozontech/cute/asserts/json"
+	"net/http"
+	"testing"
+)
+
+type experimentRequest struct {
+	FeatureID int64     `json:"feature_id"`
+	TagID     int64     `json:"tag_id"`
+	Name      string    `json:"name"`
+	Variants  []variant `json:"variants"`
+}
+
+type variant struct {
+	Key     string `json:"key"`
+	Weight  int    `json:"weight"`
+	Content any    `json:"content"`
+}
+
+func Test_Experiment(t *testing.T) {
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 801, 801)
+	createBanner(t, adminToken, 801, []int64{801}, `{"title":"live"}`)
+
+	request := experimentRequest{
+		FeatureID: 801,
+		TagID:     801,
+		Name:      "e2e split",
+		Variants: []variant{
+			{Key: "a", Weight: 50, Content: map[string]string{"title": "a"}},
+			{Key: "b", Weight: 50, Content: map[string]string{"title": "b"}},
+		},
+	}
+	create := call{
+		method: http.MethodPost,
+		path:   "/experiments",
+		auth:   bearer(adminToken),
+		body:   request,
+	}
+
+	single := request
+	single.Variants = request.Variants[:1]
+	create.body = single
+	step(t, "experiment needs two variants", create, http.StatusBadRequest, json.Present("error"))
+
+	unregistered := request
+	unregistered.FeatureID = 899
+	create.body = unregistered
+	step(t, "experiment on an unregistered feature", create, http.StatusUnprocessableEntity, json.Present("error"))
+
+	create.body = request
+	body := step(t, "create experiment", create, http.StatusCreated, json.Equal("status", "running"))
+	experimentID := decode[struct {
+		ID int `json:"id"`
+	}](t, body).ID
+
+	step(t, "second experiment for the pair", create, http.StatusConflict,
+		json.Equal("error", "another experiment is running for the feature and tag"))
+
+	seen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		userKey := fmt.Sprintf("user-%d", i)
+
+		got := getUserBanner(t, bearer(adminToken), 801, 801, userKey, http.StatusOK)
+		if got.Variant != "a" && got.Variant != "b" {
+			t.Fatalf("user %s got variant %q", userKey, got.Variant)
+		}
+		// The content of the variant is given instead of the banner, and
+		// the same variant every time.
+		getUserBanner(t, bearer(adminToken), 801, 801, userKey, http.StatusOK,
+			json.Equal("variant", got.Variant), json.Equal("content.title", got.Variant))
+
+		seen[got.Variant] = true
+	}
+	if len(seen) != 2 {
+		t.Fatalf("20 users saw only the variants %v", seen)
+	}
+
+	if got := getUserBanner(t, bearer(adminToken), 801, 801, "", http.StatusOK, json.Equal("content.title", "live")); got.Variant != "" {
+		t.Fatalf("user without a key got variant %q", got.Variant)
+	}
+
+	// The variants were never approved, so the feature can not require
+	// approval while they are served, nor get an experiment after.
+	policy := call{
+		method: http.MethodPut,
+		path:   "/features/801/policy",
+		auth:   bearer(adminToken),
+		body:   map[string]bool{"requires_approval": true},
+	}
+	step(t, "require approval while experiment runs", policy, http.StatusConflict,
+		json.Equal("error", "an experiment is running for the feature"))
+
+	stop := call{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/experiments/%d/stop", experimentID),
+		auth:   bearer(adminToken),
+	}
+	step(t, "stop experiment", stop, http.StatusOK, json.Equal("status", "stopped"))
+	step(t, "stop experiment twice", stop, http.StatusConflict,
+		json.Equal("error", "experiment has already been stopped"))
+
+	step(t, "get stopped experiment", call{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/experiments/%d", experimentID),
+		auth:   bearer(adminToken),
+	}, http.StatusOK, json.Equal("status", "stopped"), json.Present("stopped_at"))
+
+	if got := getUserBanner(t, bearer(adminToken), 801, 801, "user-0", http.StatusOK, json.Equal("content.title", "live")); got.Variant != "" {
+		t.Fatalf("user got variant %q after the experiment stopped", got.Variant)
+	}
+
+	step(t, "require approval", policy, http.StatusOK)
+	step(t, "experiment on a feature requiring approval", create, http.StatusForbidden,
+		json.Equal("error", "feature requires approval, experiments are not allowed"))
+
+	step(t, "get unknown experiment", call{
+		method: http.MethodGet,
+		path:   "/experiments/999999",
+		auth:   bearer(adminToken),
+	}, http.StatusNotFound, json.Equal("error", "experiment not found"))
+}
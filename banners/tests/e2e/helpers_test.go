@@ -0,0 +1,236 @@
+package e2e
+
+import (
+	"context"
+	json2 "encoding/json"
+	"fmt"
+	"github.com/ozontech/cute"
+	"io"
+	"net/http"
+	"testing"
+)
+
+const baseURL = "http://bannerage-e2e:8080"
+
+// userPassword is the password of the users the scenarios create.
+const userPassword = "opopop111"
+
+// call is a request made by a step of a scenario. auth is the whole value
+// of the Authorization header, see bearer and apiKey.
+type call struct {
+	method string
+	path   string
+	query  map[string][]string
+	auth   string
+	body   any
+}
+
+func bearer(token string) string {
+	return "Bearer " + token
+}
+
+func apiKey(key string) string {
+	return "ApiKey " + key
+}
+
+// step runs the call as a test that expects the status and the asserts, and
+// returns the response body. The scenario stops at the first failed step,
+// since later steps build on what earlier ones created.
+func step(t *testing.T, title string, c call, status int, asserts ...cute.AssertBody) []byte {
+	t.Helper()
+
+	builders := []cute.RequestBuilder{
+		cute.WithURI(baseURL + c.path),
+		cute.WithMethod(c.method),
+		cute.WithHeadersKV("Content-Type", "application/json"),
+	}
+	if c.query != nil {
+		builders = append(builders, cute.WithQuery(c.query))
+	}
+	if c.auth != "" {
+		builders = append(builders, cute.WithHeadersKV("Authorization", c.auth))
+	}
+	if c.body != nil {
+		builders = append(builders, cute.WithMarshalBody(c.body))
+	}
+
+	var body []byte
+	results := cute.NewTestBuilder().
+		Title(title).
+		Create().
+		RequestBuilder(builders...).
+		ExpectStatus(status).
+		AssertBody(asserts...).
+		After(func(response *http.Response, errors []error) error {
+			var err error
+			body, err = io.ReadAll(response.Body)
+			return err
+		}).
+		ExecuteTest(context.Background(), t)
+
+	for _, result := range results {
+		if len(result.GetErrors()) > 0 {
+			t.FailNow()
+		}
+	}
+
+	return body
+}
+
+func decode[T any](t *testing.T, body []byte) T {
+	t.Helper()
+
+	var v T
+	if err := json2.Unmarshal(body, &v); err != nil {
+		t.Fatalf("failed to decode %s: %v", body, err)
+	}
+
+	return v
+}
+
+type tokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func login(t *testing.T, email, password string) tokens {
+	t.Helper()
+
+	body := step(t, "login "+email, call{
+		method: http.MethodPost,
+		path:   "/login",
+		body:   map[string]string{"email": email, "password": password},
+	}, http.StatusOK)
+
+	return decode[tokens](t, body)
+}
+
+func loginAdmin(t *testing.T) string {
+	t.Helper()
+
+	return login(t, adminEmail, adminPassword).Token
+}
+
+// createUser creates a user with the role as the admin and logs it in.
+func createUser(t *testing.T, adminToken, email, role string) tokens {
+	t.Helper()
+
+	step(t, "create "+role+" "+email, call{
+		method: http.MethodPost,
+		path:   "/users",
+		auth:   bearer(adminToken),
+		body:   map[string]string{"email": email, "password": userPassword, "role": role},
+	}, http.StatusCreated)
+
+	return login(t, email, userPassword)
+}
+
+// register adds the feature and the tags to the registries, which banners
+// may only refer to once they are there.
+func register(t *testing.T, adminToken string, featureID int64, tagIDs ...int64) {
+	t.Helper()
+
+	entries := map[string][]int64{"/features": {featureID}, "/tags": tagIDs}
+	for _, path := range []string{"/features", "/tags"} {
+		for _, id := range entries[path] {
+			step(t, "register "+path[1:], call{
+				method: http.MethodPost,
+				path:   path,
+				auth:   bearer(adminToken),
+				body:   map[string]any{"id": id, "name": fmt.Sprintf("e2e%s-%d", path[1:], id)},
+			}, http.StatusCreated)
+		}
+	}
+}
+
+type bannerRequest struct {
+	FeatureID int64            `json:"feature_id"`
+	TagIDs    []int64          `json:"tag_ids"`
+	Content   json2.RawMessage `json:"content"`
+	IsActive  bool             `json:"is_active"`
+}
+
+// createBanner creates an active banner and returns its ID.
+func createBanner(t *testing.T, token string, featureID int64, tagIDs []int64, content string) int {
+	t.Helper()
+
+	body := step(t, "create banner", call{
+		method: http.MethodPost,
+		path:   "/banner",
+		auth:   bearer(token),
+		body:   bannerRequest{FeatureID: featureID, TagIDs: tagIDs, Content: json2.RawMessage(content), IsActive: true},
+	}, http.StatusOK)
+
+	return decode[struct {
+		BannerID int `json:"banner_id"`
+	}](t, body).BannerID
+}
+
+// draftRevision adds a draft revision with the content to the banner and
+// returns the ID of the revision.
+func draftRevision(t *testing.T, token string, bannerID int, featureID int64, tagIDs []int64, content string) int {
+	t.Helper()
+
+	step(t, "draft revision", call{
+		method: http.MethodPatch,
+		path:   fmt.Sprintf("/banner/%d", bannerID),
+		auth:   bearer(token),
+		body:   bannerRequest{FeatureID: featureID, TagIDs: tagIDs, Content: json2.RawMessage(content), IsActive: true},
+	}, http.StatusOK)
+
+	return latestRevision(t, token, bannerID)
+}
+
+func latestRevision(t *testing.T, token string, bannerID int) int {
+	t.Helper()
+
+	body := step(t, "list revisions", call{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/banner_revisions/%d", bannerID),
+		auth:   bearer(token),
+	}, http.StatusOK)
+
+	latest := 0
+	for _, revision := range decode[[]struct {
+		RevisionID int `json:"revision_id"`
+	}](t, body) {
+		latest = max(latest, revision.RevisionID)
+	}
+
+	return latest
+}
+
+type userBanner struct {
+	Content      json2.RawMessage `json:"content"`
+	MatchedTagID int64            `json:"matched_tag_id"`
+	Fallback     bool             `json:"fallback"`
+	Variant      string           `json:"variant"`
+	Canary       bool             `json:"canary"`
+}
+
+// getUserBanner reads the live banner past the cache, so that changes made
+// by a scenario show at once.
+func getUserBanner(t *testing.T, auth string, featureID, tagID int64, userKey string, status int, asserts ...cute.AssertBody) userBanner {
+	t.Helper()
+
+	query := map[string][]string{
+		"feature_id":        {fmt.Sprint(featureID)},
+		"tag_id":            {fmt.Sprint(tagID)},
+		"use_last_revision": {"true"},
+	}
+	if userKey != "" {
+		query["user_key"] = []string{userKey}
+	}
+
+	body := step(t, "get user banner", call{
+		method: http.MethodGet,
+		path:   "/user_banner",
+		query:  query,
+		auth:   auth,
+	}, status, asserts...)
+	if status != http.StatusOK {
+		return userBanner{}
+	}
+
+	return decode[userBanner](t, body)
+}
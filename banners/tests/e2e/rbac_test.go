@@ -0,0 +1,89 @@
+package e2e
+
+import (
+	"fmt"
+	"github.com/ozontech/cute/asserts/json"
+	"net/http"
+	"testing"
+)
+
+func Test_RBAC(t *testing.T) {
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 101, 101)
+
+	plain := createUser(t, adminToken, "rbac-user@e2e.com", "user").Token
+	viewer := createUser(t, adminToken, "rbac-viewer@e2e.com", "viewer").Token
+	editor := createUser(t, adminToken, "rbac-editor@e2e.com", "editor").Token
+	publisher := createUser(t, adminToken, "rbac-publisher@e2e.com", "publisher").Token
+
+	denied := json.Equal("error", "permission denied")
+
+	step(t, "user cannot list", call{
+		method: http.MethodGet,
+		path:   "/features",
+		auth:   bearer(plain),
+	}, http.StatusForbidden, denied)
+
+	step(t, "viewer lists", call{
+		method: http.MethodGet,
+		path:   "/features",
+		auth:   bearer(viewer),
+	}, http.StatusOK)
+
+	step(t, "viewer cannot create banner", call{
+		method: http.MethodPost,
+		path:   "/banner",
+		auth:   bearer(viewer),
+		body:   bannerRequest{FeatureID: 101, TagIDs: []int64{101}, Content: []byte(`{"title":"rbac"}`), IsActive: true},
+	}, http.StatusForbidden, denied)
+
+	bannerID := createBanner(t, editor, 101, []int64{101}, `{"title":"rbac"}`)
+
+	step(t, "editor cannot publish on patch", call{
+		method: http.MethodPatch,
+		path:   fmt.Sprintf("/banner/%d", bannerID),
+		query:  map[string][]string{"publish": {"true"}},
+		auth:   bearer(editor),
+		body:   bannerRequest{FeatureID: 101, TagIDs: []int64{101}, Content: []byte(`{"title":"rbac 2"}`), IsActive: true},
+	}, http.StatusForbidden, denied)
+
+	revisionID := draftRevision(t, editor, bannerID, 101, []int64{101}, `{"title":"rbac 2"}`)
+	publish := call{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/banner/%d/publish", bannerID),
+		query:  map[string][]string{"revision_id": {fmt.Sprint(revisionID)}},
+	}
+
+	publish.auth = bearer(editor)
+	step(t, "editor cannot publish", publish, http.StatusForbidden, denied)
+
+	publish.auth = bearer(publisher)
+	step(t, "publisher publishes", publish, http.StatusOK,
+		json.Equal("message", "Successfully published a revision"))
+
+	step(t, "publisher cannot delete banner", call{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("/banner/%d", bannerID),
+		auth:   bearer(publisher),
+	}, http.StatusForbidden, denied)
+
+	step(t, "publisher cannot manage users", call{
+		method: http.MethodPost,
+		path:   "/users",
+		auth:   bearer(publisher),
+		body:   map[string]string{"email": "rbac-other@e2e.com", "password": userPassword, "role": "admin"},
+	}, http.StatusForbidden, denied)
+
+	admin := createUser(t, adminToken, "rbac-admin@e2e.com", "admin").Token
+	step(t, "admin cannot manage roles", call{
+		method: http.MethodGet,
+		path:   "/roles",
+		auth:   bearer(admin),
+	}, http.StatusForbidden, denied)
+
+	step(t, "superadmin manages roles", call{
+		method: http.MethodGet,
+		path:   "/roles",
+		auth:   bearer(adminToken),
+	}, http.StatusOK)
+}
@@ -0,0 +1,158 @@
+package e2e
+
+import (
+	"banners/domain/models"
+	"banners/internal/storage/postgresql"
+	"context"
+	"database/sql"
+	"fmt"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"net/http"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+// dataSourceName points at the database of the server under test. make
+// test gives the suite the same DB_* variables as the server.
+func dataSourceName(t *testing.T) string {
+	t.Helper()
+
+	host := os.Getenv("DB_HOST")
+	if host == "" {
+		t.Fatal("DB_HOST, DB_PORT, DB_NAME, DB_USER and DB_PASSWORD must be set")
+	}
+
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), host, os.Getenv("DB_PORT"), os.Getenv("DB_NAME"))
+}
+
+func revisionIDs(t *testing.T, token string, bannerID int) []int {
+	t.Helper()
+
+	body := step(t, "list revisions", call{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/banner_revisions/%d", bannerID),
+		query:  map[string][]string{"limit": {"100"}},
+		auth:   bearer(token),
+	}, http.StatusOK)
+
+	var ids []int
+	for _, revision := range decode[[]struct {
+		RevisionID int `json:"revision_id"`
+	}](t, body) {
+		ids = append(ids, revision.RevisionID)
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+// Test_Retention checks the revisions the dry run reports and the pruning
+// deletes against the policy of the server: a revision goes only if it is
+// neither one of the KeepLast newest of its banner nor younger than
+// KeepFor, and it was never published, is not waiting for an approval and
+// is not rolled out.
+func Test_Retention(t *testing.T) {
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 1101, 1101)
+
+	bannerID := createBanner(t, adminToken, 1101, []int64{1101}, `{"title":"published"}`)
+	for i := 2; i <= 16; i++ {
+		draftRevision(t, adminToken, bannerID, 1101, []int64{1101}, fmt.Sprintf(`{"title":"draft %d"}`, i))
+	}
+
+	revisions := revisionIDs(t, adminToken, bannerID)
+	if len(revisions) != 16 {
+		t.Fatalf("banner has %d revisions, want 16", len(revisions))
+	}
+	published, pending, rolledOut, young := revisions[0], revisions[1], revisions[2], revisions[3]
+
+	// The first revision stays in the publication history once another
+	// one is live.
+	step(t, "choose newest revision", call{
+		method: http.MethodPost,
+		path:   "/choose_revision",
+		query:  map[string][]string{"banner_id": {fmt.Sprint(bannerID)}, "revision_id": {fmt.Sprint(revisions[15])}},
+		auth:   bearer(adminToken),
+	}, http.StatusOK)
+
+	requestApproval(t, adminToken, bannerID, pending)
+	step(t, "start rollout", call{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/banner/%d/rollout", bannerID),
+		auth:   bearer(adminToken),
+		body:   map[string]int{"revision_id": rolledOut, "percent": 10},
+	}, http.StatusCreated)
+
+	body := step(t, "get retention policy", call{
+		method: http.MethodGet,
+		path:   "/retention/dry_run",
+		auth:   bearer(adminToken),
+	}, http.StatusOK)
+	preview := decode[models.PrunePreview](t, body)
+
+	keepFor, err := time.ParseDuration(preview.KeepFor)
+	if err != nil {
+		t.Fatalf("failed to parse keep_for %q: %v", preview.KeepFor, err)
+	}
+	if preview.KeepLast != 10 || keepFor <= 0 {
+		t.Fatalf("server keeps the last %d revisions for %s, the scenario needs the last 10 and a duration", preview.KeepLast, keepFor)
+	}
+
+	db, err := sql.Open("pgx", dataSourceName(t))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Every revision but one ages past KeepFor. The old ones among the ten
+	// newest stay through KeepLast, the young one through KeepFor.
+	_, err = db.ExecContext(context.Background(),
+		"UPDATE banner_revisions SET created_at = NOW() - make_interval(secs => $1) WHERE banner_id = $2 AND revision_id <> $3",
+		(keepFor + 24*time.Hour).Seconds(), bannerID, young)
+	if err != nil {
+		t.Fatalf("failed to age revisions: %v", err)
+	}
+
+	want := revisions[4:6]
+
+	body = step(t, "dry run", call{
+		method: http.MethodGet,
+		path:   "/retention/dry_run",
+		auth:   bearer(adminToken),
+	}, http.StatusOK)
+	var prunable []int
+	for _, revision := range decode[models.PrunePreview](t, body).Revisions {
+		if revision.BannerID == int64(bannerID) {
+			prunable = append(prunable, int(revision.RevisionID))
+		}
+	}
+	if !slices.Equal(prunable, want) {
+		t.Fatalf("dry run reports revisions %v, want %v", prunable, want)
+	}
+
+	repo, err := postgresql.New(dataSourceName(t), 1)
+	if err != nil {
+		t.Fatalf("failed to connect storage: %v", err)
+	}
+	defer repo.Close()
+
+	_, err = repo.PruneRevisionsStorage(context.Background(), models.RetentionPolicy{KeepLast: preview.KeepLast, KeepFor: keepFor})
+	if err != nil {
+		t.Fatalf("failed to prune revisions: %v", err)
+	}
+
+	left := revisionIDs(t, adminToken, bannerID)
+	for _, id := range want {
+		if slices.Contains(left, id) {
+			t.Errorf("revision %d was not pruned", id)
+		}
+	}
+	for _, id := range append([]int{published, pending, rolledOut, young}, revisions[6:]...) {
+		if !slices.Contains(left, id) {
+			t.Errorf("revision %d was pruned", id)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package e2e
+
+import (
+	"fmt"
+	"github.com/ozontech/cute/asserts/json"
+	"net/http"
+	"testing"
+)
+
+// publishRevision patches the banner with the content, publishing it at
+// once, and returns the ID of the new live revision.
+func publishRevision(t *testing.T, token string, bannerID int, featureID int64, tagIDs []int64, content string) int {
+	t.Helper()
+
+	step(t, "publish revision", call{
+		method: http.MethodPatch,
+		path:   fmt.Sprintf("/banner/%d", bannerID),
+		query:  map[string][]string{"publish": {"true"}},
+		auth:   bearer(token),
+		body:   bannerRequest{FeatureID: featureID, TagIDs: tagIDs, Content: []byte(content), IsActive: true},
+	}, http.StatusOK)
+
+	return latestRevision(t, token, bannerID)
+}
+
+func Test_Rollback(t *testing.T) {
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 601, 601)
+
+	bannerID := createBanner(t, adminToken, 601, []int64{601}, `{"title":"first"}`)
+	first := latestRevision(t, adminToken, bannerID)
+	publishRevision(t, adminToken, bannerID, 601, []int64{601}, `{"title":"second"}`)
+	third := publishRevision(t, adminToken, bannerID, 601, []int64{601}, `{"title":"third"}`)
+
+	rollback := func(steps string) call {
+		return call{
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/banner/%d/rollback", bannerID),
+			query:  map[string][]string{"steps": {steps}},
+			auth:   bearer(adminToken),
+		}
+	}
+
+	step(t, "rollback no steps", rollback("0"), http.StatusBadRequest, json.Equal("error", "steps must be positive"))
+
+	step(t, "rollback one step", rollback("1"), http.StatusOK, json.Equal("message", "Successfully rolled back banner"))
+	getUserBanner(t, bearer(adminToken), 601, 601, "", http.StatusOK, json.Equal("content.title", "second"))
+
+	// A rolled back publication is gone from the history, so the next step
+	// back skips it.
+	step(t, "rollback again", rollback("1"), http.StatusOK)
+	getUserBanner(t, bearer(adminToken), 601, 601, "", http.StatusOK, json.Equal("content.title", "first"))
+
+	step(t, "rollback past the first publication", rollback("1"), http.StatusConflict,
+		json.Equal("error", "banner has no earlier publication to roll back to"))
+
+	step(t, "republish", call{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/banner/%d/publish", bannerID),
+		query:  map[string][]string{"revision_id": {fmt.Sprint(third)}},
+		auth:   bearer(adminToken),
+	}, http.StatusOK)
+	getUserBanner(t, bearer(adminToken), 601, 601, "", http.StatusOK, json.Equal("content.title", "third"))
+
+	body := step(t, "rollback republished", rollback("1"), http.StatusOK)
+	if got := decode[struct {
+		RevisionID int `json:"revision_id"`
+	}](t, body).RevisionID; got != first {
+		t.Fatalf("rolled back to revision %d, want %d", got, first)
+	}
+
+	step(t, "rollback unknown banner", call{
+		method: http.MethodPost,
+		path:   "/banner/999999/rollback",
+		auth:   bearer(adminToken),
+	}, http.StatusNotFound, json.Equal("error", "banner not found"))
+}
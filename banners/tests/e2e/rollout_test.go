@@ -0,0 +1,123 @@
+package e2e
+
+import (
+	"fmt"
+	"github.com/ozontech/cute/asserts/json"
+	"net/http"
+	"testing"
+)
+
+func Test_Rollout(t *testing.T) {
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 901, 901, 902)
+
+	bannerID := createBanner(t, adminToken, 901, []int64{901}, `{"title":"live"}`)
+	live := latestRevision(t, adminToken, bannerID)
+	otherTags := draftRevision(t, adminToken, bannerID, 901, []int64{902}, `{"title":"other tags"}`)
+	candidate := draftRevision(t, adminToken, bannerID, 901, []int64{901}, `{"title":"canary"}`)
+
+	rollout := fmt.Sprintf("/banner/%d/rollout", bannerID)
+	start := func(title string, revisionID, percent, status int, message string) {
+		t.Helper()
+
+		asserts := json.Present("revision_id")
+		if message != "" {
+			asserts = json.Equal("error", message)
+		}
+
+		step(t, title, call{
+			method: http.MethodPost,
+			path:   rollout,
+			auth:   bearer(adminToken),
+			body:   map[string]int{"revision_id": revisionID, "percent": percent},
+		}, status, asserts)
+	}
+	getRollout := call{
+		method: http.MethodGet,
+		path:   rollout,
+		auth:   bearer(adminToken),
+	}
+	setPercent := func(percent int) {
+		t.Helper()
+
+		step(t, fmt.Sprintf("roll out to %d%%", percent), call{
+			method: http.MethodPatch,
+			path:   rollout,
+			auth:   bearer(adminToken),
+			body:   map[string]int{"percent": percent},
+		}, http.StatusOK)
+	}
+	// canaries counts the users of 20 that get the candidate, checking that
+	// they get its content and keep getting it.
+	canaries := func() int {
+		t.Helper()
+
+		reached := 0
+		for i := 0; i < 20; i++ {
+			userKey := fmt.Sprintf("user-%d", i)
+
+			got := getUserBanner(t, bearer(adminToken), 901, 901, userKey, http.StatusOK)
+			title := "live"
+			if got.Canary {
+				title = "canary"
+				reached++
+			}
+			getUserBanner(t, bearer(adminToken), 901, 901, userKey, http.StatusOK, json.Equal("content.title", title))
+		}
+
+		return reached
+	}
+
+	step(t, "no rollout", getRollout, http.StatusNotFound, json.Equal("error", "banner has no rollout"))
+
+	start("roll out the live revision", live, 10, http.StatusConflict, "revision is already live")
+	start("roll out other tags", otherTags, 10, http.StatusConflict,
+		"revision must have the feature and tags of the live revision")
+	start("roll out to too many", candidate, 101, http.StatusBadRequest, "percent must be between 0 and 100")
+
+	start("start rollout", candidate, 0, http.StatusCreated, "")
+	start("start second rollout", candidate, 10, http.StatusConflict, "banner already has a rollout")
+	step(t, "get rollout", getRollout, http.StatusOK, json.Present("percent"))
+
+	if got := canaries(); got != 0 {
+		t.Fatalf("0%% rollout reached %d users", got)
+	}
+
+	setPercent(50)
+	if got := canaries(); got == 0 || got == 20 {
+		t.Fatalf("50%% rollout reached %d of 20 users", got)
+	}
+
+	setPercent(100)
+	if got := canaries(); got != 20 {
+		t.Fatalf("100%% rollout reached %d of 20 users", got)
+	}
+
+	step(t, "abort rollout", call{
+		method: http.MethodDelete,
+		path:   rollout,
+		auth:   bearer(adminToken),
+	}, http.StatusNoContent)
+	step(t, "aborted rollout", getRollout, http.StatusNotFound, json.Equal("error", "banner has no rollout"))
+	if got := canaries(); got != 0 {
+		t.Fatalf("aborted rollout reached %d users", got)
+	}
+
+	start("restart rollout", candidate, 20, http.StatusCreated, "")
+
+	finalize := call{
+		method: http.MethodPost,
+		path:   rollout + "/finalize",
+		auth:   bearer(adminToken),
+	}
+	step(t, "finalize rollout", finalize, http.StatusOK, json.Equal("message", "Successfully finalized a rollout"))
+	step(t, "finalize rollout twice", finalize, http.StatusNotFound, json.Equal("error", "banner has no rollout"))
+
+	if states := revisionStates(t, adminToken, bannerID); states[candidate] != "live" {
+		t.Fatalf("states = %v, want %d live", states, candidate)
+	}
+	got := getUserBanner(t, bearer(adminToken), 901, 901, "user-0", http.StatusOK, json.Equal("content.title", "canary"))
+	if got.Canary {
+		t.Fatal("finalized revision is still given as a canary")
+	}
+}
@@ -0,0 +1,192 @@
+package e2e
+
+import (
+	"fmt"
+	"github.com/ozontech/cute/asserts/json"
+	"net/http"
+	"testing"
+)
+
+// userID pages through the users the token may manage and returns the ID
+// of the one with the email, or 0 if it is not among them.
+func userID(t *testing.T, token, email string) int64 {
+	t.Helper()
+
+	const limit = 100
+	for offset := 0; ; offset += limit {
+		body := step(t, "list users", call{
+			method: http.MethodGet,
+			path:   "/users",
+			query:  map[string][]string{"limit": {fmt.Sprint(limit)}, "offset": {fmt.Sprint(offset)}},
+			auth:   bearer(token),
+		}, http.StatusOK)
+
+		users := decode[[]struct {
+			ID    int64  `json:"id"`
+			Email string `json:"email"`
+		}](t, body)
+		for _, user := range users {
+			if user.Email == email {
+				return user.ID
+			}
+		}
+
+		if len(users) < limit {
+			return 0
+		}
+	}
+}
+
+func Test_Tenants(t *testing.T) {
+	adminToken := loginAdmin(t)
+	register(t, adminToken, 1001, 1001)
+
+	bannerA := createBanner(t, adminToken, 1001, []int64{1001}, `{"title":"tenant a"}`)
+	revisionA := latestRevision(t, adminToken, bannerA)
+
+	createUser(t, adminToken, "tenant-a-user@e2e.com", "user")
+	userA := userID(t, adminToken, "tenant-a-user@e2e.com")
+	if userA == 0 {
+		t.Fatal("user of tenant a is not listed for tenant a")
+	}
+
+	body := step(t, "create api key", call{
+		method: http.MethodPost,
+		path:   "/api_keys",
+		auth:   bearer(adminToken),
+		body:   map[string]any{"name": "e2e tenant a", "scopes": []string{"banner:read"}},
+	}, http.StatusCreated)
+	keyA := decode[createdAPIKey](t, body)
+
+	body = step(t, "create tenant", call{
+		method: http.MethodPost,
+		path:   "/tenants",
+		auth:   bearer(adminToken),
+		body:   map[string]string{"name": "e2e tenant b"},
+	}, http.StatusCreated, json.Equal("name", "e2e tenant b"))
+	tenantB := decode[struct {
+		ID int64 `json:"id"`
+	}](t, body).ID
+
+	step(t, "create admin of tenant b", call{
+		method: http.MethodPost,
+		path:   "/users",
+		auth:   bearer(adminToken),
+		body:   map[string]any{"email": "tenant-b-admin@e2e.com", "password": userPassword, "role": "admin", "tenant_id": tenantB},
+	}, http.StatusCreated)
+	adminB := login(t, "tenant-b-admin@e2e.com", userPassword).Token
+
+	// The registries and the feature and tag pairs are per tenant, so tenant
+	// b takes the same IDs without a conflict.
+	register(t, adminB, 1001, 1001)
+	bannerB := createBanner(t, adminB, 1001, []int64{1001}, `{"title":"tenant b"}`)
+
+	body = step(t, "list banners of tenant b", call{
+		method: http.MethodGet,
+		path:   "/banner",
+		query:  map[string][]string{"feature_id": {"1001"}, "tag_id": {"1001"}},
+		auth:   bearer(adminB),
+	}, http.StatusOK)
+	for _, banner := range decode[[]struct {
+		BannerID int `json:"banner_id"`
+	}](t, body) {
+		if banner.BannerID != bannerB {
+			t.Fatalf("banner %d of tenant a is listed for tenant b", banner.BannerID)
+		}
+	}
+
+	step(t, "list revisions of a banner of tenant a", call{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/banner_revisions/%d", bannerA),
+		auth:   bearer(adminB),
+	}, http.StatusNoContent)
+
+	step(t, "patch a banner of tenant a", call{
+		method: http.MethodPatch,
+		path:   fmt.Sprintf("/banner/%d", bannerA),
+		query:  map[string][]string{"publish": {"true"}},
+		auth:   bearer(adminB),
+		body:   bannerRequest{FeatureID: 1001, TagIDs: []int64{1001}, Content: []byte(`{"title":"hijacked"}`), IsActive: true},
+	}, http.StatusNotFound, json.Equal("error", "banner not found"))
+
+	step(t, "choose a revision of tenant a", call{
+		method: http.MethodPost,
+		path:   "/choose_revision",
+		query:  map[string][]string{"banner_id": {fmt.Sprint(bannerA)}, "revision_id": {fmt.Sprint(revisionA)}},
+		auth:   bearer(adminB),
+	}, http.StatusNotFound, json.Equal("error", "revision not found"))
+
+	step(t, "delete a banner of tenant a", call{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("/banner/%d", bannerA),
+		auth:   bearer(adminB),
+	}, http.StatusNotFound, json.Equal("error", "banner not found"))
+
+	if userID(t, adminB, "tenant-a-user@e2e.com") != 0 {
+		t.Fatal("user of tenant a is listed for tenant b")
+	}
+
+	userPath := fmt.Sprintf("/users/%d", userA)
+	notFound := json.Equal("error", "user not found")
+
+	step(t, "get a user of tenant a", call{
+		method: http.MethodGet,
+		path:   userPath,
+		auth:   bearer(adminB),
+	}, http.StatusNotFound, notFound)
+
+	step(t, "patch a user of tenant a", call{
+		method: http.MethodPatch,
+		path:   userPath,
+		auth:   bearer(adminB),
+		body:   map[string]any{"disabled": true},
+	}, http.StatusNotFound, notFound)
+
+	step(t, "delete a user of tenant a", call{
+		method: http.MethodDelete,
+		path:   userPath,
+		auth:   bearer(adminB),
+	}, http.StatusNotFound, notFound)
+
+	body = step(t, "list api keys of tenant b", call{
+		method: http.MethodGet,
+		path:   "/api_keys",
+		auth:   bearer(adminB),
+	}, http.StatusOK)
+	for _, key := range decode[[]createdAPIKey](t, body) {
+		if key.ID == keyA.ID {
+			t.Fatal("api key of tenant a is listed for tenant b")
+		}
+	}
+
+	step(t, "revoke an api key of tenant a", call{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("/api_keys/%d", keyA.ID),
+		auth:   bearer(adminB),
+	}, http.StatusNotFound, json.Equal("error", "api key not found"))
+
+	// Nothing tenant b tried reached tenant a.
+	step(t, "get user of tenant a", call{
+		method: http.MethodGet,
+		path:   userPath,
+		auth:   bearer(adminToken),
+	}, http.StatusOK, json.Equal("disabled", false))
+
+	getUserBanner(t, apiKey(keyA.Key), 1001, 1001, "", http.StatusOK, json.Equal("content.title", "tenant a"))
+	getUserBanner(t, bearer(adminB), 1001, 1001, "", http.StatusOK, json.Equal("content.title", "tenant b"))
+
+	// The cache is filled by the first read of tenant a and must not answer
+	// for tenant b.
+	cached := func(auth string) call {
+		return call{
+			method: http.MethodGet,
+			path:   "/user_banner",
+			query:  map[string][]string{"feature_id": {"1001"}, "tag_id": {"1001"}},
+			auth:   auth,
+		}
+	}
+	for _, title := range []string{"fill", "hit"} {
+		step(t, title+" cache of tenant a", cached(bearer(adminToken)), http.StatusOK, json.Equal("content.title", "tenant a"))
+		step(t, title+" cache of tenant b", cached(bearer(adminB)), http.StatusOK, json.Equal("content.title", "tenant b"))
+	}
+}
@@ -1,13 +1,115 @@
+-- Tenants separate the banners, users and API keys of teams sharing one
+-- deployment. Everything created before tenants existed belongs to the
+-- default tenant.
+CREATE TABLE tenants (
+   id SERIAL PRIMARY KEY,
+   name VARCHAR(50) UNIQUE NOT NULL,
+   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO tenants (name) VALUES ('default');
+
+CREATE TABLE roles (
+   name VARCHAR(30) PRIMARY KEY
+);
+
+CREATE TABLE permissions (
+   name VARCHAR(50) PRIMARY KEY
+);
+
+CREATE TABLE role_permissions (
+   role VARCHAR(30) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+   permission VARCHAR(50) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+   PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name) VALUES ('user'), ('viewer'), ('editor'), ('publisher'), ('admin'), ('superadmin');
+
+INSERT INTO permissions (name) VALUES
+   ('banner:read'),
+   ('banner:read_inactive'),
+   ('banner:list'),
+   ('banner:edit'),
+   ('banner:publish'),
+   ('banner:delete'),
+   ('banner:approve'),
+   ('feature:manage'),
+   ('tag:manage'),
+   ('retention:manage'),
+   ('user:manage'),
+   ('role:manage'),
+   ('apikey:manage'),
+   ('audit:read'),
+   ('tenant:manage');
+
+INSERT INTO role_permissions (role, permission) VALUES
+   ('user', 'banner:read'),
+   ('viewer', 'banner:read'),
+   ('viewer', 'banner:read_inactive'),
+   ('viewer', 'banner:list'),
+   ('editor', 'banner:read'),
+   ('editor', 'banner:read_inactive'),
+   ('editor', 'banner:list'),
+   ('editor', 'banner:edit'),
+   ('publisher', 'banner:read'),
+   ('publisher', 'banner:read_inactive'),
+   ('publisher', 'banner:list'),
+   ('publisher', 'banner:edit'),
+   ('publisher', 'banner:publish');
+
+-- Roles are shared by all tenants, so only superadmins may change them.
+INSERT INTO role_permissions (role, permission)
+SELECT 'admin', name FROM permissions WHERE name NOT IN ('role:manage', 'tenant:manage');
+
+INSERT INTO role_permissions (role, permission)
+SELECT 'superadmin', name FROM permissions;
+
 CREATE TABLE users (
    id SERIAL PRIMARY KEY,
    email VARCHAR(30) UNIQUE NOT NULL,
-   role VARCHAR(10) NOT NULL DEFAULT 'user',
-   password_hash VARCHAR(60) NOT NULL
+   role VARCHAR(30) NOT NULL DEFAULT 'user' REFERENCES roles(name),
+   password_hash VARCHAR(60) NOT NULL,
+   disabled BOOL NOT NULL DEFAULT FALSE,
+   tenant_id INT NOT NULL DEFAULT 1 REFERENCES tenants(id)
+);
+
+-- Registries of the feature and tag IDs banners refer to. IDs are chosen by
+-- the clients that request banners. A tenant that has not registered any
+-- feature or any tag yet may still use any ID of that kind.
+CREATE TABLE features (
+    tenant_id INT NOT NULL REFERENCES tenants(id),
+    id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_team VARCHAR(100) NOT NULL DEFAULT '',
+    archived BOOL NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, id),
+    UNIQUE (tenant_id, name)
 );
 
+-- Tags nest: users of a tag see the banners of its ancestors when the tag
+-- has none of its own for a feature. Children of a deleted tag become roots.
+CREATE TABLE tags (
+    tenant_id INT NOT NULL REFERENCES tenants(id),
+    id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_team VARCHAR(100) NOT NULL DEFAULT '',
+    archived BOOL NOT NULL DEFAULT FALSE,
+    parent_id INT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, id),
+    UNIQUE (tenant_id, name),
+    FOREIGN KEY (tenant_id, parent_id) REFERENCES tags(tenant_id, id) ON DELETE SET NULL (parent_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tags_parent_id ON tags(tenant_id, parent_id);
+
 CREATE TABLE banners (
     banner_id SERIAL PRIMARY KEY,
-    chosen_revision_id INT DEFAULT NULL
+    chosen_revision_id INT DEFAULT NULL,
+    tenant_id INT NOT NULL DEFAULT 1 REFERENCES tenants(id)
 );
 
 CREATE TABLE banner_revisions (
@@ -17,7 +119,21 @@ CREATE TABLE banner_revisions (
      is_active BOOL DEFAULT TRUE,
      content JSONB,
      created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+     created_by INT DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+     updated_by INT DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+     tenant_id INT NOT NULL DEFAULT 1 REFERENCES tenants(id),
+     starts_at TIMESTAMPTZ DEFAULT NULL,
+     ends_at TIMESTAMPTZ DEFAULT NULL,
+     -- Revisions are drafts until they are published.
+     published_at TIMESTAMP DEFAULT NULL,
+     -- The version of the feature schema the content was checked against.
+     schema_version INT DEFAULT NULL,
+     -- Rules on platform, app version, locale and custom attributes that
+     -- users must match on top of the tags. Targeted revisions share their
+     -- feature and tags with other banners.
+     targeting JSONB DEFAULT NULL,
+     CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
 );
 
 ALTER TABLE banner_revisions
@@ -38,10 +154,224 @@ CREATE TABLE revision_tags (
    PRIMARY KEY (revision_id, tag_id)
 );
 
+-- A feature and a tag identify at most one untargeted banner of a tenant.
+-- Each banner owns the feature and tags of its chosen revision here, unless
+-- the revision is targeted.
+CREATE TABLE banner_feature_tags (
+   tenant_id INT NOT NULL REFERENCES tenants(id),
+   feature_id INT NOT NULL,
+   tag_id INT NOT NULL,
+   banner_id INT NOT NULL REFERENCES banners(banner_id) ON DELETE CASCADE,
+   PRIMARY KEY (tenant_id, feature_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_banner_feature_tags_banner_id ON banner_feature_tags(banner_id);
+
+-- Every change of the chosen revision of a banner, newest last. Rollbacks
+-- mark the publications they undo instead of adding one, so that repeated
+-- rollbacks keep going back. A publication was live from published_at until
+-- the next one, until it was rolled back or until its banner was deleted.
+-- Published revisions are never pruned, and publications outlive their
+-- banner with the feature and tags they were live for, so lookups of the
+-- past never skip over them.
+CREATE TABLE banner_publications (
+   id SERIAL PRIMARY KEY,
+   tenant_id INT NOT NULL REFERENCES tenants(id),
+   banner_id INT NOT NULL,
+   revision_id INT NOT NULL,
+   feature_id INT NOT NULL,
+   tag_ids INT[] NOT NULL,
+   targeted BOOL NOT NULL DEFAULT FALSE,
+   published_by INT DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+   published_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+   rolled_back_at TIMESTAMPTZ DEFAULT NULL,
+   banner_deleted_at TIMESTAMPTZ DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_banner_publications_banner_id ON banner_publications(banner_id, id);
+
+CREATE INDEX IF NOT EXISTS idx_banner_publications_feature ON banner_publications(tenant_id, feature_id);
+
+CREATE INDEX IF NOT EXISTS idx_banner_publications_revision_id ON banner_publications(revision_id);
+
 CREATE INDEX IF NOT EXISTS idx_banners_chosen_revision_id ON banners(chosen_revision_id);
 
 CREATE INDEX IF NOT EXISTS idx_banner_revisions_banner_id_revision_id ON banner_revisions(banner_id, revision_id);
 
-CREATE INDEX IF NOT EXISTS idx_banner_revisions_feature ON banner_revisions(feature_id);
+CREATE INDEX IF NOT EXISTS idx_banner_revisions_feature ON banner_revisions(tenant_id, feature_id);
+
+CREATE INDEX IF NOT EXISTS idx_banner_revisions_tags ON revision_tags(tag_id);
+
+-- The banner users get for a feature when no banner has their tag. It is
+-- only served while its chosen revision is on the feature.
+CREATE TABLE feature_defaults (
+    tenant_id INT NOT NULL REFERENCES tenants(id),
+    feature_id INT NOT NULL,
+    banner_id INT NOT NULL REFERENCES banners(banner_id) ON DELETE CASCADE,
+    PRIMARY KEY (tenant_id, feature_id)
+);
+
+-- Every change of the default of a feature, newest last, so that lookups of
+-- the past find the default of the time. banner_id is null from the time the
+-- default was removed, and has no foreign key so that the history outlives
+-- the banner.
+CREATE TABLE feature_default_changes (
+    id SERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL REFERENCES tenants(id),
+    feature_id INT NOT NULL,
+    banner_id INT DEFAULT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_feature_default_changes_feature ON feature_default_changes(tenant_id, feature_id, id);
+
+-- A rollout serves a revision of a banner to a percentage of its users
+-- before it is chosen for all of them. Choosing any revision of the banner
+-- ends its rollout.
+CREATE TABLE banner_rollouts (
+    banner_id INT PRIMARY KEY REFERENCES banners(banner_id) ON DELETE CASCADE,
+    tenant_id INT NOT NULL REFERENCES tenants(id),
+    revision_id INT NOT NULL REFERENCES banner_revisions(revision_id) ON DELETE CASCADE,
+    percent INT NOT NULL CHECK (percent BETWEEN 0 AND 100),
+    created_by INT DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Experiments split the users of a feature and tag between variants of the
+-- content by weight. Only one experiment may run for a feature and tag.
+CREATE TABLE experiments (
+    id SERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL REFERENCES tenants(id),
+    feature_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'stopped')),
+    created_by INT DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    stopped_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_experiments_running ON experiments(tenant_id, feature_id, tag_id) WHERE status = 'running';
+
+CREATE TABLE experiment_variants (
+    experiment_id INT NOT NULL REFERENCES experiments(id) ON DELETE CASCADE,
+    key VARCHAR(50) NOT NULL,
+    weight INT NOT NULL CHECK (weight > 0),
+    content JSONB NOT NULL,
+    PRIMARY KEY (experiment_id, key)
+);
+
+-- Features that require approval only publish revisions another user has
+-- approved.
+CREATE TABLE feature_policies (
+    tenant_id INT NOT NULL REFERENCES tenants(id),
+    feature_id INT NOT NULL,
+    requires_approval BOOL NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (tenant_id, feature_id)
+);
+
+-- Versions of the JSON Schema banner content of a feature must match. New
+-- revisions are checked against the newest version, so tightening a schema
+-- leaves existing revisions alone.
+CREATE TABLE feature_schemas (
+    tenant_id INT NOT NULL REFERENCES tenants(id),
+    feature_id INT NOT NULL,
+    version INT NOT NULL,
+    schema JSONB NOT NULL,
+    created_by INT DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, feature_id, version)
+);
+
+CREATE TABLE approval_requests (
+    id SERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL REFERENCES tenants(id),
+    banner_id INT NOT NULL REFERENCES banners(banner_id) ON DELETE CASCADE,
+    revision_id INT NOT NULL REFERENCES banner_revisions(revision_id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    comment TEXT NOT NULL DEFAULT '',
+    requested_by INT DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+    decided_by INT DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    decided_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_approval_requests_pending ON approval_requests(revision_id) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_approval_requests_banner_id ON approval_requests(banner_id);
+
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(12) NOT NULL,
+    key_hash VARCHAR(64) UNIQUE NOT NULL,
+    created_by INT DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP DEFAULT NULL,
+    tenant_id INT NOT NULL DEFAULT 1 REFERENCES tenants(id)
+);
+
+CREATE TABLE api_key_scopes (
+    api_key_id INT NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (api_key_id, permission)
+);
+
+CREATE TABLE api_key_features (
+    api_key_id INT NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    feature_id INT NOT NULL,
+    PRIMARY KEY (api_key_id, feature_id)
+);
+
+-- The audit log is append-only: actor, banner and user ids are not foreign
+-- keys, so records outlive what they point to, and the trigger below
+-- rejects any change to existing records.
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    actor_id INT DEFAULT NULL,
+    actor_email VARCHAR(30) DEFAULT NULL,
+    api_key_id INT DEFAULT NULL,
+    action VARCHAR(50) NOT NULL,
+    banner_id INT DEFAULT NULL,
+    revision_id INT DEFAULT NULL,
+    target_user_id INT DEFAULT NULL,
+    target VARCHAR(100) DEFAULT NULL,
+    before JSONB DEFAULT NULL,
+    after JSONB DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_tenant_id ON audit_log(tenant_id);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_banner_id ON audit_log(banner_id);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+
+CREATE FUNCTION audit_log_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update
+BEFORE UPDATE OR DELETE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
 
-CREATE INDEX IF NOT EXISTS idx_banner_revisions_tags ON revision_tags(tag_id);
\ No newline at end of file
+CREATE TRIGGER audit_log_no_truncate
+BEFORE TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();